@@ -0,0 +1,295 @@
+package echosight
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alexjoedt/echosight/internal/validator"
+)
+
+const (
+	DefaultAnomalyAlpha             float64 = 0.05
+	DefaultAnomalyWarnDeviation     float64 = 3
+	DefaultAnomalyCriticalDeviation float64 = 4
+	DefaultAnomalyWarmUp            int     = 30
+
+	// hoursPerWeek is the number of seasonal buckets
+	hoursPerWeek int = 7 * 24
+	// minBucketSamples is the number of samples a seasonal bucket needs, before it's used
+	minBucketSamples int = 5
+
+	// AnomalyWarmUpRange is the time range of metrics used to learn the baseline at startup
+	AnomalyWarmUpRange string = "-7d"
+)
+
+type AnomalyDirection string
+
+const (
+	AnomalyAbove AnomalyDirection = "above"
+	AnomalyBelow AnomalyDirection = "below"
+	AnomalyBoth  AnomalyDirection = "both"
+)
+
+// AnomalyRule enables the anomaly detection for a metric field.
+// The value is compared with a learned baseline and raises WARN or CRITICAL
+// if it deviates by more than the given standard deviations.
+type AnomalyRule struct {
+	Field string `json:"field"`
+	// Alpha is the smoothing factor of the EWMA (0 < alpha <= 1)
+	Alpha float64 `json:"alpha,omitempty"`
+	// WarnDeviation and CriticalDeviation are the number of standard deviations (K)
+	WarnDeviation     float64 `json:"warnDeviation,omitempty"`
+	CriticalDeviation float64 `json:"criticalDeviation,omitempty"`
+	// Seasonal uses a separate baseline for every hour of the week
+	Seasonal bool `json:"seasonal"`
+	// WarmUp is the number of samples the baseline needs before anomalies are reported
+	WarmUp    int              `json:"warmUp,omitempty"`
+	Direction AnomalyDirection `json:"direction,omitempty"`
+}
+
+// applyDefaults sets the default values for all unset fields
+func (ar *AnomalyRule) applyDefaults() {
+	if ar.Alpha == 0 {
+		ar.Alpha = DefaultAnomalyAlpha
+	}
+
+	if ar.WarnDeviation == 0 {
+		ar.WarnDeviation = DefaultAnomalyWarnDeviation
+	}
+
+	if ar.CriticalDeviation == 0 {
+		ar.CriticalDeviation = DefaultAnomalyCriticalDeviation
+	}
+
+	if ar.WarmUp == 0 {
+		ar.WarmUp = DefaultAnomalyWarmUp
+	}
+
+	if ar.Direction == "" {
+		ar.Direction = AnomalyBoth
+	}
+}
+
+func ValidateAnomalyRule(v *validator.Validator, key string, rule *AnomalyRule) {
+	v.Check(rule.Field != "", key+".field", "must be provided")
+	v.Check(rule.Alpha >= 0 && rule.Alpha <= 1, key+".alpha", "must be between 0 and 1")
+	v.Check(rule.WarnDeviation >= 0, key+".warnDeviation", "must not be negative")
+	v.Check(rule.CriticalDeviation >= 0, key+".criticalDeviation", "must not be negative")
+	v.Check(rule.WarmUp >= 0, key+".warmUp", "must not be negative")
+	if rule.Direction != "" {
+		v.Check(validator.PermittedValue(rule.Direction, AnomalyAbove, AnomalyBelow, AnomalyBoth), key+".direction", "must be above, below or both")
+	}
+}
+
+// ewma is an exponentially weighted moving average and variance
+type ewma struct {
+	mean     float64
+	variance float64
+	count    int
+}
+
+func (e *ewma) add(value float64, alpha float64) {
+	if e.count == 0 {
+		e.mean = value
+		e.count++
+		return
+	}
+
+	diff := value - e.mean
+	incr := alpha * diff
+	e.mean += incr
+	e.variance = (1 - alpha) * (e.variance + diff*incr)
+	e.count++
+}
+
+// Baseline is the learned baseline of a metric field
+type Baseline struct {
+	rule    AnomalyRule
+	global  ewma
+	buckets [hoursPerWeek]ewma
+}
+
+func NewBaseline(rule AnomalyRule) *Baseline {
+	rule.applyDefaults()
+	return &Baseline{rule: rule}
+}
+
+// hourOfWeek returns the seasonal bucket in UTC, the learned metrics and the live results
+// must use the same buckets regardless of the location of the host
+func hourOfWeek(t time.Time) int {
+	t = t.UTC()
+	return int(t.Weekday())*24 + t.Hour()
+}
+
+// Add adds a value at the given time to the baseline
+func (b *Baseline) Add(value float64, t time.Time) {
+	b.global.add(value, b.rule.Alpha)
+	if b.rule.Seasonal {
+		b.buckets[hourOfWeek(t)].add(value, b.rule.Alpha)
+	}
+}
+
+// Expected returns the expected value and the standard deviation at the given time
+func (b *Baseline) Expected(t time.Time) (float64, float64) {
+	stat := b.global
+	if b.rule.Seasonal {
+		if bucket := b.buckets[hourOfWeek(t)]; bucket.count >= minBucketSamples {
+			stat = bucket
+		}
+	}
+
+	// avoid a division by zero for constant values
+	stddev := math.Max(math.Sqrt(stat.variance), math.Max(math.Abs(stat.mean)*0.01, 1e-6))
+	return stat.mean, stddev
+}
+
+// WarmedUp reports if the baseline has enough samples to report anomalies
+func (b *Baseline) WarmedUp() bool {
+	return b.global.count >= b.rule.WarmUp
+}
+
+// Band returns the lower and upper bound of the expected values for the warn deviation
+func (b *Baseline) Band(t time.Time) (float64, float64) {
+	mean, stddev := b.Expected(t)
+	return mean - b.rule.WarnDeviation*stddev, mean + b.rule.WarnDeviation*stddev
+}
+
+// Evaluate returns the state for the value at the given time and the deviation in standard deviations
+func (b *Baseline) Evaluate(value float64, t time.Time) (State, float64) {
+	if !b.WarmedUp() {
+		return StateOK, 0
+	}
+
+	mean, stddev := b.Expected(t)
+	deviation := (value - mean) / stddev
+
+	switch b.rule.Direction {
+	case AnomalyAbove:
+		deviation = math.Max(deviation, 0)
+	case AnomalyBelow:
+		deviation = math.Max(-deviation, 0)
+	default:
+		deviation = math.Abs(deviation)
+	}
+
+	switch {
+	case deviation > b.rule.CriticalDeviation:
+		return StateCritical, deviation
+	case deviation > b.rule.WarnDeviation:
+		return StateWarn, deviation
+	default:
+		return StateOK, deviation
+	}
+}
+
+// AnomalyDetector holds the baselines of all anomaly rules of a detector.
+// The bands are read by the metrics API, while the checks add values.
+type AnomalyDetector struct {
+	mu        sync.Mutex
+	baselines []*Baseline
+}
+
+func NewAnomalyDetector(rules []AnomalyRule) *AnomalyDetector {
+	ad := &AnomalyDetector{
+		baselines: make([]*Baseline, len(rules)),
+	}
+
+	for i := range rules {
+		ad.baselines[i] = NewBaseline(rules[i])
+	}
+
+	return ad
+}
+
+// Learn adds existing metric points, e.g. from the metric storage, to the baselines.
+// The points must be sorted by time.
+func (ad *AnomalyDetector) Learn(points []MetricPoint) {
+	if ad == nil {
+		return
+	}
+
+	ad.mu.Lock()
+	defer ad.mu.Unlock()
+	for _, p := range points {
+		for _, b := range ad.baselines {
+			if value, ok := toFloat(p.Fields[b.rule.Field]); ok {
+				b.Add(value, p.Time)
+			}
+		}
+	}
+}
+
+// Evaluate compares the metric fields of the result with the baselines.
+// The state of the result is set to the worst state, afterwards the values
+// are added to the baselines.
+func (ad *AnomalyDetector) Evaluate(result *Result) {
+	if ad == nil || len(ad.baselines) == 0 || result == nil || result.Metric == nil {
+		return
+	}
+
+	t := result.Metric.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+
+	ad.mu.Lock()
+	defer ad.mu.Unlock()
+
+	var messages []string
+	for _, b := range ad.baselines {
+		field := b.rule.Field
+		value, ok := toFloat(result.Metric.Fields[field])
+		if !ok {
+			continue
+		}
+
+		state, deviation := b.Evaluate(value, t)
+		if state != StateOK {
+			result.State = WorseState(result.State, state)
+			messages = append(messages, fmt.Sprintf("%s anomaly (%v, %.1f std dev)", field, value, deviation))
+		}
+
+		b.Add(value, t)
+	}
+
+	if len(messages) > 0 {
+		msg := strings.Join(messages, ", ")
+		if result.Message != "" {
+			msg = result.Message + "; " + msg
+		}
+		result.Message = msg
+	}
+}
+
+// AddBands adds the expected band of the warmed-up baselines to the fields of the points
+// (<field>_expected, <field>_lower, <field>_upper), e.g. for graphs. The band isn't part of
+// the written metrics, it's calculated with the current baselines.
+func (ad *AnomalyDetector) AddBands(points []MetricPoint) {
+	if ad == nil {
+		return
+	}
+
+	ad.mu.Lock()
+	defer ad.mu.Unlock()
+	for _, p := range points {
+		if p.Fields == nil {
+			continue
+		}
+
+		for _, b := range ad.baselines {
+			if !b.WarmedUp() {
+				continue
+			}
+
+			field := b.rule.Field
+			mean, _ := b.Expected(p.Time)
+			lower, upper := b.Band(p.Time)
+			p.Fields[field+"_expected"] = mean
+			p.Fields[field+"_lower"] = lower
+			p.Fields[field+"_upper"] = upper
+		}
+	}
+}
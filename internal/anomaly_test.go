@@ -0,0 +1,40 @@
+package echosight
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHourOfWeekIgnoresLocation(t *testing.T) {
+	utc := time.Date(2024, 3, 4, 23, 30, 0, 0, time.UTC)
+	berlin := utc.In(time.FixedZone("CET", 3600))
+
+	if hourOfWeek(utc) != hourOfWeek(berlin) {
+		t.Fatalf("bucket of the same instant differs: %d != %d", hourOfWeek(utc), hourOfWeek(berlin))
+	}
+}
+
+func TestAnomalyDetectorKeepsBandOutOfMetric(t *testing.T) {
+	ad := NewAnomalyDetector([]AnomalyRule{{Field: "latency", WarmUp: 3}})
+
+	start := time.Date(2024, 3, 4, 10, 0, 0, 0, time.UTC)
+	var points []MetricPoint
+	for i := 0; i < 10; i++ {
+		points = append(points, MetricPoint{Time: start.Add(time.Duration(i) * time.Minute), Fields: map[string]any{"latency": 100.0 + float64(i%2)}})
+	}
+	ad.Learn(points)
+
+	result := &Result{State: StateOK, Metric: &Metric{Time: start.Add(time.Hour), Fields: map[string]any{"latency": 100.0}}}
+	ad.Evaluate(result)
+	if len(result.Metric.Fields) != 1 {
+		t.Fatalf("band written to the metric: %v", result.Metric.Fields)
+	}
+
+	read := []MetricPoint{{Time: start, Fields: map[string]any{"latency": 100.0}}}
+	ad.AddBands(read)
+	for _, key := range []string{"latency_expected", "latency_lower", "latency_upper"} {
+		if _, ok := read[0].Fields[key]; !ok {
+			t.Fatalf("missing %s in %v", key, read[0].Fields)
+		}
+	}
+}
@@ -8,15 +8,23 @@ import (
 	echosight "github.com/alexjoedt/echosight/internal"
 	"github.com/alexjoedt/echosight/internal/cache"
 	"github.com/alexjoedt/echosight/internal/crypt"
+	"github.com/alexjoedt/echosight/internal/escalation"
 	"github.com/alexjoedt/echosight/internal/eventflow"
 	"github.com/alexjoedt/echosight/internal/filter"
 	"github.com/alexjoedt/echosight/internal/http"
+	"github.com/alexjoedt/echosight/internal/incident"
 	"github.com/alexjoedt/echosight/internal/influx"
 	"github.com/alexjoedt/echosight/internal/logger"
 	"github.com/alexjoedt/echosight/internal/mail"
+	"github.com/alexjoedt/echosight/internal/maintenance"
 	"github.com/alexjoedt/echosight/internal/notify"
 	engine "github.com/alexjoedt/echosight/internal/observer"
+	"github.com/alexjoedt/echosight/internal/oncall"
+	"github.com/alexjoedt/echosight/internal/outbox"
 	"github.com/alexjoedt/echosight/internal/postgres"
+	"github.com/alexjoedt/echosight/internal/report"
+	"github.com/alexjoedt/echosight/internal/routing"
+	"github.com/alexjoedt/echosight/internal/slo"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -59,18 +67,22 @@ func Run() error {
 	eventHandler := eventflow.NewEngine()
 	defer eventHandler.Stop()
 
-	// Init Notification-Service
-	// TODO: read all enabled notification services from the DB and initialize them
-	// TODO: create a sql table for notification services
-	// TODO: add API route to initalize and enable notification services
+	// Init Notification-Service, the channels stored in the DB are added below
 	notifier := notify.NewNotifier()
 
+	// Init Renderer, the stored templates take precedence over the built-in templates
+	renderer := notify.NewRenderer(&db.Templates, &db.Incidents)
+	renderer.PublicURL = config.Notifications.PublicURL
+	renderer.SetDefault("mail", mail.DefaultTemplate())
+	renderer.SetDefault("telegram", notify.TelegramTemplate())
+
 	// Init MailService
 	logger.Infof("Initialize Mail-Service")
 	mailer, err := mail.New(mail.Opts{
 		AppPreferences: &db.Preferences,
 		Recipients:     &db.Recipients,
 		Crypter:        crypter,
+		Renderer:       renderer,
 	})
 	if err != nil {
 		logger.Warnf("failed to initialize mailer: %v", err)
@@ -78,17 +90,83 @@ func Run() error {
 		notifier.AddSender("mail", mailer)
 	}
 
-	// Init Telegram Bot
-	tele, err := notify.NewTelegramBot(&db.Preferences)
-	if err != nil {
-		logger.Warnf("failed to init telegram bot: %v", err)
-	} else {
-		notifier.AddSender("telegram", tele)
+	// Init Outbox, the notifications are persisted and delivered with retries
+	dispatcher := outbox.NewDispatcher(&db.Notifications, notifier)
+	if config.Notifications.Workers > 0 {
+		dispatcher.Workers = config.Notifications.Workers
+	}
+	if config.Notifications.MaxAttempts > 0 {
+		dispatcher.MaxAttempts = config.Notifications.MaxAttempts
+	}
+	if config.Notifications.MaxBackoff > 0 {
+		dispatcher.MaxBackoff = time.Duration(config.Notifications.MaxBackoff)
+	}
+	notifier.SetOutbox(dispatcher)
+	if config.Notifications.GroupWindow > 0 {
+		groupBy := notify.GroupByHost
+		if config.Notifications.GroupBy == string(notify.GroupByTag) {
+			groupBy = notify.GroupByTag
+		}
+		notifier.SetGrouping(time.Duration(config.Notifications.GroupWindow), groupBy)
+	}
+	if config.Notifications.RateLimit > 0 {
+		notifier.SetRateLimit(config.Notifications.RateLimit)
 	}
+	dispatcher.Start()
+
+	// Init Webhooks
+	webhooks := notify.NewWebhookSender(&db.Webhooks, crypter)
+	webhooks.Renderer = renderer
+	notifier.AddSender("webhook", webhooks)
 
 	// Init observer engine and starts
 	logger.Debugf("Initialize Observer-Engine...")
 	scheduler := engine.NewScheduler(&db.Detectors, influxClient, eventHandler, notifier)
+	if config.Observer.DefaultTimeout > 0 {
+		scheduler.DefaultTimeout = time.Duration(config.Observer.DefaultTimeout)
+	}
+	if config.Observer.MaxTimeout > 0 {
+		scheduler.MaxTimeout = time.Duration(config.Observer.MaxTimeout)
+	}
+	scheduler.HistoryService = &db.History
+
+	// Init Incident-Manager
+	incidents := incident.NewManager(&db.Incidents, eventHandler, notifier)
+	if config.Incidents.AutoResolve != nil {
+		incidents.AutoResolve = *config.Incidents.AutoResolve
+	}
+	scheduler.Incidents = incidents
+
+	// Init Maintenance-Calendar
+	calendar := maintenance.NewCalendar(&db.Maintenance)
+	if err := calendar.Reload(ctx); err != nil {
+		logger.Errorf("failed to load maintenance windows: %v", err)
+	}
+	scheduler.Maintenance = calendar
+
+	// Init Router, all notifications of detectors are sent through the routing rules
+	onCall := oncall.NewResolver(&db.OnCall)
+	router := routing.NewRouter(&db.Routing, notifier)
+	router.OnCall = onCall
+	router.Detectors = &db.Detectors
+	if err := router.Reload(ctx); err != nil {
+		logger.Errorf("failed to load routing rules: %v", err)
+	}
+	router.Start()
+	scheduler.Routing = router
+	incidents.Routing = router
+
+	// Init Escalator, it must be subscribed before incidents are opened
+	escalator := escalation.NewEscalator(&db.Escalations, &db.Incidents, &db.Detectors, eventHandler, notifier)
+	escalator.OnCall = onCall
+	escalator.Routing = router
+	if err := escalator.Reload(ctx); err != nil {
+		logger.Errorf("failed to load escalation policies: %v", err)
+	}
+	if err := escalator.Start(); err != nil {
+		return err
+	}
+	scheduler.Escalations = escalator
 
 	// load all active detectors
 	dFilter := filter.NewDefaultDetectorFilter()
@@ -103,6 +181,54 @@ func Run() error {
 	// TODO: read state of observer/scheduler from database
 	scheduler.Start()
 
+	// The Telegram channels answer the bot commands
+	commands := &notify.BotCommands{
+		Detectors:    &db.Detectors,
+		Incidents:    &db.Incidents,
+		Acknowledger: incidents,
+		Checker:      scheduler,
+		Maintenance:  &db.Maintenance,
+		Calendar:     calendar,
+	}
+
+	// Init Notification-Channels, the senders of the stored channels are registered
+	// in addition to the built-in mail and webhook senders and rebuilt when a channel changes.
+	// The configurations of the preference based senders of older versions are migrated to channels.
+	channels := notify.NewChannels(&db.Channels, notifier)
+	channels.Crypter = crypter
+	channels.Renderer = renderer
+	channels.Recipients = &db.Recipients
+	channels.Commands = commands
+	channels.Webhooks = webhooks
+	channels.Register("mail", "smtp", func(_ *echosight.NotificationChannel, prefService echosight.PreferenceService) (notify.Sender, error) {
+		mailer, err := mail.New(mail.Opts{
+			AppPreferences: prefService,
+			Recipients:     &db.Recipients,
+			Crypter:        crypter,
+			Renderer:       renderer,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return mailer, nil
+	})
+	if err := channels.MigratePreferences(ctx, &db.Preferences); err != nil {
+		logger.Errorf("failed to migrate the preferences to notification channels: %v", err)
+	}
+	if err := channels.Reload(ctx); err != nil {
+		logger.Errorf("failed to load notification channels: %v", err)
+	}
+
+	// Init SLO-Tracker
+	logger.Debugf("Initialize SLO-Tracker...")
+	sloTracker := slo.NewTracker(&db.SLOs, &report.Reporter{
+		Detectors:  &db.Detectors,
+		History:    &db.History,
+		Exclusions: calendar,
+	}, influxClient, eventHandler, notifier)
+	sloTracker.Routing = router
+	sloTracker.Start()
+
 	// Init redis
 	logger.Debugf("Connect to Redis...")
 	rc := redis.NewClient(&redis.Options{
@@ -142,6 +268,28 @@ func Run() error {
 	server.RecipientService = &db.Recipients
 	server.PreferenceService = &db.Preferences
 	server.SessionService = &db.Sessions
+	server.HistoryService = &db.History
+	server.SLOService = &db.SLOs
+	server.SLOTracker = sloTracker
+	server.IncidentService = &db.Incidents
+	server.IncidentManager = incidents
+	server.MaintenanceService = &db.Maintenance
+	server.Maintenance = calendar
+	server.EscalationService = &db.Escalations
+	server.Escalations = escalator
+	server.OnCallService = &db.OnCall
+	server.OnCall = onCall
+	server.RoutingService = &db.Routing
+	server.Routing = router
+	server.NotificationService = &db.Notifications
+	server.Notifier = notifier
+	server.Outbox = dispatcher
+	server.TemplateService = &db.Templates
+	server.Renderer = renderer
+	server.WebhookService = &db.Webhooks
+	server.Webhooks = webhooks
+	server.ChannelService = &db.Channels
+	server.Channels = channels
 	server.MetricReader = influxClient
 	server.Crypter = crypter
 
@@ -166,6 +314,13 @@ func Run() error {
 	logger.Infof("Server stopped")
 	logger.Infof("Waiting for background jobs")
 	scheduler.Stop()
+	sloTracker.Stop()
+	escalator.Stop()
+	router.Stop()
+	// the pending groups are added to the outbox before it stops
+	notifier.Flush()
+	dispatcher.Stop()
+	channels.Stop()
 	logger.Infof("Shutdown")
 	return nil
 }
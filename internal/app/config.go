@@ -102,6 +102,65 @@ type Config struct {
 		TTL Duration `json:"ttl" toml:"ttl" yaml:"ttl" env:"CACHE_TTL"`
 	} `json:"cache,omitempty" toml:"cache,omitempty" yaml:"cache,omitempty"`
 
+	// Observer configures the scheduler which runs the detector checks
+	Observer struct {
+		// DefaultTimeout is used for detectors without a timeout.
+		//
+		// default: `10s`
+		DefaultTimeout Duration `json:"defaultTimeout" toml:"defaultTimeout" yaml:"defaultTimeout" env:"OBSERVER_DEFAULT_TIMEOUT"`
+
+		// MaxTimeout is the upper limit for the timeout of a detector.
+		//
+		// default: `5m`
+		MaxTimeout Duration `json:"maxTimeout" toml:"maxTimeout" yaml:"maxTimeout" env:"OBSERVER_MAX_TIMEOUT"`
+	} `json:"observer,omitempty" toml:"observer,omitempty" yaml:"observer,omitempty"`
+
+	// Incidents configures the incident management
+	Incidents struct {
+		// AutoResolve resolves an incident when the detector recovers.
+		// If disabled, incidents must be resolved manually.
+		//
+		// default: `true`
+		AutoResolve *bool `json:"autoResolve" toml:"autoResolve" yaml:"autoResolve" env:"INCIDENTS_AUTO_RESOLVE"`
+	} `json:"incidents,omitempty" toml:"incidents,omitempty" yaml:"incidents,omitempty"`
+
+	// Notifications configures the outbox which delivers the notifications
+	Notifications struct {
+		// Workers is the number of concurrent deliveries.
+		//
+		// default: `3`
+		Workers int `json:"workers" toml:"workers" yaml:"workers" env:"NOTIFICATIONS_WORKERS"`
+
+		// MaxAttempts is the number of attempts until a notification is dead.
+		//
+		// default: `8`
+		MaxAttempts int `json:"maxAttempts" toml:"maxAttempts" yaml:"maxAttempts" env:"NOTIFICATIONS_MAX_ATTEMPTS"`
+
+		// MaxBackoff limits the delay between two attempts.
+		//
+		// default: `1h`
+		MaxBackoff Duration `json:"maxBackoff" toml:"maxBackoff" yaml:"maxBackoff" env:"NOTIFICATIONS_MAX_BACKOFF"`
+
+		// GroupWindow combines the state changes which arrive within the window
+		// into a single notification. Grouping is disabled without a window.
+		GroupWindow Duration `json:"groupWindow" toml:"groupWindow" yaml:"groupWindow" env:"NOTIFICATIONS_GROUP_WINDOW"`
+
+		// GroupBy is the key to group the state changes.
+		//
+		// Valid values: `host`, `tag`
+		//
+		// default: `host`
+		GroupBy string `json:"groupBy" toml:"groupBy" yaml:"groupBy" env:"NOTIFICATIONS_GROUP_BY"`
+
+		// RateLimit caps the notifications per channel and minute, the dropped
+		// notifications are summarized after the minute. Zero disables the limit.
+		RateLimit int `json:"rateLimit" toml:"rateLimit" yaml:"rateLimit" env:"NOTIFICATIONS_RATE_LIMIT"`
+
+		// PublicURL is the public address of the client, it's used for the links
+		// in the notifications. Without an address, no links are added.
+		PublicURL string `json:"publicUrl" toml:"publicUrl" yaml:"publicUrl" env:"NOTIFICATIONS_PUBLIC_URL"`
+	} `json:"notifications,omitempty" toml:"notifications,omitempty" yaml:"notifications,omitempty"`
+
 	// Mailserver connection information
 	SMTP struct {
 		Host     string `json:"host" toml:"host" yaml:"host" env:"SMTP_HOST"`
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/mitchellh/mapstructure"
 )
 
@@ -31,7 +32,7 @@ const (
 	StateInactive State = "INACTIVE"
 )
 
-// TODO: use this?
+// StateInt is the severity of a state
 type StateInt int
 
 const (
@@ -45,6 +46,28 @@ func (s State) String() string {
 	return string(s)
 }
 
+// Int returns the severity of the state
+func (s State) Int() StateInt {
+	switch s {
+	case StateOK:
+		return StateIntOK
+	case StateWarn:
+		return StateIntWarn
+	case StateCritical:
+		return StateIntCritical
+	default:
+		return StateIntInactive
+	}
+}
+
+// WorseState returns the state with the higher severity
+func WorseState(a State, b State) State {
+	if b.Int() > a.Int() {
+		return b
+	}
+	return a
+}
+
 var (
 	checkerFuncs = map[DetectorType]func(d *Detector) (Checker, error){
 		DetectorHTTP:     getHTTPChecker,
@@ -110,10 +133,58 @@ type Result struct {
 	Message  string  `json:"message"`
 	Metric   *Metric `json:"metric"`
 
+	// StateType is SOFT as long the state is not confirmed by enough attempts
+	StateType StateType `json:"stateType"`
+	Attempt   int       `json:"attempt"`
+
+	// TimedOut indicates that the check was canceled after the detector timeout
+	TimedOut bool `json:"timedOut"`
+
+	Flapping    bool    `json:"flapping"`
+	FlapPercent float64 `json:"flapPercent"`
+
+	// Notification is set if the result is sent as notification
+	Notification NotificationType `json:"notification,omitempty"`
+	// DetectorID is set if the result is sent as notification
+	DetectorID string `json:"detectorId,omitempty"`
+	// IncidentID is the unresolved incident of the detector
+	IncidentID string `json:"incidentId,omitempty"`
+	// EscalationLevel is the level of the escalation policy, starting with 1
+	EscalationLevel int `json:"escalationLevel,omitempty"`
+	// Reminder is the number of the reminder, starting with 1
+	Reminder int `json:"reminder,omitempty"`
+	// Elapsed is the duration of the problem, it's set for reminders and recoveries
+	Elapsed Duration `json:"elapsed,omitempty"`
+	// PreviousState is the problem state of a recovery
+	PreviousState State `json:"previousState,omitempty"`
+	// Recipients limits the notification to these recipients, all if empty
+	Recipients []uuid.UUID `json:"-"`
+	// Webhooks limits the webhook notification to these webhooks, all if empty
+	Webhooks []uuid.UUID `json:"webhooks,omitempty"`
+	// Tags of the detector, used to group the notifications
+	Tags []string `json:"-"`
+	// Group holds the combined results of a group, dropped or digest notification
+	Group []*Result `json:"group,omitempty"`
+	// Dropped is the number of results which exceeded the rate limit
+	Dropped int `json:"dropped,omitempty"`
+	// DeliveryID is the id of the outbox notification, it's the same for all delivery attempts
+	DeliveryID string `json:"-"`
+
 	// err indicates if an internal err happened
 	err error
 }
 
+// NewTimeoutResult returns a CRITICAL result for a check which exceeds the timeout
+func NewTimeoutResult(timeout time.Duration) *Result {
+	err := fmt.Errorf("check timed out after %s: %w", timeout, context.DeadlineExceeded)
+	return &Result{
+		State:    StateCritical,
+		Message:  err.Error(),
+		TimedOut: true,
+		err:      err,
+	}
+}
+
 func (r *Result) Error() error {
 	return r.err
 }
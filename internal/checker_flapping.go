@@ -0,0 +1,105 @@
+package echosight
+
+const (
+	// flapWindowSize is the number of results used to calculate the state transitions
+	flapWindowSize int = 21
+
+	DefaultFlapLowThreshold  float64 = 5.0
+	DefaultFlapHighThreshold float64 = 20.0
+)
+
+// FlapDetection detects detectors which are changing their state too frequently.
+// Source: https://assets.nagios.com/downloads/nagioscore/docs/nagioscore/4/en/flapping.html
+//
+// The percent state change is calculated over a sliding window of the latest results.
+// Newer state changes are weighted more heavily than older ones.
+// A detector starts flapping above the high threshold and stops below the low threshold.
+type FlapDetection struct {
+	LowThreshold  float64
+	HighThreshold float64
+
+	states   []State
+	percent  float64
+	flapping bool
+	changed  bool
+	// restored is set while the window after a restore isn't full again,
+	// the partial window can't stop the flapping
+	restored bool
+}
+
+func NewFlapDetection(low float64, high float64) *FlapDetection {
+	if low <= 0 {
+		low = DefaultFlapLowThreshold
+	}
+
+	if high <= 0 {
+		high = DefaultFlapHighThreshold
+	}
+
+	return &FlapDetection{
+		LowThreshold:  low,
+		HighThreshold: high,
+		states:        make([]State, 0, flapWindowSize),
+	}
+}
+
+// Add adds a state to the window and evaluates the flapping condition
+func (f *FlapDetection) Add(s State) {
+	f.states = append(f.states, s)
+	if len(f.states) > flapWindowSize {
+		f.states = f.states[len(f.states)-flapWindowSize:]
+	}
+
+	f.percent = f.calcPercentStateChange()
+	if f.restored && len(f.states) >= flapWindowSize {
+		f.restored = false
+	}
+
+	f.changed = false
+	switch {
+	case !f.flapping && f.percent > f.HighThreshold:
+		f.flapping = true
+		f.changed = true
+	case f.flapping && !f.restored && f.percent < f.LowThreshold:
+		f.flapping = false
+		f.changed = true
+	}
+}
+
+// calcPercentStateChange weights the transitions linear from 0.8 (oldest) to 1.2 (newest)
+func (f *FlapDetection) calcPercentStateChange() float64 {
+	transitions := flapWindowSize - 1
+	var changes float64
+	for i := 1; i < len(f.states); i++ {
+		if f.states[i] != f.states[i-1] {
+			// position of the transition in a full window
+			pos := transitions - (len(f.states) - 1) + (i - 1)
+			changes += 0.8 + 0.4*float64(pos)/float64(transitions-1)
+		}
+	}
+
+	return changes * 100 / float64(transitions)
+}
+
+// Restore sets the flapping condition, e.g. after a restart.
+// The states of the window aren't persisted, a restored flapping stops
+// at the earliest when the window is full again.
+func (f *FlapDetection) Restore(flapping bool, percent float64) {
+	f.flapping = flapping
+	f.percent = percent
+	f.restored = flapping
+	f.states = f.states[:0]
+}
+
+func (f *FlapDetection) IsFlapping() bool {
+	return f.flapping
+}
+
+// Changed reports if the last added state has started or stopped the flapping
+func (f *FlapDetection) Changed() bool {
+	return f.changed
+}
+
+func (f *FlapDetection) Percent() float64 {
+	return f.percent
+}
@@ -0,0 +1,47 @@
+package echosight
+
+import "testing"
+
+func TestFlapDetectionRestoreKeepsFlapping(t *testing.T) {
+	f := NewFlapDetection(0, 0)
+	f.Restore(true, 30)
+
+	// a stable partial window must not stop the restored flapping
+	for i := 0; i < flapWindowSize-1; i++ {
+		f.Add(StateOK)
+		if f.Changed() || !f.IsFlapping() {
+			t.Fatalf("flapping stopped after %d states of a partial window", i+1)
+		}
+	}
+
+	f.Add(StateOK)
+	if !f.Changed() || f.IsFlapping() {
+		t.Fatalf("flapping not stopped with a full stable window, percent %.1f", f.Percent())
+	}
+}
+
+func TestFlapDetectionStartsAndStops(t *testing.T) {
+	f := NewFlapDetection(0, 0)
+
+	started := false
+	for i := 0; i < flapWindowSize; i++ {
+		if i%2 == 0 {
+			f.Add(StateOK)
+		} else {
+			f.Add(StateCritical)
+		}
+		started = started || f.Changed()
+	}
+	if !started || !f.IsFlapping() {
+		t.Fatalf("alternating states not flapping, percent %.1f", f.Percent())
+	}
+
+	stopped := false
+	for i := 0; i < flapWindowSize; i++ {
+		f.Add(StateOK)
+		stopped = stopped || f.Changed()
+	}
+	if !stopped || f.IsFlapping() {
+		t.Fatalf("stable states still flapping, percent %.1f", f.Percent())
+	}
+}
@@ -1,16 +1,173 @@
 package echosight
 
+const (
+	// defaultHistorySize is the minimum number of results kept in a CheckHistory
+	defaultHistorySize int = 3
+)
+
+// StateType indicates if a state is confirmed (HARD) or
+// still waiting for confirmation through re-checks (SOFT)
+type StateType string
+
+const (
+	StateTypeSoft StateType = "SOFT"
+	StateTypeHard StateType = "HARD"
+)
+
+func (st StateType) String() string {
+	return string(st)
+}
+
+// isProblem reports if the state is a non-OK state
+func isProblem(s State) bool {
+	return s == StateWarn || s == StateCritical
+}
+
+// CheckHistory holds the latest results of a detector and tracks
+// the soft/hard state of the detector.
+//
+// A change between OK and a problem state (WARN, CRITICAL) must be confirmed
+// by MaxAttempts consecutive results, a recovery by RecoveryAttempts consecutive results.
+// Until then the state is SOFT. A change between WARN and CRITICAL is always HARD.
 type CheckHistory struct {
 	Results []*Result
+	size    int
+
+	MaxAttempts      int
+	RecoveryAttempts int
+
+	// HardState is the last confirmed state
+	HardState State
+	StateType StateType
+	// Attempt is the count of consecutive results which differs from the hard state
+	Attempt int
+
+	// Flap is nil if the flap detection is disabled
+	Flap *FlapDetection
+
+	hardStateChanged  bool
+	previousHardState State
+}
+
+// NewCheckHistory creates a CheckHistory which keeps the latest results
+// and needs maxAttempts results to confirm a problem and recoveryAttempts to confirm a recovery.
+func NewCheckHistory(maxAttempts int, recoveryAttempts int) *CheckHistory {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	if recoveryAttempts < 1 {
+		recoveryAttempts = 1
+	}
+
+	size := max(defaultHistorySize, maxAttempts, recoveryAttempts)
+	return &CheckHistory{
+		Results:          make([]*Result, 0, size),
+		size:             size,
+		MaxAttempts:      maxAttempts,
+		RecoveryAttempts: recoveryAttempts,
+		HardState:        StateInactive,
+		StateType:        StateTypeHard,
+	}
+}
+
+// Restore sets the state from a persisted detector, e.g. after a restart
+func (ch *CheckHistory) Restore(d *Detector) {
+	if d.HardState != "" {
+		ch.HardState = d.HardState
+	}
+
+	if d.StateType != "" {
+		ch.StateType = d.StateType
+	}
+
+	ch.Attempt = d.Attempt
+
+	if ch.Flap != nil {
+		ch.Flap.Restore(d.Flapping, d.FlapPercent)
+	}
 }
 
+// EnableFlapDetection enables the flap detection with the given thresholds in percent
+func (ch *CheckHistory) EnableFlapDetection(low float64, high float64) {
+	ch.Flap = NewFlapDetection(low, high)
+}
+
+// IsFlapping reports if the flap detection is enabled and the detector is flapping
+func (ch *CheckHistory) IsFlapping() bool {
+	return ch.Flap != nil && ch.Flap.IsFlapping()
+}
+
+// FlappingChanged reports if the last added result has started or stopped the flapping
+func (ch *CheckHistory) FlappingChanged() bool {
+	return ch.Flap != nil && ch.Flap.Changed()
+}
+
+// AddResult adds the result to the history and evaluates the soft/hard state.
+// StateType and Attempt of the result will be set.
 func (ch *CheckHistory) AddResult(result *Result) {
+	if result == nil {
+		return
+	}
+
 	ch.Results = append(ch.Results, result)
-	if len(ch.Results) > 3 {
-		ch.Results = ch.Results[len(ch.Results)-3:]
+	if len(ch.Results) > ch.size {
+		ch.Results = ch.Results[len(ch.Results)-ch.size:]
 	}
+
+	ch.hardStateChanged = false
+	ch.previousHardState = ch.HardState
+
+	if isProblem(result.State) == isProblem(ch.HardState) {
+		// same kind of state (e.g. OK -> OK or WARN -> CRITICAL), no confirmation needed
+		ch.setHardState(result.State)
+	} else {
+		needed := ch.MaxAttempts
+		if !isProblem(result.State) {
+			needed = ch.RecoveryAttempts
+		}
+
+		ch.Attempt++
+		if ch.Attempt >= needed {
+			ch.setHardState(result.State)
+		} else {
+			ch.StateType = StateTypeSoft
+		}
+	}
+
+	result.StateType = ch.StateType
+	result.Attempt = ch.Attempt
+
+	if ch.Flap != nil {
+		ch.Flap.Add(result.State)
+		result.Flapping = ch.Flap.IsFlapping()
+		result.FlapPercent = ch.Flap.Percent()
+	}
+}
+
+func (ch *CheckHistory) setHardState(s State) {
+	ch.hardStateChanged = ch.HardState != s
+	ch.HardState = s
+	ch.StateType = StateTypeHard
+	ch.Attempt = 0
+}
+
+// HardStateChanged reports if the last added result has changed the hard state
+func (ch *CheckHistory) HardStateChanged() bool {
+	return ch.hardStateChanged
+}
+
+// PreviousHardState returns the hard state before the last added result
+func (ch *CheckHistory) PreviousHardState() State {
+	return ch.previousHardState
+}
+
+// IsSoft reports if the current state is not confirmed yet
+func (ch *CheckHistory) IsSoft() bool {
+	return ch.StateType == StateTypeSoft
 }
 
+// StateChanged reports if the state of the last two results differs
 func (ch *CheckHistory) StateChanged() bool {
 	last := len(ch.Results) - 1
 	if last <= 0 {
@@ -32,7 +189,7 @@ func (ch *CheckHistory) StateChanged() bool {
 // are warn or critical
 func (ch *CheckHistory) WarnOrCritical() bool {
 	for _, h := range ch.Results {
-		if h.State == StateOK {
+		if h == nil || h.State == StateOK {
 			return false
 		}
 	}
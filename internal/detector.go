@@ -25,9 +25,44 @@ type Detector struct {
 	// Config is the configuration for the specified checker type which implements also the Checker interface
 	Config CheckerConfig `json:"config" bun:"type:jsonb"`
 
+	// Thresholds are evaluated on the metric fields after every check
+	Thresholds []ThresholdRule `json:"thresholds" bun:"type:jsonb"`
+	// Anomalies are evaluated on the metric fields against a learned baseline
+	Anomalies []AnomalyRule `json:"anomalies" bun:"type:jsonb"`
+
+	// MaxAttempts is the number of consecutive problem results until a problem becomes HARD
+	MaxAttempts int `json:"maxAttempts" bun:",default:1"`
+	// RecoveryAttempts is the number of consecutive OK results until a recovery becomes HARD
+	RecoveryAttempts int `json:"recoveryAttempts" bun:",default:1"`
+	// RetryInterval is used instead of Interval while the state is SOFT
+	RetryInterval Duration `json:"retryInterval"`
+
+	// RenotifyInterval sends a reminder while the problem persists and is not acknowledged.
+	// Zero disables the reminders.
+	RenotifyInterval Duration `json:"renotifyInterval"`
+	// RenotifyMax limits the reminders of a problem, zero is unlimited
+	RenotifyMax int `json:"renotifyMax"`
+
+	// FlapDetection enables the detection of frequent state changes.
+	// The thresholds are the percent state change to start and stop flapping.
+	FlapDetection     bool    `json:"flapDetection"`
+	FlapLowThreshold  float64 `json:"flapLowThreshold"`
+	FlapHighThreshold float64 `json:"flapHighThreshold"`
+
 	State         State     `json:"state"`
+	StateType     StateType `json:"stateType" bun:",default:'HARD'"`
+	HardState     State     `json:"hardState" bun:",default:'INACTIVE'"`
+	Attempt       int       `json:"attempt"`
+	Flapping      bool      `json:"flapping"`
+	FlapPercent   float64   `json:"flapPercent"`
 	StatusMessage string    `json:"statusMessage"`
 	LastCheckedAt time.Time `json:"lastCheckedAt"`
+	// StateChangedAt is the time of the last hard state change
+	StateChangedAt time.Time `json:"stateChangedAt"`
+	// InMaintenance is set while a maintenance window is active, notifications are suppressed
+	InMaintenance bool `json:"inMaintenance"`
+	// MaintenanceWindows are the active and upcoming maintenance windows of the detector
+	MaintenanceWindows []*MaintenanceWindow `json:"maintenanceWindows,omitempty" bun:"-"`
 
 	LookupVersion int       `json:"lookupVersion" bun:",default:1"`
 	CreatedAt     time.Time `json:"createdAt"`
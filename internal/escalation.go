@@ -0,0 +1,142 @@
+package echosight
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/alexjoedt/echosight/internal/validator"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// EscalationLevel notifies the channels and recipients after the delay,
+// if the incident is neither acknowledged nor recovered.
+type EscalationLevel struct {
+	// Delay after the incident was opened, without the time the escalation was paused
+	Delay Duration `json:"delay"`
+	// Channels are the ids of the notification senders, e.g. mail or telegram.
+	// Without channels, all senders are used.
+	Channels []string `json:"channels,omitempty"`
+	// Recipients are the ids of the mail recipients.
+	// Without recipients, all active recipients are notified.
+	Recipients []uuid.UUID `json:"recipients,omitempty"`
+	// Schedules are the ids of on-call schedules, whoever is on call is notified
+	// in addition to the recipients
+	Schedules []uuid.UUID `json:"schedules,omitempty"`
+}
+
+// EscalationPolicy escalates unacknowledged incidents of detectors
+// through ordered levels, e.g. telegram at 0 min, mail to the team lead at 15 min.
+// It applies to the detectors and all detectors with one of the tags.
+type EscalationPolicy struct {
+	bun.BaseModel `bun:"table:escalation_policies"`
+	ID            uuid.UUID         `json:"id" bun:"type:uuid,pk,default:uuid_generate_v4()"`
+	LookupVersion int               `json:"lookupVersion" bun:",default:1"`
+	Name          string            `json:"name"`
+	Active        bool              `json:"active"`
+	DetectorIDs   []uuid.UUID       `json:"detectorIds" bun:"type:uuid[],array"`
+	Tags          []string          `json:"tags" bun:",array"`
+	Levels        []EscalationLevel `json:"levels" bun:"type:jsonb"`
+
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Matches reports if the policy applies to the detector
+func (ep *EscalationPolicy) Matches(d *Detector) bool {
+	if !ep.Active {
+		return false
+	}
+
+	if slices.Contains(ep.DetectorIDs, d.ID) {
+		return true
+	}
+
+	for _, tag := range ep.Tags {
+		if slices.Contains(d.Tags, tag) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func ValidateEscalationPolicy(v *validator.Validator, ep *EscalationPolicy) {
+	v.Check(len(ep.Name) > 3, "name", "name too short")
+	v.Check(len(ep.DetectorIDs) > 0 || len(ep.Tags) > 0, "detectorIds", "at least one detector or tag must be provided")
+	v.Check(len(ep.Levels) > 0, "levels", "at least one level must be provided")
+
+	for i, level := range ep.Levels {
+		key := fmt.Sprintf("levels[%d]", i)
+		v.Check(level.Delay >= 0, key+".delay", "must not be negative")
+		if i > 0 {
+			v.Check(level.Delay >= ep.Levels[i-1].Delay, key+".delay", "must not be shorter than the delay of the previous level")
+		}
+	}
+}
+
+type EscalationStatus string
+
+const (
+	EscalationPending   EscalationStatus = "pending"
+	EscalationStopped   EscalationStatus = "stopped"
+	EscalationCompleted EscalationStatus = "completed"
+)
+
+// Escalation is the durable state of a policy for an incident.
+// Level is the index of the next level, which is due at NextAt.
+type Escalation struct {
+	bun.BaseModel `bun:"table:escalations"`
+	ID            uuid.UUID        `json:"id" bun:"type:uuid,pk,default:uuid_generate_v4()"`
+	PolicyID      uuid.UUID        `json:"policyId" bun:"type:uuid"`
+	IncidentID    uuid.UUID        `json:"incidentId" bun:"type:uuid"`
+	DetectorID    uuid.UUID        `json:"detectorId" bun:"type:uuid"`
+	Level         int              `json:"level"`
+	NextAt        time.Time        `json:"nextAt"`
+	Status        EscalationStatus `json:"status"`
+	StopReason    string           `json:"stopReason,omitempty" bun:",nullzero"`
+	// StartedAt is the time the incident was opened, the level delays are relative to it.
+	// It's shifted by the time the escalation was paused.
+	StartedAt time.Time `json:"startedAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// NewEscalation creates a pending escalation of the policy for the incident
+func NewEscalation(policy *EscalationPolicy, incident *Incident) *Escalation {
+	return &Escalation{
+		PolicyID:   policy.ID,
+		IncidentID: incident.ID,
+		DetectorID: incident.DetectorID,
+		Status:     EscalationPending,
+		StartedAt:  incident.CreatedAt,
+		NextAt:     incident.CreatedAt.Add(time.Duration(policy.Levels[0].Delay)),
+	}
+}
+
+// Advance moves the escalation to the next level of the policy
+// or completes it after the last level
+func (e *Escalation) Advance(policy *EscalationPolicy) {
+	e.Level++
+	if e.Level >= len(policy.Levels) {
+		e.Status = EscalationCompleted
+		return
+	}
+	e.NextAt = e.StartedAt.Add(time.Duration(policy.Levels[e.Level].Delay))
+}
+
+// Pause postpones the due level until the time, e.g. during maintenance.
+// The later levels are postponed by the same time, so their delays are kept.
+func (e *Escalation) Pause(until time.Time) {
+	if !e.NextAt.Before(until) {
+		return
+	}
+	e.StartedAt = e.StartedAt.Add(until.Sub(e.NextAt))
+	e.NextAt = until
+}
+
+// Stop stops the pending escalation
+func (e *Escalation) Stop(reason string) {
+	e.Status = EscalationStopped
+	e.StopReason = reason
+}
@@ -0,0 +1,348 @@
+package escalation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	es "github.com/alexjoedt/echosight/internal"
+	"github.com/alexjoedt/echosight/internal/eventflow"
+	"github.com/alexjoedt/echosight/internal/filter"
+	"github.com/alexjoedt/echosight/internal/logger"
+	"github.com/alexjoedt/echosight/internal/notify"
+	"github.com/alexjoedt/echosight/internal/oncall"
+	"github.com/alexjoedt/echosight/internal/routing"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultInterval time.Duration = time.Second * 10
+	processTimeout  time.Duration = time.Second * 30
+
+	StopAcknowledged  = "acknowledged"
+	StopResolved      = "resolved"
+	StopRecovered     = "recovered"
+	StopPolicyRemoved = "policy removed"
+)
+
+// Escalator starts an escalation for every matching policy when an incident is opened
+// and notifies the levels until the incident is acknowledged, resolved or recovered.
+//
+// The escalations are stored with the time of the next level, due escalations
+// are polled from the database. Pending escalations continue after a restart.
+type Escalator struct {
+	escalations  es.EscalationService
+	incidents    es.IncidentService
+	detectors    es.DetectorService
+	eventHandler *eventflow.Engine
+	notifier     *notify.Notifier
+	log          *logger.Logger
+
+	mu       sync.RWMutex
+	policies []*es.EscalationPolicy
+
+	wake   chan struct{}
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	// Interval between two polls for due escalations
+	Interval time.Duration
+
+	// OnCall resolves the on-call schedules of the levels, optional
+	OnCall *oncall.Resolver
+	// Routing sends the levels, optional
+	Routing *routing.Router
+}
+
+func NewEscalator(escs es.EscalationService, is es.IncidentService, ds es.DetectorService, eh *eventflow.Engine, n *notify.Notifier) *Escalator {
+	return &Escalator{
+		escalations:  escs,
+		incidents:    is,
+		detectors:    ds,
+		eventHandler: eh,
+		notifier:     n,
+		log:          logger.New("Escalator"),
+		wake:         make(chan struct{}, 1),
+		Interval:     defaultInterval,
+	}
+}
+
+// Reload loads all active policies.
+// It must be called after a policy was created, changed or deleted.
+func (e *Escalator) Reload(ctx context.Context) error {
+	f := filter.NewDefaultEscalationFilter()
+	active := true
+	f.Active = &active
+
+	policies, err := e.escalations.ListPolicies(ctx, f)
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	e.policies = policies
+	e.mu.Unlock()
+	return nil
+}
+
+// Covers reports if an active policy applies to the detector.
+// The problem notifications of covered detectors are sent by the escalation levels.
+func (e *Escalator) Covers(d *es.Detector) bool {
+	return len(e.matching(d)) > 0
+}
+
+func (e *Escalator) matching(d *es.Detector) []*es.EscalationPolicy {
+	if e == nil {
+		return nil
+	}
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	var policies []*es.EscalationPolicy
+	for _, p := range e.policies {
+		if p.Matches(d) {
+			policies = append(policies, p)
+		}
+	}
+	return policies
+}
+
+// Start subscribes to the incident topic and processes the due escalations
+// in the background until Stop is called
+func (e *Escalator) Start() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	e.cancel = cancel
+
+	if _, err := e.eventHandler.Subscribe(ctx, es.IncidentTopic, e.onIncident); err != nil {
+		cancel()
+		return fmt.Errorf("failed to subscribe to incident topic: %w", err)
+	}
+
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+
+		ticker := time.NewTicker(e.Interval)
+		defer ticker.Stop()
+
+		for {
+			e.ProcessDue(ctx)
+
+			select {
+			case <-ticker.C:
+			case <-e.wake:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+// Stop blocks until the running processing is done
+func (e *Escalator) Stop() {
+	if e.cancel == nil {
+		return
+	}
+	e.cancel()
+	e.wg.Wait()
+}
+
+// onIncident starts the escalations of opened incidents and stops them
+// on acknowledge, resolve or recovery
+func (e *Escalator) onIncident(ctx context.Context, event *eventflow.Event) error {
+	var ie es.IncidentEvent
+	if err := json.Unmarshal(event.Payload, &ie); err != nil {
+		return err
+	}
+
+	if ie.Incident == nil {
+		return nil
+	}
+
+	switch ie.Type {
+	case es.IncidentEntryOpened:
+		return e.start(ctx, ie.Incident)
+	case es.IncidentEntryAcknowledged:
+		return e.stop(ctx, ie.Incident.ID, StopAcknowledged)
+	case es.IncidentEntryResolved:
+		return e.stop(ctx, ie.Incident.ID, StopResolved)
+	case es.IncidentEntryStateChanged:
+		if ie.Incident.State == es.StateOK {
+			return e.stop(ctx, ie.Incident.ID, StopRecovered)
+		}
+	}
+
+	return nil
+}
+
+func (e *Escalator) start(ctx context.Context, incident *es.Incident) error {
+	d, err := e.detectors.GetByID(ctx, incident.DetectorID)
+	if err != nil {
+		return err
+	}
+
+	policies := e.matching(d)
+	if len(policies) == 0 {
+		return nil
+	}
+
+	for _, policy := range policies {
+		escalation := es.NewEscalation(policy, incident)
+		if err := e.escalations.CreateEscalation(ctx, escalation); err != nil {
+			return err
+		}
+	}
+
+	e.log.Debugw("escalation started", logger.UUID("incident_id", incident.ID))
+
+	// the first level is usually due immediately
+	select {
+	case e.wake <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+func (e *Escalator) stop(ctx context.Context, incidentID uuid.UUID, reason string) error {
+	escalations, err := e.escalations.ListPending(ctx, incidentID)
+	if err != nil {
+		return err
+	}
+
+	for _, escalation := range escalations {
+		escalation.Stop(reason)
+		if err := e.escalations.UpdateEscalation(ctx, escalation); err != nil {
+			return err
+		}
+		e.log.Debugw("escalation stopped", logger.UUID("incident_id", incidentID), logger.Str("reason", reason))
+	}
+
+	return nil
+}
+
+// ProcessDue notifies the due levels of all pending escalations
+func (e *Escalator) ProcessDue(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, processTimeout)
+	defer cancel()
+
+	now := time.Now()
+	escalations, err := e.escalations.ListDue(ctx, now)
+	if err != nil {
+		e.log.Errorf("failed to list due escalations: %v", err)
+		return
+	}
+
+	for _, escalation := range escalations {
+		if err := e.process(ctx, escalation, now); err != nil {
+			e.log.Errorf("failed to process escalation %s: %v", escalation.ID, err)
+		}
+	}
+}
+
+func (e *Escalator) process(ctx context.Context, escalation *es.Escalation, now time.Time) error {
+	policy, err := e.escalations.GetPolicyByID(ctx, escalation.PolicyID)
+	if err != nil && es.ErrorCode(err) != es.ENOTFOUND {
+		return err
+	}
+
+	if policy == nil || !policy.Active || escalation.Level >= len(policy.Levels) {
+		escalation.Stop(StopPolicyRemoved)
+		return e.escalations.UpdateEscalation(ctx, escalation)
+	}
+
+	incident, err := e.incidents.GetByID(ctx, escalation.IncidentID)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case incident.IsResolved():
+		escalation.Stop(StopResolved)
+		return e.escalations.UpdateEscalation(ctx, escalation)
+	case incident.IsAcknowledged():
+		escalation.Stop(StopAcknowledged)
+		return e.escalations.UpdateEscalation(ctx, escalation)
+	case incident.State == es.StateOK:
+		escalation.Stop(StopRecovered)
+		return e.escalations.UpdateEscalation(ctx, escalation)
+	}
+
+	d, err := e.detectors.GetByID(ctx, escalation.DetectorID)
+	if err != nil {
+		return err
+	}
+
+	// the escalation is paused during maintenance
+	if d.InMaintenance {
+		escalation.Pause(now.Add(e.Interval))
+		return e.escalations.UpdateEscalation(ctx, escalation)
+	}
+
+	// all levels which are due are notified, e.g. after a restart
+	for escalation.Status == es.EscalationPending && !escalation.NextAt.After(now) {
+		e.notify(ctx, incident, escalation.Level, policy.Levels[escalation.Level])
+		escalation.Advance(policy)
+	}
+
+	return e.escalations.UpdateEscalation(ctx, escalation)
+}
+
+// notify sends the incident to the channels and recipients of the level
+func (e *Escalator) notify(ctx context.Context, incident *es.Incident, level int, el es.EscalationLevel) {
+	if e.notifier == nil {
+		return
+	}
+
+	result := &es.Result{
+		Host:            incident.HostName,
+		Detector:        incident.DetectorName,
+		State:           incident.State,
+		Message:         incident.Message,
+		Notification:    es.NotificationEscalation,
+		DetectorID:      incident.DetectorID.String(),
+		IncidentID:      incident.ID.String(),
+		EscalationLevel: level + 1,
+	}
+
+	// the level is sent like a routing rule, so the on-call schedules are resolved the same way
+	if e.Routing != nil {
+		err := e.Routing.SendTo(ctx, &es.RoutingRule{
+			Name:       fmt.Sprintf("escalation level %d of incident %s", level+1, incident.ID),
+			Channels:   el.Channels,
+			Recipients: el.Recipients,
+			Schedules:  el.Schedules,
+		}, result)
+		if err != nil {
+			e.log.Errorf("failed to send escalation notifications: %v", err)
+		}
+		return
+	}
+
+	recipients := el.Recipients
+	if len(el.Schedules) > 0 {
+		onCall, err := e.OnCall.Recipients(ctx, el.Schedules)
+		if err != nil {
+			e.log.Errorf("failed to resolve on-call recipients: %v", err)
+		}
+		recipients = append(slices.Clone(recipients), onCall...)
+	}
+
+	// an empty list is sent to all recipients, the level must only reach whoever is on call
+	if len(el.Schedules) > 0 && len(recipients) == 0 {
+		e.log.Warnf("escalation level %d of incident %s skipped, nobody is on call", level+1, incident.ID)
+		return
+	}
+
+	// a level without recipients and schedules notifies all recipients
+	result.Recipients = recipients
+	if err := e.notifier.SendTo(ctx, el.Channels, result); err != nil {
+		e.log.Errorf("failed to send escalation notifications: %v", err)
+	}
+}
@@ -0,0 +1,31 @@
+package echosight
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEscalationPauseKeepsLevelDelays(t *testing.T) {
+	policy := &EscalationPolicy{Levels: []EscalationLevel{
+		{Delay: Duration(0)},
+		{Delay: Duration(10 * time.Minute)},
+		{Delay: Duration(20 * time.Minute)},
+	}}
+	opened := time.Date(2024, 3, 4, 10, 0, 0, 0, time.UTC)
+	escalation := NewEscalation(policy, &Incident{CreatedAt: opened})
+	escalation.Advance(policy)
+
+	// a maintenance window of an hour, the escalation is paused every minute
+	for now := opened.Add(10 * time.Minute); now.Before(opened.Add(70 * time.Minute)); now = now.Add(time.Minute) {
+		escalation.Pause(now.Add(time.Minute))
+	}
+
+	if want := opened.Add(70 * time.Minute); !escalation.NextAt.Equal(want) {
+		t.Fatalf("level 2 is due at %s, want %s", escalation.NextAt, want)
+	}
+
+	escalation.Advance(policy)
+	if want := opened.Add(80 * time.Minute); !escalation.NextAt.Equal(want) {
+		t.Fatalf("level 3 is due at %s, want %s", escalation.NextAt, want)
+	}
+}
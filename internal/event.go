@@ -9,5 +9,10 @@ type ResultEvent struct {
 	DetectorID   string
 	HostName     string
 	DetectorName string
-	CheckResult  *Result
+	StateType    StateType
+	Attempt      int
+	MaxAttempts  int
+	// InMaintenance is set while a maintenance window of the detector is active
+	InMaintenance bool
+	CheckResult   *Result
 }
@@ -8,6 +8,7 @@ type DetectorFilter struct {
 	HostID *uuid.UUID
 	Type   *string
 	Active *bool
+	Tag    *string
 }
 
 func NewDefaultDetectorFilter() *DetectorFilter {
@@ -0,0 +1,17 @@
+package filter
+
+import "github.com/google/uuid"
+
+type EscalationFilter struct {
+	Filter
+	Name   *string
+	Active *bool
+	// IncidentID selects the escalations of an incident
+	IncidentID *uuid.UUID
+}
+
+func NewDefaultEscalationFilter() *EscalationFilter {
+	return &EscalationFilter{
+		Filter: NewDefaultFilter(),
+	}
+}
@@ -0,0 +1,24 @@
+package filter
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type HistoryFilter struct {
+	Filter
+	DetectorID *uuid.UUID
+	HostID     *uuid.UUID
+	From       *time.Time
+	To         *time.Time
+}
+
+func NewDefaultHistoryFilter() *HistoryFilter {
+	f := NewDefaultFilter()
+	f.PageSize = 100
+	f.Sort = "-created_at"
+	return &HistoryFilter{
+		Filter: f,
+	}
+}
@@ -0,0 +1,22 @@
+package filter
+
+import "github.com/google/uuid"
+
+type IncidentFilter struct {
+	Filter
+	DetectorID *uuid.UUID
+	HostID     *uuid.UUID
+	AssigneeID *uuid.UUID
+	Status     *string
+	// Unresolved selects open and acknowledged incidents
+	Unresolved bool
+}
+
+func NewDefaultIncidentFilter() *IncidentFilter {
+	f := NewDefaultFilter()
+	f.PageSize = 100
+	f.Sort = "-created_at"
+	return &IncidentFilter{
+		Filter: f,
+	}
+}
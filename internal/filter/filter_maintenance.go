@@ -0,0 +1,19 @@
+package filter
+
+import "github.com/google/uuid"
+
+type MaintenanceFilter struct {
+	Filter
+	HostID     *uuid.UUID
+	DetectorID *uuid.UUID
+	Tag        *string
+}
+
+func NewDefaultMaintenanceFilter() *MaintenanceFilter {
+	f := NewDefaultFilter()
+	f.Sort = "starts_at"
+	f.SortSafelist = append(f.SortSafelist, "starts_at", "-starts_at")
+	return &MaintenanceFilter{
+		Filter: f,
+	}
+}
@@ -0,0 +1,16 @@
+package filter
+
+type NotificationFilter struct {
+	Filter
+	Status  *string
+	Channel *string
+	Type    *string
+}
+
+func NewDefaultNotificationFilter() *NotificationFilter {
+	f := NewDefaultFilter()
+	f.Sort = "-created_at"
+	return &NotificationFilter{
+		Filter: f,
+	}
+}
@@ -0,0 +1,16 @@
+package filter
+
+type NotificationChannelFilter struct {
+	Filter
+	Type    *string
+	Name    *string
+	Enabled *bool
+}
+
+func NewDefaultNotificationChannelFilter() *NotificationChannelFilter {
+	f := NewDefaultFilter()
+	f.SortSafelist = append(f.SortSafelist, "name", "-name", "type", "-type")
+	return &NotificationChannelFilter{
+		Filter: f,
+	}
+}
@@ -0,0 +1,22 @@
+package filter
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type OnCallFilter struct {
+	Filter
+	Name *string
+	// ScheduleID, From and To select the overrides of a schedule within the time range
+	ScheduleID *uuid.UUID
+	From       *time.Time
+	To         *time.Time
+}
+
+func NewDefaultOnCallFilter() *OnCallFilter {
+	return &OnCallFilter{
+		Filter: NewDefaultFilter(),
+	}
+}
@@ -8,6 +8,7 @@ type RecipientFilter struct {
 	Name   *string
 	Email  *string
 	Active *bool
+	IDs    []uuid.UUID
 }
 
 func NewDefaultRecipientFilter() *RecipientFilter {
@@ -0,0 +1,16 @@
+package filter
+
+type RoutingFilter struct {
+	Filter
+	Name   *string
+	Active *bool
+}
+
+func NewDefaultRoutingFilter() *RoutingFilter {
+	f := NewDefaultFilter()
+	f.Sort = "position"
+	f.SortSafelist = append(f.SortSafelist, "position", "-position")
+	return &RoutingFilter{
+		Filter: f,
+	}
+}
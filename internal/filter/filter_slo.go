@@ -0,0 +1,16 @@
+package filter
+
+import "github.com/google/uuid"
+
+type SLOFilter struct {
+	Filter
+	Name       *string
+	DetectorID *uuid.UUID
+	Tag        *string
+}
+
+func NewDefaultSLOFilter() *SLOFilter {
+	return &SLOFilter{
+		Filter: NewDefaultFilter(),
+	}
+}
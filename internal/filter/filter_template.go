@@ -0,0 +1,17 @@
+package filter
+
+type TemplateFilter struct {
+	Filter
+	Channel *string
+	Type    *string
+	Active  *bool
+}
+
+func NewDefaultTemplateFilter() *TemplateFilter {
+	f := NewDefaultFilter()
+	f.Sort = "channel"
+	f.SortSafelist = append(f.SortSafelist, "channel", "-channel", "type", "-type")
+	return &TemplateFilter{
+		Filter: f,
+	}
+}
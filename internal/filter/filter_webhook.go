@@ -0,0 +1,16 @@
+package filter
+
+import "github.com/google/uuid"
+
+type WebhookFilter struct {
+	Filter
+	Name   *string
+	Active *bool
+	IDs    []uuid.UUID
+}
+
+func NewDefaultWebhookFilter() *WebhookFilter {
+	return &WebhookFilter{
+		Filter: NewDefaultFilter(),
+	}
+}
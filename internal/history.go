@@ -0,0 +1,53 @@
+package echosight
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// StateChange is a persisted hard state transition of a detector
+type StateChange struct {
+	bun.BaseModel `bun:"table:detector_state_changes"`
+	ID            uuid.UUID `json:"id" bun:"type:uuid,pk,default:uuid_generate_v4()"`
+	DetectorID    uuid.UUID `json:"detectorId" bun:"type:uuid"`
+	HostID        uuid.UUID `json:"hostId" bun:"type:uuid"`
+	FromState     State     `json:"fromState"`
+	ToState       State     `json:"toState"`
+	Message       string    `json:"message"`
+	Flapping      bool      `json:"flapping"`
+	// Duration is the time the detector was in the previous state
+	Duration  Duration  `json:"duration"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// ResultLog is a persisted check result of a detector
+type ResultLog struct {
+	bun.BaseModel `bun:"table:detector_results"`
+	ID            int64     `json:"id" bun:",pk,autoincrement"`
+	DetectorID    uuid.UUID `json:"detectorId" bun:"type:uuid"`
+	HostID        uuid.UUID `json:"hostId" bun:"type:uuid"`
+	State         State     `json:"state"`
+	StateType     StateType `json:"stateType"`
+	Attempt       int       `json:"attempt"`
+	Message       string    `json:"message"`
+	TimedOut      bool      `json:"timedOut"`
+	Flapping      bool      `json:"flapping"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+// NewResultLog creates the log entry of a check result
+func NewResultLog(d *Detector, r *Result) *ResultLog {
+	return &ResultLog{
+		DetectorID: d.ID,
+		HostID:     d.HostID,
+		State:      r.State,
+		StateType:  r.StateType,
+		Attempt:    r.Attempt,
+		Message:    r.Message,
+		TimedOut:   r.TimedOut,
+		Flapping:   r.Flapping,
+		CreatedAt:  time.Now(),
+	}
+}
@@ -43,4 +43,9 @@ type Host struct {
 	UpdatedAt time.Time `json:"updatedAt"`
 
 	Detectors []*Detector `json:"detectors,omitempty" bun:"rel:has-many,join:id=host_id"`
+
+	// InMaintenance is set while a maintenance window of the host is active
+	InMaintenance bool `json:"inMaintenance" bun:"-"`
+	// MaintenanceWindows are the active and upcoming maintenance windows of the host
+	MaintenanceWindows []*MaintenanceWindow `json:"maintenanceWindows,omitempty" bun:"-"`
 }
@@ -0,0 +1,23 @@
+package http
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+)
+
+// writeCSV writes the records as CSV attachment with the given filename
+func writeCSV(w http.ResponseWriter, status int, filename string, records [][]string) error {
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+
+	cw := csv.NewWriter(w)
+	err := cw.WriteAll(records)
+	if err != nil {
+		return fmt.Errorf("failed to write csv: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,279 @@
+package http
+
+import (
+	"context"
+	"net/http"
+
+	echosight "github.com/alexjoedt/echosight/internal"
+	"github.com/alexjoedt/echosight/internal/filter"
+	"github.com/alexjoedt/echosight/internal/validator"
+	"github.com/go-chi/chi/v5"
+)
+
+func (s *Server) registerChannelRoutes(r *chi.Mux) {
+	r.With(s.requireAuth).Route("/notification-channels", func(r chi.Router) {
+		r.Get("/", makeHandlerFunc(s.handlerGetChannels))
+		r.Post("/", makeHandlerFunc(s.handlerCreateChannel))
+		r.Get("/types", makeHandlerFunc(s.handlerGetChannelTypes))
+		r.Get("/{channelID}", makeHandlerFunc(s.handlerGetChannelByID))
+		r.Patch("/{channelID}", makeHandlerFunc(s.handlerUpdateChannel))
+		r.Delete("/{channelID}", makeHandlerFunc(s.handlerDeleteChannelByID))
+	})
+}
+
+type channelInput struct {
+	Type    *string `json:"type"`
+	Name    *string `json:"name"`
+	Enabled *bool   `json:"enabled"`
+	// Settings replace the stored settings, the secrets are encrypted.
+	// Stored secrets which aren't provided are kept, an empty secret removes it.
+	Settings map[string]string `json:"settings"`
+}
+
+// apply sets the provided fields on the channel and encrypts the secrets
+func (input *channelInput) apply(channel *echosight.NotificationChannel, crypter echosight.Crypter) error {
+	if input.Type != nil {
+		channel.Type = *input.Type
+	}
+
+	if input.Name != nil {
+		channel.Name = *input.Name
+	}
+
+	if input.Enabled != nil {
+		channel.Enabled = *input.Enabled
+	}
+
+	if input.Settings != nil {
+		if err := channel.SetSettings(crypter, input.Settings); err != nil {
+			return err
+		}
+	}
+	channel.SetSecretKeys()
+
+	return nil
+}
+
+// validateChannel validates the channel, the type must be supported
+// and the name must not be used by a built-in sender.
+// The sender is built with the settings, so a broken channel isn't saved.
+func (s *Server) validateChannel(channel *echosight.NotificationChannel) error {
+	v := validator.New()
+	echosight.ValidateNotificationChannel(v, channel)
+	if s.Channels != nil {
+		v.Check(s.Channels.Supports(channel.Type), "type", "unsupported type")
+		v.Check(!s.Channels.Reserved(channel.Name), "name", "used by a built-in sender")
+	}
+
+	if !v.Valid() {
+		return echosight.ErrInvalidf("invalid notification channel payload").WithData(v.Errors)
+	}
+
+	// a disabled channel can be saved with broken settings, e.g. to disable it
+	if s.Channels != nil && channel.Enabled {
+		if err := s.Channels.Validate(channel); err != nil {
+			return echosight.ErrInvalidf("invalid notification channel settings").WithData(map[string]string{"settings": err.Error()})
+		}
+	}
+	return nil
+}
+
+func (s *Server) handlerCreateChannel(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := s.ServerContext(r.Context())
+	defer cancel()
+
+	var input channelInput
+	err := readJSON(r, &input)
+	if err != nil {
+		s.log.Errorc("failed to read notification channel payload", err)
+		return err
+	}
+
+	channel := echosight.NotificationChannel{
+		Enabled:  true,
+		Settings: make(map[string]string),
+	}
+	if err := input.apply(&channel, s.Crypter); err != nil {
+		return err
+	}
+
+	if err := s.validateChannel(&channel); err != nil {
+		return err
+	}
+
+	err = s.ChannelService.Create(ctx, &channel)
+	if err != nil {
+		return err
+	}
+
+	s.reloadChannels(ctx)
+
+	return writeJSON(w, http.StatusOK, Response{
+		Status:  StatusOK,
+		Message: "notification channel created",
+		Data: W{
+			"channel": channel,
+		},
+	})
+}
+
+func (s *Server) handlerGetChannelByID(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := s.ServerContext(r.Context())
+	defer cancel()
+
+	channelID, err := ReadUUIDParam(r, "channelID")
+	if err != nil {
+		return err
+	}
+
+	channel, err := s.ChannelService.GetByID(ctx, channelID)
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(w, http.StatusOK, Response{
+		Status: StatusOK,
+		Data: W{
+			"channel": channel,
+		},
+	})
+}
+
+// handlerGetChannels returns the notification channels, the secrets are never returned.
+//
+// Query params: type, name, enabled, page, page_size, sort
+func (s *Server) handlerGetChannels(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := s.ServerContext(r.Context())
+	defer cancel()
+
+	qs := r.URL.Query()
+	v := validator.New()
+	channelFilter := filter.NewDefaultNotificationChannelFilter()
+	if channelType := ReadString(qs, "type", ""); channelType != "" {
+		channelFilter.Type = &channelType
+	}
+	if name := ReadString(qs, "name", ""); name != "" {
+		channelFilter.Name = &name
+	}
+	if qs.Has("enabled") {
+		enabled := ReadBool(qs, "enabled")
+		channelFilter.Enabled = &enabled
+	}
+	channelFilter.Page = ReadInt(qs, "page", channelFilter.Page, v)
+	channelFilter.PageSize = ReadInt(qs, "page_size", channelFilter.PageSize, v)
+	channelFilter.Sort = ReadString(qs, "sort", channelFilter.Sort)
+	filter.ValidateFilters(v, channelFilter.Filter)
+	if !v.Valid() {
+		return echosight.ErrInvalidf("invalid query params").WithData(v.Errors)
+	}
+
+	channels, err := s.ChannelService.List(ctx, channelFilter)
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(w, http.StatusOK, Response{
+		Status: StatusOK,
+		Data: W{
+			"channels":   channels,
+			"pagination": channelFilter.Pagination,
+		},
+	})
+}
+
+// handlerGetChannelTypes returns the supported channel types
+func (s *Server) handlerGetChannelTypes(w http.ResponseWriter, r *http.Request) error {
+	types := make([]string, 0)
+	if s.Channels != nil {
+		types = s.Channels.Types()
+	}
+
+	return writeJSON(w, http.StatusOK, Response{
+		Status: StatusOK,
+		Data: W{
+			"types": types,
+		},
+	})
+}
+
+func (s *Server) handlerUpdateChannel(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := s.ServerContext(r.Context())
+	defer cancel()
+
+	channelID, err := ReadUUIDParam(r, "channelID")
+	if err != nil {
+		return err
+	}
+
+	var input channelInput
+	err = readJSON(r, &input)
+	if err != nil {
+		s.log.Errorc("failed to read notification channel payload", err)
+		return err
+	}
+
+	channel, err := s.ChannelService.GetByID(ctx, channelID)
+	if err != nil {
+		return err
+	}
+
+	if err := input.apply(channel, s.Crypter); err != nil {
+		return err
+	}
+
+	if err := s.validateChannel(channel); err != nil {
+		return err
+	}
+
+	err = s.ChannelService.Update(ctx, channel)
+	if err != nil {
+		return err
+	}
+
+	s.reloadChannels(ctx)
+
+	return writeJSON(w, http.StatusOK, Response{
+		Status:  StatusOK,
+		Message: "notification channel updated",
+		Data: W{
+			"channel": channel,
+		},
+	})
+}
+
+func (s *Server) handlerDeleteChannelByID(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := s.ServerContext(r.Context())
+	defer cancel()
+
+	channelID, err := ReadUUIDParam(r, "channelID")
+	if err != nil {
+		return err
+	}
+
+	channel, err := s.ChannelService.DeleteByID(ctx, channelID)
+	if err != nil {
+		return err
+	}
+
+	s.reloadChannels(ctx)
+
+	return writeJSON(w, http.StatusOK, Response{
+		Status:  StatusOK,
+		Message: "notification channel deleted",
+		Data: W{
+			"channel": channel,
+		},
+	})
+}
+
+// reloadChannels rebuilds the senders after a channel has changed. The changed channel
+// was validated, a failed rebuild keeps the previous sender of a channel.
+func (s *Server) reloadChannels(ctx context.Context) {
+	if s.Channels == nil {
+		return
+	}
+
+	if err := s.Channels.Reload(ctx); err != nil {
+		s.log.Errorf("failed to reload notification channels: %v", err)
+	}
+}
@@ -30,6 +30,20 @@ func (s *Server) handlerCreateDetector(w http.ResponseWriter, r *http.Request) e
 		Interval echosight.Duration      `json:"interval"`
 		Tags     []string                `json:"tags"`
 		Config   echosight.CheckerConfig `json:"config"`
+
+		MaxAttempts      int                `json:"maxAttempts"`
+		RecoveryAttempts int                `json:"recoveryAttempts"`
+		RetryInterval    echosight.Duration `json:"retryInterval"`
+
+		RenotifyInterval echosight.Duration `json:"renotifyInterval"`
+		RenotifyMax      int                `json:"renotifyMax"`
+
+		FlapDetection     bool    `json:"flapDetection"`
+		FlapLowThreshold  float64 `json:"flapLowThreshold"`
+		FlapHighThreshold float64 `json:"flapHighThreshold"`
+
+		Thresholds []echosight.ThresholdRule `json:"thresholds"`
+		Anomalies  []echosight.AnomalyRule   `json:"anomalies"`
 	}
 
 	err = readJSON(r, &input)
@@ -49,6 +63,36 @@ func (s *Server) handlerCreateDetector(w http.ResponseWriter, r *http.Request) e
 		Timeout:  input.Timeout,
 		Interval: input.Interval,
 		Config:   input.Config,
+
+		MaxAttempts:      input.MaxAttempts,
+		RecoveryAttempts: input.RecoveryAttempts,
+		RetryInterval:    input.RetryInterval,
+
+		RenotifyInterval: input.RenotifyInterval,
+		RenotifyMax:      input.RenotifyMax,
+
+		FlapDetection:     input.FlapDetection,
+		FlapLowThreshold:  input.FlapLowThreshold,
+		FlapHighThreshold: input.FlapHighThreshold,
+
+		Thresholds: input.Thresholds,
+		Anomalies:  input.Anomalies,
+	}
+
+	if detector.MaxAttempts == 0 {
+		detector.MaxAttempts = 1
+	}
+
+	if detector.RecoveryAttempts == 0 {
+		detector.RecoveryAttempts = 1
+	}
+
+	if detector.FlapLowThreshold == 0 {
+		detector.FlapLowThreshold = echosight.DefaultFlapLowThreshold
+	}
+
+	if detector.FlapHighThreshold == 0 {
+		detector.FlapHighThreshold = echosight.DefaultFlapHighThreshold
 	}
 
 	v := validator.New()
@@ -98,9 +142,15 @@ func (s *Server) handlerGetDetectorByID(w http.ResponseWriter, r *http.Request)
 	if err != nil {
 		s.log.Errorf("failed to read metrics")
 	} else {
+		// the anomaly bands are only held by the scheduler
+		if s.Scheduler != nil {
+			s.Scheduler.AnomalyBands(detector.ID, metrics)
+		}
 		detector.Metrics = metrics
 	}
 
+	s.setDetectorMaintenance(detector)
+
 	return writeJSON(w, http.StatusOK, Response{
 		Status: StatusOK,
 		Data: W{
@@ -127,6 +177,8 @@ func (s *Server) handlerGetDetectors(w http.ResponseWriter, r *http.Request) err
 		return err
 	}
 
+	s.setDetectorMaintenance(detectors...)
+
 	return writeJSON(w, http.StatusOK, Response{
 		Status: StatusOK,
 		Data: W{
@@ -151,11 +203,6 @@ func (s *Server) handlerUpdateDetector(w http.ResponseWriter, r *http.Request) e
 		return echosight.ErrInvalidf("invalid ID '%s'", id)
 	}
 
-	err = s.Scheduler.RemoveDetector(detectorID)
-	if err != nil {
-		return err
-	}
-
 	// The detector will be activated and deactivated through a seperate route.
 	var input struct {
 		Name     *string                  `json:"name"`
@@ -164,6 +211,20 @@ func (s *Server) handlerUpdateDetector(w http.ResponseWriter, r *http.Request) e
 		Interval *echosight.Duration      `json:"interval"`
 		Tags     []string                 `json:"tags"`
 		Config   *echosight.CheckerConfig `json:"config"`
+
+		MaxAttempts      *int                `json:"maxAttempts"`
+		RecoveryAttempts *int                `json:"recoveryAttempts"`
+		RetryInterval    *echosight.Duration `json:"retryInterval"`
+
+		RenotifyInterval *echosight.Duration `json:"renotifyInterval"`
+		RenotifyMax      *int                `json:"renotifyMax"`
+
+		FlapDetection     *bool    `json:"flapDetection"`
+		FlapLowThreshold  *float64 `json:"flapLowThreshold"`
+		FlapHighThreshold *float64 `json:"flapHighThreshold"`
+
+		Thresholds []echosight.ThresholdRule `json:"thresholds"`
+		Anomalies  []echosight.AnomalyRule   `json:"anomalies"`
 	}
 
 	err = readJSON(r, &input)
@@ -201,6 +262,46 @@ func (s *Server) handlerUpdateDetector(w http.ResponseWriter, r *http.Request) e
 		detector.Config = *input.Config
 	}
 
+	if input.MaxAttempts != nil {
+		detector.MaxAttempts = *input.MaxAttempts
+	}
+
+	if input.RecoveryAttempts != nil {
+		detector.RecoveryAttempts = *input.RecoveryAttempts
+	}
+
+	if input.RetryInterval != nil {
+		detector.RetryInterval = *input.RetryInterval
+	}
+
+	if input.RenotifyInterval != nil {
+		detector.RenotifyInterval = *input.RenotifyInterval
+	}
+
+	if input.RenotifyMax != nil {
+		detector.RenotifyMax = *input.RenotifyMax
+	}
+
+	if input.FlapDetection != nil {
+		detector.FlapDetection = *input.FlapDetection
+	}
+
+	if input.FlapLowThreshold != nil {
+		detector.FlapLowThreshold = *input.FlapLowThreshold
+	}
+
+	if input.FlapHighThreshold != nil {
+		detector.FlapHighThreshold = *input.FlapHighThreshold
+	}
+
+	if input.Thresholds != nil {
+		detector.Thresholds = input.Thresholds
+	}
+
+	if input.Anomalies != nil {
+		detector.Anomalies = input.Anomalies
+	}
+
 	detector.UpdatedAt = time.Now()
 
 	v := validator.New()
@@ -214,7 +315,7 @@ func (s *Server) handlerUpdateDetector(w http.ResponseWriter, r *http.Request) e
 		return err
 	}
 
-	_, err = s.Scheduler.AddDetector(detectorID)
+	err = s.Scheduler.ReloadDetector(detectorID)
 	if err != nil {
 		return err
 	}
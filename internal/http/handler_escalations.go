@@ -0,0 +1,279 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	echosight "github.com/alexjoedt/echosight/internal"
+	"github.com/alexjoedt/echosight/internal/filter"
+	"github.com/alexjoedt/echosight/internal/validator"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+func (s *Server) registerEscalationRoutes(r *chi.Mux) {
+	r.With(s.requireAuth).Route("/escalation-policies", func(r chi.Router) {
+		r.Get("/", makeHandlerFunc(s.handlerGetEscalationPolicies))
+		r.Post("/", makeHandlerFunc(s.handlerCreateEscalationPolicy))
+		r.Get("/{policyID}", makeHandlerFunc(s.handlerGetEscalationPolicyByID))
+		r.Patch("/{policyID}", makeHandlerFunc(s.handlerUpdateEscalationPolicy))
+		r.Delete("/{policyID}", makeHandlerFunc(s.handlerDeleteEscalationPolicyByID))
+	})
+
+	r.With(s.requireAuth).Get("/incidents/{incidentID}/escalations", makeHandlerFunc(s.handlerGetIncidentEscalations))
+}
+
+func (s *Server) handlerCreateEscalationPolicy(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := s.ServerContext(r.Context())
+	defer cancel()
+
+	var input struct {
+		Name        string                      `json:"name"`
+		Active      *bool                       `json:"active"`
+		DetectorIDs []uuid.UUID                 `json:"detectorIds"`
+		Tags        []string                    `json:"tags"`
+		Levels      []echosight.EscalationLevel `json:"levels"`
+	}
+
+	err := readJSON(r, &input)
+	if err != nil {
+		s.log.Errorc("failed to read escalation policy payload", err)
+		return err
+	}
+
+	policy := echosight.EscalationPolicy{
+		Name:        input.Name,
+		Active:      true,
+		DetectorIDs: input.DetectorIDs,
+		Tags:        input.Tags,
+		Levels:      input.Levels,
+	}
+
+	if input.Active != nil {
+		policy.Active = *input.Active
+	}
+
+	v := validator.New()
+	echosight.ValidateEscalationPolicy(v, &policy)
+	if !v.Valid() {
+		return echosight.ErrInvalidf("invalid escalation policy payload").WithData(v.Errors)
+	}
+
+	err = s.EscalationService.CreatePolicy(ctx, &policy)
+	if err != nil {
+		return err
+	}
+
+	s.reloadEscalations(ctx)
+
+	return writeJSON(w, http.StatusOK, Response{
+		Status:  StatusOK,
+		Message: "escalation policy created",
+		Data: W{
+			"escalationPolicy": policy,
+		},
+	})
+}
+
+func (s *Server) handlerGetEscalationPolicyByID(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := s.ServerContext(r.Context())
+	defer cancel()
+
+	policyID, err := ReadUUIDParam(r, "policyID")
+	if err != nil {
+		return err
+	}
+
+	policy, err := s.EscalationService.GetPolicyByID(ctx, policyID)
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(w, http.StatusOK, Response{
+		Status: StatusOK,
+		Data: W{
+			"escalationPolicy": policy,
+		},
+	})
+}
+
+// handlerGetEscalationPolicies returns the escalation policies.
+//
+// Query params: name, active, page, page_size, sort
+func (s *Server) handlerGetEscalationPolicies(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := s.ServerContext(r.Context())
+	defer cancel()
+
+	v := validator.New()
+	escalationFilter := readEscalationFilter(r.URL.Query(), v)
+	if !v.Valid() {
+		return echosight.ErrInvalidf("invalid query params").WithData(v.Errors)
+	}
+
+	policies, err := s.EscalationService.ListPolicies(ctx, escalationFilter)
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(w, http.StatusOK, Response{
+		Status: StatusOK,
+		Data: W{
+			"escalationPolicies": policies,
+			"pagination":         escalationFilter.Pagination,
+		},
+	})
+}
+
+func (s *Server) handlerUpdateEscalationPolicy(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := s.ServerContext(r.Context())
+	defer cancel()
+
+	policyID, err := ReadUUIDParam(r, "policyID")
+	if err != nil {
+		return err
+	}
+
+	var input struct {
+		Name        *string                     `json:"name"`
+		Active      *bool                       `json:"active"`
+		DetectorIDs []uuid.UUID                 `json:"detectorIds"`
+		Tags        []string                    `json:"tags"`
+		Levels      []echosight.EscalationLevel `json:"levels"`
+	}
+
+	err = readJSON(r, &input)
+	if err != nil {
+		s.log.Errorc("failed to read escalation policy payload", err)
+		return err
+	}
+
+	policy, err := s.EscalationService.GetPolicyByID(ctx, policyID)
+	if err != nil {
+		return err
+	}
+
+	if input.Name != nil {
+		policy.Name = *input.Name
+	}
+
+	if input.Active != nil {
+		policy.Active = *input.Active
+	}
+
+	if input.DetectorIDs != nil {
+		policy.DetectorIDs = input.DetectorIDs
+	}
+
+	if input.Tags != nil {
+		policy.Tags = input.Tags
+	}
+
+	if input.Levels != nil {
+		policy.Levels = input.Levels
+	}
+
+	v := validator.New()
+	echosight.ValidateEscalationPolicy(v, policy)
+	if !v.Valid() {
+		return echosight.ErrInvalidf("invalid escalation policy payload").WithData(v.Errors)
+	}
+
+	err = s.EscalationService.UpdatePolicy(ctx, policy)
+	if err != nil {
+		return err
+	}
+
+	s.reloadEscalations(ctx)
+
+	return writeJSON(w, http.StatusOK, Response{
+		Status:  StatusOK,
+		Message: "escalation policy updated",
+		Data: W{
+			"escalationPolicy": policy,
+		},
+	})
+}
+
+// handlerDeleteEscalationPolicyByID deletes the policy and its escalations
+func (s *Server) handlerDeleteEscalationPolicyByID(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := s.ServerContext(r.Context())
+	defer cancel()
+
+	policyID, err := ReadUUIDParam(r, "policyID")
+	if err != nil {
+		return err
+	}
+
+	policy, err := s.EscalationService.DeletePolicyByID(ctx, policyID)
+	if err != nil {
+		return err
+	}
+
+	s.reloadEscalations(ctx)
+
+	return writeJSON(w, http.StatusOK, Response{
+		Status:  StatusOK,
+		Message: "escalation policy deleted",
+		Data: W{
+			"escalationPolicy": policy,
+		},
+	})
+}
+
+// handlerGetIncidentEscalations returns the escalations of the incident
+func (s *Server) handlerGetIncidentEscalations(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := s.ServerContext(r.Context())
+	defer cancel()
+
+	incidentID, err := ReadUUIDParam(r, "incidentID")
+	if err != nil {
+		return err
+	}
+
+	escalationFilter := filter.NewDefaultEscalationFilter()
+	escalationFilter.IncidentID = &incidentID
+
+	escalations, err := s.EscalationService.ListEscalations(ctx, escalationFilter)
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(w, http.StatusOK, Response{
+		Status: StatusOK,
+		Data: W{
+			"escalations": escalations,
+			"pagination":  escalationFilter.Pagination,
+		},
+	})
+}
+
+func readEscalationFilter(qs url.Values, v *validator.Validator) *filter.EscalationFilter {
+	escalationFilter := filter.NewDefaultEscalationFilter()
+
+	if name := ReadString(qs, "name", ""); name != "" {
+		escalationFilter.Name = &name
+	}
+
+	if qs.Has("active") {
+		active := ReadBool(qs, "active")
+		escalationFilter.Active = &active
+	}
+
+	escalationFilter.Page = ReadInt(qs, "page", escalationFilter.Page, v)
+	escalationFilter.PageSize = ReadInt(qs, "page_size", escalationFilter.PageSize, v)
+	escalationFilter.Sort = ReadString(qs, "sort", escalationFilter.Sort)
+	filter.ValidateFilters(v, escalationFilter.Filter)
+	return escalationFilter
+}
+
+// reloadEscalations reloads the escalation policies after a policy has changed
+func (s *Server) reloadEscalations(ctx context.Context) {
+	if s.Escalations == nil {
+		return
+	}
+
+	if err := s.Escalations.Reload(ctx); err != nil {
+		s.log.Errorf("failed to reload escalation policies: %v", err)
+	}
+}
@@ -0,0 +1,78 @@
+package http
+
+import (
+	"net/http"
+	"net/url"
+
+	echosight "github.com/alexjoedt/echosight/internal"
+	"github.com/alexjoedt/echosight/internal/filter"
+	"github.com/alexjoedt/echosight/internal/validator"
+)
+
+// handlerGetDetectorHistory returns the state changes of a detector or with
+// type=results the logged check results.
+//
+// Query params: type (states, results), from, to, page, page_size, sort
+func (s *Server) handlerGetDetectorHistory(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := s.ServerContext(r.Context())
+	defer cancel()
+
+	host, err := s.checkForHost(ctx, r)
+	if err != nil {
+		return err
+	}
+
+	detectorID, err := ReadUUIDParam(r, "detectorID")
+	if err != nil {
+		return err
+	}
+
+	qs := r.URL.Query()
+	v := validator.New()
+
+	historyFilter, historyType := readHistoryFilter(qs, v)
+	historyFilter.HostID = &host.ID
+	historyFilter.DetectorID = &detectorID
+	if !v.Valid() {
+		return echosight.ErrInvalidf("invalid query params").WithData(v.Errors)
+	}
+
+	var data any
+	switch historyType {
+	case "results":
+		data, err = s.HistoryService.ListResults(ctx, historyFilter)
+	default:
+		data, err = s.HistoryService.ListStateChanges(ctx, historyFilter)
+	}
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(w, http.StatusOK, Response{
+		Status: StatusOK,
+		Data: W{
+			historyType:  data,
+			"pagination": historyFilter.Pagination,
+		},
+	})
+}
+
+// readHistoryFilter reads the common history query params
+func readHistoryFilter(qs url.Values, v *validator.Validator) (*filter.HistoryFilter, string) {
+	historyFilter := filter.NewDefaultHistoryFilter()
+	historyFilter.From = ReadDateTime(qs, "from", v)
+	historyFilter.To = ReadDateTime(qs, "to", v)
+	historyFilter.Page = ReadInt(qs, "page", historyFilter.Page, v)
+	historyFilter.PageSize = ReadInt(qs, "page_size", historyFilter.PageSize, v)
+	historyFilter.Sort = ReadString(qs, "sort", historyFilter.Sort)
+	filter.ValidateFilters(v, historyFilter.Filter)
+
+	if historyFilter.From != nil && historyFilter.To != nil {
+		v.Check(historyFilter.From.Before(*historyFilter.To), "from", "must be before to")
+	}
+
+	historyType := ReadString(qs, "type", "states")
+	v.Check(validator.PermittedValue(historyType, "states", "results"), "type", "must be states or results")
+
+	return historyFilter, historyType
+}
@@ -85,6 +85,8 @@ func (s *Server) handlerGetHostByID(w http.ResponseWriter, r *http.Request) erro
 		return err
 	}
 
+	s.setHostMaintenance(host)
+
 	return writeJSON(w, http.StatusOK, Response{
 		Status: StatusOK,
 		Data: W{
@@ -105,6 +107,8 @@ func (s *Server) handlerGetHosts(w http.ResponseWriter, r *http.Request) error {
 		return err
 	}
 
+	s.setHostMaintenance(hosts...)
+
 	return writeJSON(w, http.StatusOK, Response{
 		Status: StatusOK,
 		Data: W{
@@ -0,0 +1,274 @@
+package http
+
+import (
+	"net/http"
+	"net/url"
+
+	echosight "github.com/alexjoedt/echosight/internal"
+	"github.com/alexjoedt/echosight/internal/filter"
+	"github.com/alexjoedt/echosight/internal/validator"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+func (s *Server) registerIncidentRoutes(r *chi.Mux) {
+	r.With(s.requireAuth).Route("/incidents", func(r chi.Router) {
+		r.Get("/", makeHandlerFunc(s.handlerGetIncidents))
+		r.Get("/{incidentID}", makeHandlerFunc(s.handlerGetIncidentByID))
+		r.Post("/{incidentID}/acknowledge", makeHandlerFunc(s.handlerAcknowledgeIncident))
+		r.Post("/{incidentID}/assign", makeHandlerFunc(s.handlerAssignIncident))
+		r.Post("/{incidentID}/comments", makeHandlerFunc(s.handlerCommentIncident))
+		r.Post("/{incidentID}/resolve", makeHandlerFunc(s.handlerResolveIncident))
+	})
+}
+
+// handlerGetIncidents returns the incidents.
+//
+// Query params: status (open, acknowledged, resolved, unresolved), detector_id, host_id,
+// assignee_id, page, page_size, sort
+func (s *Server) handlerGetIncidents(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := s.ServerContext(r.Context())
+	defer cancel()
+
+	qs := r.URL.Query()
+	v := validator.New()
+	incidentFilter := readIncidentFilter(qs, v)
+	if !v.Valid() {
+		return echosight.ErrInvalidf("invalid query params").WithData(v.Errors)
+	}
+
+	incidents, err := s.IncidentService.List(ctx, incidentFilter)
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(w, http.StatusOK, Response{
+		Status: StatusOK,
+		Data: W{
+			"incidents":  incidents,
+			"pagination": incidentFilter.Pagination,
+		},
+	})
+}
+
+// handlerGetIncidentByID returns the incident including the timeline
+func (s *Server) handlerGetIncidentByID(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := s.ServerContext(r.Context())
+	defer cancel()
+
+	incidentID, err := ReadUUIDParam(r, "incidentID")
+	if err != nil {
+		return err
+	}
+
+	incident, err := s.IncidentService.GetByID(ctx, incidentID)
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(w, http.StatusOK, Response{
+		Status: StatusOK,
+		Data: W{
+			"incident": incident,
+		},
+	})
+}
+
+func (s *Server) handlerAcknowledgeIncident(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := s.ServerContext(r.Context())
+	defer cancel()
+
+	incidentID, err := ReadUUIDParam(r, "incidentID")
+	if err != nil {
+		return err
+	}
+
+	user, err := echosight.UserFromContext(r.Context())
+	if err != nil {
+		return err
+	}
+
+	incident, err := s.IncidentManager.Acknowledge(ctx, incidentID, user)
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(w, http.StatusOK, Response{
+		Status:  StatusOK,
+		Message: "incident acknowledged",
+		Data: W{
+			"incident": incident,
+		},
+	})
+}
+
+func (s *Server) handlerAssignIncident(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := s.ServerContext(r.Context())
+	defer cancel()
+
+	incidentID, err := ReadUUIDParam(r, "incidentID")
+	if err != nil {
+		return err
+	}
+
+	var input struct {
+		AssigneeID uuid.UUID `json:"assigneeId"`
+	}
+
+	err = readJSON(r, &input)
+	if err != nil {
+		s.log.Errorc("failed to read assign payload", err)
+		return err
+	}
+
+	v := validator.New()
+	v.Check(input.AssigneeID != uuid.Nil, "assigneeId", "must be provided")
+	if !v.Valid() {
+		return echosight.ErrInvalidf("invalid assign payload").WithData(v.Errors)
+	}
+
+	user, err := echosight.UserFromContext(r.Context())
+	if err != nil {
+		return err
+	}
+
+	assignee, err := s.UserService.GetByID(ctx, input.AssigneeID)
+	if err != nil {
+		return err
+	}
+
+	incident, err := s.IncidentManager.Assign(ctx, incidentID, user, assignee)
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(w, http.StatusOK, Response{
+		Status:  StatusOK,
+		Message: "incident assigned",
+		Data: W{
+			"incident": incident,
+		},
+	})
+}
+
+func (s *Server) handlerCommentIncident(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := s.ServerContext(r.Context())
+	defer cancel()
+
+	incidentID, err := ReadUUIDParam(r, "incidentID")
+	if err != nil {
+		return err
+	}
+
+	var input struct {
+		Message string `json:"message"`
+	}
+
+	err = readJSON(r, &input)
+	if err != nil {
+		s.log.Errorc("failed to read comment payload", err)
+		return err
+	}
+
+	v := validator.New()
+	v.Check(input.Message != "", "message", "must be provided")
+	if !v.Valid() {
+		return echosight.ErrInvalidf("invalid comment payload").WithData(v.Errors)
+	}
+
+	user, err := echosight.UserFromContext(r.Context())
+	if err != nil {
+		return err
+	}
+
+	entry, err := s.IncidentManager.Comment(ctx, incidentID, user, input.Message)
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(w, http.StatusOK, Response{
+		Status:  StatusOK,
+		Message: "comment added",
+		Data: W{
+			"entry": entry,
+		},
+	})
+}
+
+func (s *Server) handlerResolveIncident(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := s.ServerContext(r.Context())
+	defer cancel()
+
+	incidentID, err := ReadUUIDParam(r, "incidentID")
+	if err != nil {
+		return err
+	}
+
+	var input struct {
+		Message string `json:"message"`
+	}
+
+	// the message is optional, the body can be empty
+	if r.ContentLength != 0 {
+		err = readJSON(r, &input)
+		if err != nil {
+			s.log.Errorc("failed to read resolve payload", err)
+			return err
+		}
+	}
+
+	user, err := echosight.UserFromContext(r.Context())
+	if err != nil {
+		return err
+	}
+
+	incident, err := s.IncidentManager.Resolve(ctx, incidentID, user, input.Message)
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(w, http.StatusOK, Response{
+		Status:  StatusOK,
+		Message: "incident resolved",
+		Data: W{
+			"incident": incident,
+		},
+	})
+}
+
+func readIncidentFilter(qs url.Values, v *validator.Validator) *filter.IncidentFilter {
+	incidentFilter := filter.NewDefaultIncidentFilter()
+
+	for key, target := range map[string]**uuid.UUID{
+		"detector_id": &incidentFilter.DetectorID,
+		"host_id":     &incidentFilter.HostID,
+		"assignee_id": &incidentFilter.AssigneeID,
+	} {
+		if raw := qs.Get(key); raw != "" {
+			id, err := uuid.Parse(raw)
+			if err != nil {
+				v.AddError(key, "must be a valid uuid")
+				continue
+			}
+			*target = &id
+		}
+	}
+
+	status := ReadString(qs, "status", "")
+	switch status {
+	case "":
+	case "unresolved":
+		incidentFilter.Unresolved = true
+	default:
+		v.Check(validator.PermittedValue(echosight.IncidentStatus(status),
+			echosight.IncidentOpen, echosight.IncidentAcknowledged, echosight.IncidentResolved),
+			"status", "must be open, acknowledged, resolved or unresolved")
+		incidentFilter.Status = &status
+	}
+
+	incidentFilter.Page = ReadInt(qs, "page", incidentFilter.Page, v)
+	incidentFilter.PageSize = ReadInt(qs, "page_size", incidentFilter.PageSize, v)
+	incidentFilter.Sort = ReadString(qs, "sort", incidentFilter.Sort)
+	filter.ValidateFilters(v, incidentFilter.Filter)
+	return incidentFilter
+}
@@ -0,0 +1,375 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+
+	echosight "github.com/alexjoedt/echosight/internal"
+	"github.com/alexjoedt/echosight/internal/filter"
+	"github.com/alexjoedt/echosight/internal/validator"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+func (s *Server) registerMaintenanceRoutes(r *chi.Mux) {
+	r.With(s.requireAuth).Route("/maintenance", func(r chi.Router) {
+		r.Get("/", makeHandlerFunc(s.handlerGetMaintenanceWindows))
+		r.Post("/", makeHandlerFunc(s.handlerCreateMaintenanceWindow))
+		r.Get("/{maintenanceID}", makeHandlerFunc(s.handlerGetMaintenanceWindowByID))
+		r.Patch("/{maintenanceID}", makeHandlerFunc(s.handlerUpdateMaintenanceWindow))
+		r.Delete("/{maintenanceID}", makeHandlerFunc(s.handlerDeleteMaintenanceWindowByID))
+		r.Post("/{maintenanceID}/end", makeHandlerFunc(s.handlerEndMaintenanceWindow))
+	})
+}
+
+func (s *Server) handlerCreateMaintenanceWindow(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := s.ServerContext(r.Context())
+	defer cancel()
+
+	var input struct {
+		Name        string              `json:"name"`
+		Description string              `json:"description"`
+		HostID      *uuid.UUID          `json:"hostId"`
+		DetectorID  *uuid.UUID          `json:"detectorId"`
+		Tag         string              `json:"tag"`
+		StartsAt    *time.Time          `json:"startsAt"`
+		EndsAt      *time.Time          `json:"endsAt"`
+		Recurrence  string              `json:"recurrence"`
+		Duration    *echosight.Duration `json:"duration"`
+		Timezone    string              `json:"timezone"`
+	}
+
+	err := readJSON(r, &input)
+	if err != nil {
+		s.log.Errorc("failed to read maintenance window payload", err)
+		return err
+	}
+
+	window := echosight.MaintenanceWindow{
+		Name:        input.Name,
+		Description: input.Description,
+		HostID:      input.HostID,
+		DetectorID:  input.DetectorID,
+		Tag:         input.Tag,
+		StartsAt:    time.Now(),
+		EndsAt:      input.EndsAt,
+		Recurrence:  input.Recurrence,
+		Timezone:    input.Timezone,
+	}
+
+	if input.StartsAt != nil {
+		window.StartsAt = *input.StartsAt
+	}
+
+	if input.Duration != nil {
+		window.Duration = *input.Duration
+		// a one-off window can be created with a duration from now or startsAt
+		if window.EndsAt == nil && !window.IsRecurring() {
+			endsAt := window.StartsAt.Add(time.Duration(window.Duration))
+			window.EndsAt = &endsAt
+		}
+	}
+
+	if window.Timezone == "" {
+		window.Timezone = "UTC"
+	}
+
+	if user, err := echosight.UserFromContext(r.Context()); err == nil && !user.IsAnonymus() {
+		window.CreatedBy = &user.ID
+	}
+
+	v := validator.New()
+	echosight.ValidateMaintenanceWindow(v, &window)
+	if !v.Valid() {
+		return echosight.ErrInvalidf("invalid maintenance window payload").WithData(v.Errors)
+	}
+
+	err = s.MaintenanceService.Create(ctx, &window)
+	if err != nil {
+		return err
+	}
+
+	s.reloadMaintenance(ctx)
+	window.SetStatus(time.Now())
+
+	return writeJSON(w, http.StatusOK, Response{
+		Status:  StatusOK,
+		Message: "maintenance window created",
+		Data: W{
+			"maintenanceWindow": window,
+		},
+	})
+}
+
+func (s *Server) handlerGetMaintenanceWindowByID(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := s.ServerContext(r.Context())
+	defer cancel()
+
+	maintenanceID, err := ReadUUIDParam(r, "maintenanceID")
+	if err != nil {
+		return err
+	}
+
+	window, err := s.MaintenanceService.GetByID(ctx, maintenanceID)
+	if err != nil {
+		return err
+	}
+
+	window.SetStatus(time.Now())
+
+	return writeJSON(w, http.StatusOK, Response{
+		Status: StatusOK,
+		Data: W{
+			"maintenanceWindow": window,
+		},
+	})
+}
+
+// handlerGetMaintenanceWindows returns the maintenance windows.
+//
+// Query params: host_id, detector_id, tag, active (true returns only active windows)
+func (s *Server) handlerGetMaintenanceWindows(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := s.ServerContext(r.Context())
+	defer cancel()
+
+	qs := r.URL.Query()
+	v := validator.New()
+	maintenanceFilter := readMaintenanceFilter(qs, v)
+	if !v.Valid() {
+		return echosight.ErrInvalidf("invalid query params").WithData(v.Errors)
+	}
+
+	windows, err := s.MaintenanceService.List(ctx, maintenanceFilter)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	onlyActive := ReadBool(qs, "active")
+	result := make([]*echosight.MaintenanceWindow, 0, len(windows))
+	for _, window := range windows {
+		window.SetStatus(now)
+		if onlyActive && !window.Active {
+			continue
+		}
+		result = append(result, window)
+	}
+
+	return writeJSON(w, http.StatusOK, Response{
+		Status: StatusOK,
+		Data: W{
+			"maintenanceWindows": result,
+			"pagination":         maintenanceFilter.Pagination,
+		},
+	})
+}
+
+func (s *Server) handlerUpdateMaintenanceWindow(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := s.ServerContext(r.Context())
+	defer cancel()
+
+	maintenanceID, err := ReadUUIDParam(r, "maintenanceID")
+	if err != nil {
+		return err
+	}
+
+	var input struct {
+		Name        *string             `json:"name"`
+		Description *string             `json:"description"`
+		StartsAt    *time.Time          `json:"startsAt"`
+		EndsAt      *time.Time          `json:"endsAt"`
+		Recurrence  *string             `json:"recurrence"`
+		Duration    *echosight.Duration `json:"duration"`
+		Timezone    *string             `json:"timezone"`
+	}
+
+	err = readJSON(r, &input)
+	if err != nil {
+		s.log.Errorc("failed to read maintenance window payload", err)
+		return err
+	}
+
+	window, err := s.MaintenanceService.GetByID(ctx, maintenanceID)
+	if err != nil {
+		return err
+	}
+
+	if input.Name != nil {
+		window.Name = *input.Name
+	}
+
+	if input.Description != nil {
+		window.Description = *input.Description
+	}
+
+	if input.StartsAt != nil {
+		window.StartsAt = *input.StartsAt
+	}
+
+	if input.EndsAt != nil {
+		window.EndsAt = input.EndsAt
+	}
+
+	if input.Recurrence != nil {
+		window.Recurrence = *input.Recurrence
+	}
+
+	if input.Duration != nil {
+		window.Duration = *input.Duration
+	}
+
+	if input.Timezone != nil {
+		window.Timezone = *input.Timezone
+	}
+
+	v := validator.New()
+	echosight.ValidateMaintenanceWindow(v, window)
+	if !v.Valid() {
+		return echosight.ErrInvalidf("invalid maintenance window payload").WithData(v.Errors)
+	}
+
+	err = s.MaintenanceService.Update(ctx, window)
+	if err != nil {
+		return err
+	}
+
+	s.reloadMaintenance(ctx)
+	window.SetStatus(time.Now())
+
+	return writeJSON(w, http.StatusOK, Response{
+		Status:  StatusOK,
+		Message: "maintenance window updated",
+		Data: W{
+			"maintenanceWindow": window,
+		},
+	})
+}
+
+// handlerEndMaintenanceWindow ends an active window and all further occurrences now.
+// The window is kept, because it's excluded from the uptime.
+func (s *Server) handlerEndMaintenanceWindow(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := s.ServerContext(r.Context())
+	defer cancel()
+
+	maintenanceID, err := ReadUUIDParam(r, "maintenanceID")
+	if err != nil {
+		return err
+	}
+
+	window, err := s.MaintenanceService.GetByID(ctx, maintenanceID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if window.Finished(now) {
+		return echosight.ErrConflictf("maintenance window has already ended")
+	}
+
+	// a window which has not started yet, ends without any occurrence
+	endsAt := now
+	if endsAt.Before(window.StartsAt) {
+		endsAt = window.StartsAt
+	}
+	window.EndsAt = &endsAt
+
+	err = s.MaintenanceService.Update(ctx, window)
+	if err != nil {
+		return err
+	}
+
+	s.reloadMaintenance(ctx)
+	window.SetStatus(now)
+
+	return writeJSON(w, http.StatusOK, Response{
+		Status:  StatusOK,
+		Message: "maintenance window ended",
+		Data: W{
+			"maintenanceWindow": window,
+		},
+	})
+}
+
+func (s *Server) handlerDeleteMaintenanceWindowByID(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := s.ServerContext(r.Context())
+	defer cancel()
+
+	maintenanceID, err := ReadUUIDParam(r, "maintenanceID")
+	if err != nil {
+		return err
+	}
+
+	window, err := s.MaintenanceService.DeleteByID(ctx, maintenanceID)
+	if err != nil {
+		return err
+	}
+
+	s.reloadMaintenance(ctx)
+
+	return writeJSON(w, http.StatusOK, Response{
+		Status:  StatusOK,
+		Message: "maintenance window deleted",
+		Data: W{
+			"maintenanceWindow": window,
+		},
+	})
+}
+
+func readMaintenanceFilter(qs url.Values, v *validator.Validator) *filter.MaintenanceFilter {
+	maintenanceFilter := filter.NewDefaultMaintenanceFilter()
+
+	for key, target := range map[string]**uuid.UUID{
+		"host_id":     &maintenanceFilter.HostID,
+		"detector_id": &maintenanceFilter.DetectorID,
+	} {
+		if raw := qs.Get(key); raw != "" {
+			id, err := uuid.Parse(raw)
+			if err != nil {
+				v.AddError(key, "must be a valid uuid")
+				continue
+			}
+			*target = &id
+		}
+	}
+
+	if tag := ReadString(qs, "tag", ""); tag != "" {
+		maintenanceFilter.Tag = &tag
+	}
+
+	return maintenanceFilter
+}
+
+// reloadMaintenance reloads the maintenance calendar after a window has changed
+func (s *Server) reloadMaintenance(ctx context.Context) {
+	if s.Maintenance == nil {
+		return
+	}
+
+	if err := s.Maintenance.Reload(ctx); err != nil {
+		s.log.Errorf("failed to reload maintenance windows: %v", err)
+	}
+}
+
+// setDetectorMaintenance sets the active and upcoming maintenance windows of the detectors
+func (s *Server) setDetectorMaintenance(detectors ...*echosight.Detector) {
+	now := time.Now()
+	for _, d := range detectors {
+		d.MaintenanceWindows = s.Maintenance.DetectorWindows(d, now)
+	}
+}
+
+// setHostMaintenance sets the active and upcoming maintenance windows of the hosts and their detectors
+func (s *Server) setHostMaintenance(hosts ...*echosight.Host) {
+	now := time.Now()
+	for _, h := range hosts {
+		h.MaintenanceWindows = s.Maintenance.HostWindows(h, now)
+		for _, mw := range h.MaintenanceWindows {
+			if mw.Active {
+				h.InMaintenance = true
+			}
+		}
+		s.setDetectorMaintenance(h.Detectors...)
+	}
+}
@@ -0,0 +1,161 @@
+package http
+
+import (
+	"net/http"
+
+	echosight "github.com/alexjoedt/echosight/internal"
+	"github.com/alexjoedt/echosight/internal/filter"
+	"github.com/alexjoedt/echosight/internal/validator"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+func (s *Server) registerNotificationRoutes(r *chi.Mux) {
+	r.With(s.requireAuth).Route("/notifications", func(r chi.Router) {
+		r.Get("/", makeHandlerFunc(s.handlerGetNotifications))
+		r.Post("/test", makeHandlerFunc(s.handlerTestNotification))
+		r.Get("/{notificationID}", makeHandlerFunc(s.handlerGetNotificationByID))
+		r.Post("/{notificationID}/resend", makeHandlerFunc(s.handlerResendNotification))
+	})
+}
+
+// handlerGetNotifications returns the notifications of the outbox with the delivery status.
+//
+// Query params: status, channel, type, page, page_size, sort
+func (s *Server) handlerGetNotifications(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := s.ServerContext(r.Context())
+	defer cancel()
+
+	qs := r.URL.Query()
+	v := validator.New()
+	notificationFilter := filter.NewDefaultNotificationFilter()
+	if status := ReadString(qs, "status", ""); status != "" {
+		v.Check(validator.PermittedValue(echosight.DeliveryStatus(status), echosight.DeliveryPending, echosight.DeliveryDelivered, echosight.DeliveryDead), "status", "must be pending, delivered or dead")
+		notificationFilter.Status = &status
+	}
+	if channel := ReadString(qs, "channel", ""); channel != "" {
+		notificationFilter.Channel = &channel
+	}
+	if notificationType := ReadString(qs, "type", ""); notificationType != "" {
+		notificationFilter.Type = &notificationType
+	}
+	notificationFilter.Page = ReadInt(qs, "page", notificationFilter.Page, v)
+	notificationFilter.PageSize = ReadInt(qs, "page_size", notificationFilter.PageSize, v)
+	notificationFilter.Sort = ReadString(qs, "sort", notificationFilter.Sort)
+	filter.ValidateFilters(v, notificationFilter.Filter)
+	if !v.Valid() {
+		return echosight.ErrInvalidf("invalid query params").WithData(v.Errors)
+	}
+
+	notifications, err := s.NotificationService.List(ctx, notificationFilter)
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(w, http.StatusOK, Response{
+		Status: StatusOK,
+		Data: W{
+			"notifications": notifications,
+			"pagination":    notificationFilter.Pagination,
+		},
+	})
+}
+
+// handlerGetNotificationByID returns the notification with the log of the delivery attempts
+func (s *Server) handlerGetNotificationByID(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := s.ServerContext(r.Context())
+	defer cancel()
+
+	notificationID, err := ReadUUIDParam(r, "notificationID")
+	if err != nil {
+		return err
+	}
+
+	notification, err := s.NotificationService.GetByID(ctx, notificationID)
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(w, http.StatusOK, Response{
+		Status: StatusOK,
+		Data: W{
+			"notification": notification,
+		},
+	})
+}
+
+// handlerResendNotification schedules a delivered or dead notification for a new delivery
+func (s *Server) handlerResendNotification(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := s.ServerContext(r.Context())
+	defer cancel()
+
+	if s.Outbox == nil {
+		return echosight.ErrInternalf("notification outbox is not enabled")
+	}
+
+	notificationID, err := ReadUUIDParam(r, "notificationID")
+	if err != nil {
+		return err
+	}
+
+	notification, err := s.Outbox.Resend(ctx, notificationID)
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(w, http.StatusOK, Response{
+		Status:  StatusOK,
+		Message: "notification scheduled for resend",
+		Data: W{
+			"notification": notification,
+		},
+	})
+}
+
+// handlerTestNotification sends a sample result with the sender of the channel, also if it's disabled.
+// The test is sent immediately, without the outbox.
+//
+// Payload: channel, recipientId (optional, limits the test to the recipient)
+func (s *Server) handlerTestNotification(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := s.ServerContext(r.Context())
+	defer cancel()
+
+	if s.Notifier == nil {
+		return echosight.ErrInternalf("notifier is not initialized")
+	}
+
+	var input struct {
+		Channel     string     `json:"channel"`
+		RecipientID *uuid.UUID `json:"recipientId"`
+	}
+	err := readJSON(r, &input)
+	if err != nil {
+		s.log.Errorc("failed to read test notification payload", err)
+		return err
+	}
+
+	sender, ok := s.Notifier.Sender(input.Channel)
+	if !ok {
+		return echosight.ErrNotfoundf("no sender for channel '%s'", input.Channel)
+	}
+
+	result := sampleResult()
+	result.Notification = echosight.NotificationProblem
+	if input.RecipientID != nil {
+		if _, err := s.RecipientService.GetByID(ctx, *input.RecipientID); err != nil {
+			return err
+		}
+		result.Recipients = []uuid.UUID{*input.RecipientID}
+	}
+
+	if err := sender.Send(ctx, result); err != nil {
+		return echosight.ErrInvalidf("test notification failed").WithData(map[string]string{
+			input.Channel: err.Error(),
+		})
+	}
+
+	return writeJSON(w, http.StatusOK, Response{
+		Status:  StatusOK,
+		Message: "test notification sent",
+	})
+}
@@ -0,0 +1,524 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	echosight "github.com/alexjoedt/echosight/internal"
+	"github.com/alexjoedt/echosight/internal/filter"
+	"github.com/alexjoedt/echosight/internal/oncall"
+	"github.com/alexjoedt/echosight/internal/validator"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	// defaultUpcomingRange is the range of the upcoming shifts
+	defaultUpcomingRange = time.Hour * 24 * 7
+	// icalPast and icalFuture are the range of the exported shifts
+	icalPast   = time.Hour * 24 * 30
+	icalFuture = time.Hour * 24 * 90
+)
+
+func (s *Server) registerOnCallRoutes(r *chi.Mux) {
+	r.With(s.requireAuth).Route("/oncall", func(r chi.Router) {
+		r.Get("/schedules", makeHandlerFunc(s.handlerGetOnCallSchedules))
+		r.Post("/schedules", makeHandlerFunc(s.handlerCreateOnCallSchedule))
+		r.Get("/schedules/{scheduleID}", makeHandlerFunc(s.handlerGetOnCallScheduleByID))
+		r.Patch("/schedules/{scheduleID}", makeHandlerFunc(s.handlerUpdateOnCallSchedule))
+		r.Delete("/schedules/{scheduleID}", makeHandlerFunc(s.handlerDeleteOnCallScheduleByID))
+		r.Get("/schedules/{scheduleID}/current", makeHandlerFunc(s.handlerGetCurrentOnCall))
+		r.Get("/schedules/{scheduleID}/shifts", makeHandlerFunc(s.handlerGetOnCallShifts))
+		r.Get("/schedules/{scheduleID}/overrides", makeHandlerFunc(s.handlerGetOnCallOverrides))
+		r.Post("/schedules/{scheduleID}/overrides", makeHandlerFunc(s.handlerCreateOnCallOverride))
+		r.Delete("/schedules/{scheduleID}/overrides/{overrideID}", makeHandlerFunc(s.handlerDeleteOnCallOverride))
+		r.Get("/recipients/{recipientID}/calendar.ics", makeHandlerFunc(s.handlerGetOnCallICal))
+	})
+}
+
+func (s *Server) handlerCreateOnCallSchedule(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := s.ServerContext(r.Context())
+	defer cancel()
+
+	var input struct {
+		Name                string                  `json:"name"`
+		Description         string                  `json:"description"`
+		Timezone            string                  `json:"timezone"`
+		Layers              []echosight.OnCallLayer `json:"layers"`
+		FallbackRecipientID *uuid.UUID              `json:"fallbackRecipientId"`
+	}
+
+	err := readJSON(r, &input)
+	if err != nil {
+		s.log.Errorc("failed to read on-call schedule payload", err)
+		return err
+	}
+
+	schedule := echosight.OnCallSchedule{
+		Name:        input.Name,
+		Description: input.Description,
+		Timezone:    input.Timezone,
+		Layers:      input.Layers,
+	}
+
+	if input.FallbackRecipientID != nil && *input.FallbackRecipientID != uuid.Nil {
+		schedule.FallbackRecipientID = input.FallbackRecipientID
+	}
+
+	if schedule.Timezone == "" {
+		schedule.Timezone = "UTC"
+	}
+
+	v := validator.New()
+	echosight.ValidateOnCallSchedule(v, &schedule)
+	s.validateParticipants(ctx, v, &schedule)
+	if !v.Valid() {
+		return echosight.ErrInvalidf("invalid on-call schedule payload").WithData(v.Errors)
+	}
+
+	err = s.OnCallService.Create(ctx, &schedule)
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(w, http.StatusOK, Response{
+		Status:  StatusOK,
+		Message: "on-call schedule created",
+		Data: W{
+			"schedule": schedule,
+		},
+	})
+}
+
+func (s *Server) handlerGetOnCallScheduleByID(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := s.ServerContext(r.Context())
+	defer cancel()
+
+	scheduleID, err := ReadUUIDParam(r, "scheduleID")
+	if err != nil {
+		return err
+	}
+
+	schedule, err := s.OnCallService.GetByID(ctx, scheduleID)
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(w, http.StatusOK, Response{
+		Status: StatusOK,
+		Data: W{
+			"schedule": schedule,
+		},
+	})
+}
+
+// handlerGetOnCallSchedules returns the on-call schedules.
+//
+// Query params: name, page, page_size, sort
+func (s *Server) handlerGetOnCallSchedules(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := s.ServerContext(r.Context())
+	defer cancel()
+
+	qs := r.URL.Query()
+	v := validator.New()
+	oncallFilter := filter.NewDefaultOnCallFilter()
+	if name := ReadString(qs, "name", ""); name != "" {
+		oncallFilter.Name = &name
+	}
+	oncallFilter.Page = ReadInt(qs, "page", oncallFilter.Page, v)
+	oncallFilter.PageSize = ReadInt(qs, "page_size", oncallFilter.PageSize, v)
+	oncallFilter.Sort = ReadString(qs, "sort", oncallFilter.Sort)
+	filter.ValidateFilters(v, oncallFilter.Filter)
+	if !v.Valid() {
+		return echosight.ErrInvalidf("invalid query params").WithData(v.Errors)
+	}
+
+	schedules, err := s.OnCallService.List(ctx, oncallFilter)
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(w, http.StatusOK, Response{
+		Status: StatusOK,
+		Data: W{
+			"schedules":  schedules,
+			"pagination": oncallFilter.Pagination,
+		},
+	})
+}
+
+func (s *Server) handlerUpdateOnCallSchedule(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := s.ServerContext(r.Context())
+	defer cancel()
+
+	scheduleID, err := ReadUUIDParam(r, "scheduleID")
+	if err != nil {
+		return err
+	}
+
+	var input struct {
+		Name        *string                 `json:"name"`
+		Description *string                 `json:"description"`
+		Timezone    *string                 `json:"timezone"`
+		Layers      []echosight.OnCallLayer `json:"layers"`
+		// FallbackRecipientID removes the fallback with the nil uuid
+		FallbackRecipientID *uuid.UUID `json:"fallbackRecipientId"`
+	}
+
+	err = readJSON(r, &input)
+	if err != nil {
+		s.log.Errorc("failed to read on-call schedule payload", err)
+		return err
+	}
+
+	schedule, err := s.OnCallService.GetByID(ctx, scheduleID)
+	if err != nil {
+		return err
+	}
+
+	if input.Name != nil {
+		schedule.Name = *input.Name
+	}
+
+	if input.Description != nil {
+		schedule.Description = *input.Description
+	}
+
+	if input.Timezone != nil {
+		schedule.Timezone = *input.Timezone
+	}
+
+	if input.Layers != nil {
+		schedule.Layers = input.Layers
+	}
+
+	if input.FallbackRecipientID != nil {
+		schedule.FallbackRecipientID = input.FallbackRecipientID
+		if *input.FallbackRecipientID == uuid.Nil {
+			schedule.FallbackRecipientID = nil
+		}
+	}
+
+	v := validator.New()
+	echosight.ValidateOnCallSchedule(v, schedule)
+	s.validateParticipants(ctx, v, schedule)
+	if !v.Valid() {
+		return echosight.ErrInvalidf("invalid on-call schedule payload").WithData(v.Errors)
+	}
+
+	err = s.OnCallService.Update(ctx, schedule)
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(w, http.StatusOK, Response{
+		Status:  StatusOK,
+		Message: "on-call schedule updated",
+		Data: W{
+			"schedule": schedule,
+		},
+	})
+}
+
+func (s *Server) handlerDeleteOnCallScheduleByID(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := s.ServerContext(r.Context())
+	defer cancel()
+
+	scheduleID, err := ReadUUIDParam(r, "scheduleID")
+	if err != nil {
+		return err
+	}
+
+	schedule, err := s.OnCallService.DeleteByID(ctx, scheduleID)
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(w, http.StatusOK, Response{
+		Status:  StatusOK,
+		Message: "on-call schedule deleted",
+		Data: W{
+			"schedule": schedule,
+		},
+	})
+}
+
+// handlerGetCurrentOnCall returns the current and the upcoming shifts of the schedule.
+//
+// Query params: range (e.g. 24h, 7d), default 7d
+func (s *Server) handlerGetCurrentOnCall(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := s.ServerContext(r.Context())
+	defer cancel()
+
+	scheduleID, err := ReadUUIDParam(r, "scheduleID")
+	if err != nil {
+		return err
+	}
+
+	upcomingRange := defaultUpcomingRange
+	if raw := r.URL.Query().Get("range"); raw != "" {
+		v := validator.New()
+		upcomingRange, err = parseReportRange(raw)
+		v.Check(err == nil, "range", "must be a duration like 24h or 7d")
+		if !v.Valid() {
+			return echosight.ErrInvalidf("invalid query params").WithData(v.Errors)
+		}
+	}
+
+	schedule, err := s.OnCallService.GetByID(ctx, scheduleID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	current, err := s.OnCall.Current(ctx, schedule, now)
+	if err != nil {
+		return err
+	}
+
+	shifts, err := s.OnCall.Shifts(ctx, schedule, now, now.Add(upcomingRange))
+	if err != nil {
+		return err
+	}
+
+	// the first shift is the current shift
+	upcoming := shifts
+	if current != nil && len(upcoming) > 0 {
+		upcoming = upcoming[1:]
+	}
+
+	return writeJSON(w, http.StatusOK, Response{
+		Status: StatusOK,
+		Data: W{
+			"current":  current,
+			"upcoming": upcoming,
+		},
+	})
+}
+
+// handlerGetOnCallShifts returns the final shifts of the schedule including the overrides.
+//
+// Query params: from, to (RFC 3339), default the next 7 days
+func (s *Server) handlerGetOnCallShifts(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := s.ServerContext(r.Context())
+	defer cancel()
+
+	scheduleID, err := ReadUUIDParam(r, "scheduleID")
+	if err != nil {
+		return err
+	}
+
+	v := validator.New()
+	from, to := readShiftRange(r, v)
+	if !v.Valid() {
+		return echosight.ErrInvalidf("invalid query params").WithData(v.Errors)
+	}
+
+	schedule, err := s.OnCallService.GetByID(ctx, scheduleID)
+	if err != nil {
+		return err
+	}
+
+	shifts, err := s.OnCall.Shifts(ctx, schedule, from, to)
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(w, http.StatusOK, Response{
+		Status: StatusOK,
+		Data: W{
+			"from":   from,
+			"to":     to,
+			"shifts": shifts,
+		},
+	})
+}
+
+// handlerGetOnCallOverrides returns the overrides of the schedule.
+//
+// Query params: from, to (RFC 3339), default the next 7 days
+func (s *Server) handlerGetOnCallOverrides(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := s.ServerContext(r.Context())
+	defer cancel()
+
+	scheduleID, err := ReadUUIDParam(r, "scheduleID")
+	if err != nil {
+		return err
+	}
+
+	v := validator.New()
+	from, to := readShiftRange(r, v)
+	if !v.Valid() {
+		return echosight.ErrInvalidf("invalid query params").WithData(v.Errors)
+	}
+
+	oncallFilter := filter.NewDefaultOnCallFilter()
+	oncallFilter.ScheduleID = &scheduleID
+	oncallFilter.From = &from
+	oncallFilter.To = &to
+
+	overrides, err := s.OnCallService.ListOverrides(ctx, oncallFilter)
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(w, http.StatusOK, Response{
+		Status: StatusOK,
+		Data: W{
+			"overrides": overrides,
+		},
+	})
+}
+
+func (s *Server) handlerCreateOnCallOverride(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := s.ServerContext(r.Context())
+	defer cancel()
+
+	scheduleID, err := ReadUUIDParam(r, "scheduleID")
+	if err != nil {
+		return err
+	}
+
+	var input struct {
+		RecipientID uuid.UUID `json:"recipientId"`
+		StartsAt    time.Time `json:"startsAt"`
+		EndsAt      time.Time `json:"endsAt"`
+	}
+
+	err = readJSON(r, &input)
+	if err != nil {
+		s.log.Errorc("failed to read on-call override payload", err)
+		return err
+	}
+
+	// the schedule must exist
+	if _, err := s.OnCallService.GetByID(ctx, scheduleID); err != nil {
+		return err
+	}
+
+	override := echosight.OnCallOverride{
+		ScheduleID:  scheduleID,
+		RecipientID: input.RecipientID,
+		StartsAt:    input.StartsAt,
+		EndsAt:      input.EndsAt,
+	}
+
+	if user, err := echosight.UserFromContext(r.Context()); err == nil && !user.IsAnonymus() {
+		override.CreatedBy = &user.ID
+	}
+
+	v := validator.New()
+	echosight.ValidateOnCallOverride(v, &override)
+	if !v.Valid() {
+		return echosight.ErrInvalidf("invalid on-call override payload").WithData(v.Errors)
+	}
+
+	if _, err := s.RecipientService.GetByID(ctx, override.RecipientID); err != nil {
+		return err
+	}
+
+	err = s.OnCallService.CreateOverride(ctx, &override)
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(w, http.StatusOK, Response{
+		Status:  StatusOK,
+		Message: "on-call override created",
+		Data: W{
+			"override": override,
+		},
+	})
+}
+
+func (s *Server) handlerDeleteOnCallOverride(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := s.ServerContext(r.Context())
+	defer cancel()
+
+	scheduleID, err := ReadUUIDParam(r, "scheduleID")
+	if err != nil {
+		return err
+	}
+
+	overrideID, err := ReadUUIDParam(r, "overrideID")
+	if err != nil {
+		return err
+	}
+
+	override, err := s.OnCallService.DeleteOverride(ctx, scheduleID, overrideID)
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(w, http.StatusOK, Response{
+		Status:  StatusOK,
+		Message: "on-call override deleted",
+		Data: W{
+			"override": override,
+		},
+	})
+}
+
+// handlerGetOnCallICal exports the shifts of the recipient in all schedules as iCalendar,
+// from 30 days ago until 90 days ahead
+func (s *Server) handlerGetOnCallICal(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := s.ServerContext(r.Context())
+	defer cancel()
+
+	recipientID, err := ReadUUIDParam(r, "recipientID")
+	if err != nil {
+		return err
+	}
+
+	recipient, err := s.RecipientService.GetByID(ctx, recipientID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	shifts, err := s.OnCall.RecipientShifts(ctx, recipientID, now.Add(-icalPast), now.Add(icalFuture))
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "oncall.ics"))
+	w.Header().Set("Cache-Control", "no-store")
+	return oncall.WriteICal(w, fmt.Sprintf("On call: %s", recipient.Name), shifts)
+}
+
+// readShiftRange reads from and to, default the next 7 days
+func readShiftRange(r *http.Request, v *validator.Validator) (time.Time, time.Time) {
+	qs := r.URL.Query()
+	from := time.Now()
+	if fromParam := ReadDateTime(qs, "from", v); fromParam != nil {
+		from = *fromParam
+	}
+
+	to := from.Add(defaultUpcomingRange)
+	if toParam := ReadDateTime(qs, "to", v); toParam != nil {
+		to = *toParam
+	}
+
+	v.Check(from.Before(to), "from", "must be before to")
+	return from, to
+}
+
+// validateParticipants checks that the participants of all layers exist
+func (s *Server) validateParticipants(ctx context.Context, v *validator.Validator, schedule *echosight.OnCallSchedule) {
+	for i, layer := range schedule.Layers {
+		for _, id := range layer.Participants {
+			if _, err := s.RecipientService.GetByID(ctx, id); err != nil {
+				v.AddError(fmt.Sprintf("layers[%d].participants", i), fmt.Sprintf("recipient %s not found", id))
+			}
+		}
+	}
+
+	if schedule.FallbackRecipientID != nil {
+		if _, err := s.RecipientService.GetByID(ctx, *schedule.FallbackRecipientID); err != nil {
+			v.AddError("fallbackRecipientId", fmt.Sprintf("recipient %s not found", *schedule.FallbackRecipientID))
+		}
+	}
+}
@@ -29,7 +29,7 @@ func (s *Server) handlerCreatePreferences(w http.ResponseWriter, r *http.Request
 		return err
 	}
 
-	input.CryptValues(s.Crypter, "password", "key", "secret", "token")
+	input.CryptValues(s.Crypter, "password", "key", "secret", "token", "webhook_url")
 	v := validator.New()
 	input.Validate(v)
 
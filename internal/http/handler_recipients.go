@@ -2,6 +2,9 @@ package http
 
 import (
 	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
 	"time"
 
 	echosight "github.com/alexjoedt/echosight/internal"
@@ -11,13 +14,19 @@ import (
 	"github.com/google/uuid"
 )
 
+// ntfyTopicRX matches the topic names of ntfy
+var ntfyTopicRX = regexp.MustCompile("^[-_A-Za-z0-9]{1,64}$")
+
 func (s *Server) handlerCreateRecipient(w http.ResponseWriter, r *http.Request) error {
 	ctx, cancel := s.ServerContext(r.Context())
 	defer cancel()
 
 	var input struct {
-		Name  string `json:"name"`
-		Email string `json:"email"`
+		Name      string `json:"name"`
+		Email     string `json:"email"`
+		NtfyTopic string `json:"ntfy_topic"`
+		// GotifyToken is encrypted before it's stored
+		GotifyToken string `json:"gotify_token"`
 	}
 
 	err := readJSON(r, &input)
@@ -27,8 +36,14 @@ func (s *Server) handlerCreateRecipient(w http.ResponseWriter, r *http.Request)
 	}
 
 	recipient := echosight.Recipient{
-		Name:  input.Name,
-		Email: input.Email,
+		Name:      input.Name,
+		Email:     input.Email,
+		NtfyTopic: input.NtfyTopic,
+	}
+
+	err = setGotifyToken(&recipient, input.GotifyToken, s.Crypter)
+	if err != nil {
+		return err
 	}
 
 	v := validator.New()
@@ -119,6 +134,9 @@ func (s *Server) handlerUpdateRecipient(w http.ResponseWriter, r *http.Request)
 		Name      *string `json:"name"`
 		Email     *string `json:"email"`
 		Activated *bool   `json:"activated"`
+		NtfyTopic *string `json:"ntfy_topic"`
+		// GotifyToken is encrypted before it's stored, an empty token removes it
+		GotifyToken *string `json:"gotify_token"`
 	}
 
 	err = readJSON(r, &input)
@@ -144,6 +162,17 @@ func (s *Server) handlerUpdateRecipient(w http.ResponseWriter, r *http.Request)
 		recipient.Activated = *input.Activated
 	}
 
+	if input.NtfyTopic != nil {
+		recipient.NtfyTopic = *input.NtfyTopic
+	}
+
+	if input.GotifyToken != nil {
+		err = setGotifyToken(recipient, *input.GotifyToken, s.Crypter)
+		if err != nil {
+			return err
+		}
+	}
+
 	recipient.UpdatedAt = time.Now()
 
 	v := validator.New()
@@ -197,4 +226,28 @@ func (s *Server) handlerDeleteRecipientByID(w http.ResponseWriter, r *http.Reque
 func ValidateRecipient(v *validator.Validator, recipient *echosight.Recipient) {
 	v.Check(len(recipient.Name) > 3, "name", "name too short")
 	v.Check(validator.IsEmail(recipient.Email), "email", "invalid email")
+
+	if recipient.NtfyTopic != "" && strings.Contains(recipient.NtfyTopic, "://") {
+		u, err := url.Parse(recipient.NtfyTopic)
+		v.Check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "", "ntfy_topic", "must be a topic name or a valid http or https url")
+	} else if recipient.NtfyTopic != "" {
+		v.Check(validator.Matches(recipient.NtfyTopic, ntfyTopicRX), "ntfy_topic", "must be a topic name or a valid http or https url")
+	}
+}
+
+// setGotifyToken encrypts the token, an empty token removes it
+func setGotifyToken(recipient *echosight.Recipient, token string, crypter echosight.Crypter) error {
+	recipient.GotifyTokenCrypt = ""
+	recipient.HasGotifyToken = false
+	if token == "" {
+		return nil
+	}
+
+	crypted, err := crypter.Encrypt(token)
+	if err != nil {
+		return echosight.ErrInternalf("failed to encrypt gotify token").WithError(err)
+	}
+	recipient.GotifyTokenCrypt = crypted
+	recipient.HasGotifyToken = true
+	return nil
 }
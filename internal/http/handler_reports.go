@@ -0,0 +1,201 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	echosight "github.com/alexjoedt/echosight/internal"
+	"github.com/alexjoedt/echosight/internal/report"
+	"github.com/alexjoedt/echosight/internal/validator"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+func (s *Server) registerReportRoutes(r *chi.Mux) {
+	r.With(s.requireAuth).Route("/reports", func(r chi.Router) {
+		r.Get("/uptime", makeHandlerFunc(s.handlerGetUptimeReport))
+	})
+}
+
+// handlerGetUptimeReport returns the uptime report for a detector, a host or a tag.
+//
+// Query params:
+//   - scope: detector_id, host_id, tag (without scope all detectors)
+//   - time range: range (24h, 7d, 30d), month (YYYY-MM) or from and to
+//   - tz: timezone for the calendar month, default UTC
+//   - format: json (default) or csv
+func (s *Server) handlerGetUptimeReport(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := s.ServerContext(r.Context())
+	defer cancel()
+
+	qs := r.URL.Query()
+	v := validator.New()
+
+	scope := readReportScope(qs, v)
+	from, to := readReportRange(qs, v)
+	format := ReadString(qs, "format", "json")
+	v.Check(validator.PermittedValue(format, "json", "csv"), "format", "must be json or csv")
+	if !v.Valid() {
+		return echosight.ErrInvalidf("invalid query params").WithData(v.Errors)
+	}
+
+	reporter := report.Reporter{
+		Detectors:  s.DetectorService,
+		History:    s.HistoryService,
+		Exclusions: s.Maintenance,
+	}
+
+	total, reports, err := reporter.Uptime(ctx, scope, from, to)
+	if err != nil {
+		return err
+	}
+
+	if format == "csv" {
+		filename := fmt.Sprintf("uptime_%s_%s.csv", from.Format(time.DateOnly), to.Format(time.DateOnly))
+		return writeCSV(w, http.StatusOK, filename, uptimeCSV(total, reports))
+	}
+
+	return writeJSON(w, http.StatusOK, Response{
+		Status: StatusOK,
+		Data: W{
+			"report":    total,
+			"detectors": reports,
+		},
+	})
+}
+
+func readReportScope(qs url.Values, v *validator.Validator) report.Scope {
+	var scope report.Scope
+	for key, target := range map[string]**uuid.UUID{
+		"detector_id": &scope.DetectorID,
+		"host_id":     &scope.HostID,
+	} {
+		if raw := qs.Get(key); raw != "" {
+			id, err := uuid.Parse(raw)
+			if err != nil {
+				v.AddError(key, "must be a valid uuid")
+				continue
+			}
+			*target = &id
+		}
+	}
+
+	if tag := qs.Get("tag"); tag != "" {
+		scope.Tag = &tag
+	}
+
+	return scope
+}
+
+// readReportRange reads the time range of a report.
+// A calendar month (month=2024-03) or from/to takes precedence over range.
+// The end of the range is limited to now.
+func readReportRange(qs url.Values, v *validator.Validator) (time.Time, time.Time) {
+	now := time.Now()
+
+	loc := time.UTC
+	if tz := qs.Get("tz"); tz != "" {
+		l, err := time.LoadLocation(tz)
+		if err != nil {
+			v.AddError("tz", "invalid timezone")
+		} else {
+			loc = l
+		}
+	}
+
+	var from, to time.Time
+	switch {
+	case qs.Get("month") != "":
+		month, err := time.ParseInLocation("2006-01", qs.Get("month"), loc)
+		if err != nil {
+			v.AddError("month", "must be in the format YYYY-MM")
+			return from, to
+		}
+		from = month
+		to = month.AddDate(0, 1, 0)
+
+	case qs.Get("from") != "":
+		fromParam := ReadDateTime(qs, "from", v)
+		toParam := ReadDateTime(qs, "to", v)
+		if fromParam == nil {
+			return from, to
+		}
+		from = *fromParam
+		to = now
+		if toParam != nil {
+			to = *toParam
+		}
+
+	default:
+		d, err := parseReportRange(ReadString(qs, "range", "24h"))
+		if err != nil {
+			v.AddError("range", "must be a duration like 24h, 7d or 30d")
+			return from, to
+		}
+		from = now.Add(-d)
+		to = now
+	}
+
+	if to.After(now) {
+		to = now
+	}
+
+	v.Check(from.Before(to), "from", "must be before to and not in the future")
+	return from, to
+}
+
+// parseReportRange parses a duration with additional support for days, e.g. 7d
+func parseReportRange(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid range: '%s'", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid range: '%s'", s)
+	}
+	return d, nil
+}
+
+func uptimeCSV(total *echosight.UptimeReport, reports []*echosight.UptimeReport) [][]string {
+	records := [][]string{{
+		"detector_id", "detector_name", "host_id", "host_name", "from", "to",
+		"availability", "uptime_seconds", "downtime_seconds", "excluded_seconds",
+		"incidents", "mttr_seconds", "mtbf_seconds",
+	}}
+
+	row := func(r *echosight.UptimeReport, detectorID string, detectorName string) []string {
+		hostID := ""
+		if r.HostID != nil {
+			hostID = r.HostID.String()
+		}
+
+		return []string{
+			detectorID, detectorName, hostID, r.HostName,
+			r.From.Format(time.RFC3339), r.To.Format(time.RFC3339),
+			strconv.FormatFloat(r.Availability, 'f', 4, 64),
+			seconds(r.Uptime), seconds(r.Downtime), seconds(r.Excluded),
+			strconv.Itoa(r.Incidents),
+			seconds(r.MTTR), seconds(r.MTBF),
+		}
+	}
+
+	for _, r := range reports {
+		records = append(records, row(r, r.DetectorID.String(), r.DetectorName))
+	}
+	records = append(records, row(total, "", "total"))
+
+	return records
+}
+
+func seconds(d echosight.Duration) string {
+	return strconv.FormatFloat(time.Duration(d).Seconds(), 'f', 0, 64)
+}
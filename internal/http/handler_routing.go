@@ -0,0 +1,347 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	echosight "github.com/alexjoedt/echosight/internal"
+	"github.com/alexjoedt/echosight/internal/filter"
+	"github.com/alexjoedt/echosight/internal/validator"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+func (s *Server) registerRoutingRoutes(r *chi.Mux) {
+	r.With(s.requireAuth).Route("/routing-rules", func(r chi.Router) {
+		r.Get("/", makeHandlerFunc(s.handlerGetRoutingRules))
+		r.Post("/", makeHandlerFunc(s.handlerCreateRoutingRule))
+		r.Get("/match", makeHandlerFunc(s.handlerMatchRoutingRules))
+		r.Get("/{ruleID}", makeHandlerFunc(s.handlerGetRoutingRuleByID))
+		r.Patch("/{ruleID}", makeHandlerFunc(s.handlerUpdateRoutingRule))
+		r.Delete("/{ruleID}", makeHandlerFunc(s.handlerDeleteRoutingRuleByID))
+	})
+}
+
+type routingRuleInput struct {
+	Name        *string               `json:"name"`
+	Position    *int                  `json:"position"`
+	Active      *bool                 `json:"active"`
+	Continue    *bool                 `json:"continue"`
+	HostIDs     []uuid.UUID           `json:"hostIds"`
+	DetectorIDs []uuid.UUID           `json:"detectorIds"`
+	Tags        []string              `json:"tags"`
+	States      []echosight.State     `json:"states"`
+	TimeOfDay   *echosight.ClockRange `json:"timeOfDay"`
+	Timezone    *string               `json:"timezone"`
+	Channels    []string              `json:"channels"`
+	Recipients  []uuid.UUID           `json:"recipients"`
+	Schedules   []uuid.UUID           `json:"schedules"`
+	Webhooks    []uuid.UUID           `json:"webhooks"`
+	Digest      *echosight.Duration   `json:"digest"`
+}
+
+// apply sets the provided fields on the rule
+func (input *routingRuleInput) apply(rule *echosight.RoutingRule) {
+	if input.Name != nil {
+		rule.Name = *input.Name
+	}
+
+	if input.Position != nil {
+		rule.Position = *input.Position
+	}
+
+	if input.Active != nil {
+		rule.Active = *input.Active
+	}
+
+	if input.Continue != nil {
+		rule.Continue = *input.Continue
+	}
+
+	if input.HostIDs != nil {
+		rule.HostIDs = input.HostIDs
+	}
+
+	if input.DetectorIDs != nil {
+		rule.DetectorIDs = input.DetectorIDs
+	}
+
+	if input.Tags != nil {
+		rule.Tags = input.Tags
+	}
+
+	if input.States != nil {
+		rule.States = input.States
+	}
+
+	if input.TimeOfDay != nil {
+		// an empty range removes the time of day
+		if input.TimeOfDay.Start == "" && input.TimeOfDay.End == "" {
+			rule.TimeOfDay = nil
+		} else {
+			rule.TimeOfDay = input.TimeOfDay
+		}
+	}
+
+	if input.Timezone != nil {
+		rule.Timezone = *input.Timezone
+	}
+
+	if input.Channels != nil {
+		rule.Channels = input.Channels
+	}
+
+	if input.Recipients != nil {
+		rule.Recipients = input.Recipients
+	}
+
+	if input.Schedules != nil {
+		rule.Schedules = input.Schedules
+	}
+
+	if input.Webhooks != nil {
+		rule.Webhooks = input.Webhooks
+	}
+
+	if input.Digest != nil {
+		rule.Digest = *input.Digest
+	}
+}
+
+func (s *Server) handlerCreateRoutingRule(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := s.ServerContext(r.Context())
+	defer cancel()
+
+	var input routingRuleInput
+	err := readJSON(r, &input)
+	if err != nil {
+		s.log.Errorc("failed to read routing rule payload", err)
+		return err
+	}
+
+	rule := echosight.RoutingRule{
+		Active:   true,
+		Timezone: "UTC",
+	}
+	input.apply(&rule)
+
+	v := validator.New()
+	echosight.ValidateRoutingRule(v, &rule)
+	if !v.Valid() {
+		return echosight.ErrInvalidf("invalid routing rule payload").WithData(v.Errors)
+	}
+
+	err = s.RoutingService.Create(ctx, &rule)
+	if err != nil {
+		return err
+	}
+
+	s.reloadRouting(ctx)
+
+	return writeJSON(w, http.StatusOK, Response{
+		Status:  StatusOK,
+		Message: "routing rule created",
+		Data: W{
+			"routingRule": rule,
+		},
+	})
+}
+
+func (s *Server) handlerGetRoutingRuleByID(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := s.ServerContext(r.Context())
+	defer cancel()
+
+	ruleID, err := ReadUUIDParam(r, "ruleID")
+	if err != nil {
+		return err
+	}
+
+	rule, err := s.RoutingService.GetByID(ctx, ruleID)
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(w, http.StatusOK, Response{
+		Status: StatusOK,
+		Data: W{
+			"routingRule": rule,
+		},
+	})
+}
+
+// handlerGetRoutingRules returns the routing rules sorted by position.
+//
+// Query params: name, active, page, page_size, sort
+func (s *Server) handlerGetRoutingRules(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := s.ServerContext(r.Context())
+	defer cancel()
+
+	qs := r.URL.Query()
+	v := validator.New()
+	routingFilter := filter.NewDefaultRoutingFilter()
+	if name := ReadString(qs, "name", ""); name != "" {
+		routingFilter.Name = &name
+	}
+	if qs.Has("active") {
+		active := ReadBool(qs, "active")
+		routingFilter.Active = &active
+	}
+	routingFilter.Page = ReadInt(qs, "page", routingFilter.Page, v)
+	routingFilter.PageSize = ReadInt(qs, "page_size", routingFilter.PageSize, v)
+	routingFilter.Sort = ReadString(qs, "sort", routingFilter.Sort)
+	filter.ValidateFilters(v, routingFilter.Filter)
+	if !v.Valid() {
+		return echosight.ErrInvalidf("invalid query params").WithData(v.Errors)
+	}
+
+	rules, err := s.RoutingService.List(ctx, routingFilter)
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(w, http.StatusOK, Response{
+		Status: StatusOK,
+		Data: W{
+			"routingRules": rules,
+			"pagination":   routingFilter.Pagination,
+		},
+	})
+}
+
+func (s *Server) handlerUpdateRoutingRule(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := s.ServerContext(r.Context())
+	defer cancel()
+
+	ruleID, err := ReadUUIDParam(r, "ruleID")
+	if err != nil {
+		return err
+	}
+
+	var input routingRuleInput
+	err = readJSON(r, &input)
+	if err != nil {
+		s.log.Errorc("failed to read routing rule payload", err)
+		return err
+	}
+
+	rule, err := s.RoutingService.GetByID(ctx, ruleID)
+	if err != nil {
+		return err
+	}
+	input.apply(rule)
+
+	v := validator.New()
+	echosight.ValidateRoutingRule(v, rule)
+	if !v.Valid() {
+		return echosight.ErrInvalidf("invalid routing rule payload").WithData(v.Errors)
+	}
+
+	err = s.RoutingService.Update(ctx, rule)
+	if err != nil {
+		return err
+	}
+
+	s.reloadRouting(ctx)
+
+	return writeJSON(w, http.StatusOK, Response{
+		Status:  StatusOK,
+		Message: "routing rule updated",
+		Data: W{
+			"routingRule": rule,
+		},
+	})
+}
+
+func (s *Server) handlerDeleteRoutingRuleByID(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := s.ServerContext(r.Context())
+	defer cancel()
+
+	ruleID, err := ReadUUIDParam(r, "ruleID")
+	if err != nil {
+		return err
+	}
+
+	rule, err := s.RoutingService.DeleteByID(ctx, ruleID)
+	if err != nil {
+		return err
+	}
+
+	s.reloadRouting(ctx)
+
+	return writeJSON(w, http.StatusOK, Response{
+		Status:  StatusOK,
+		Message: "routing rule deleted",
+		Data: W{
+			"routingRule": rule,
+		},
+	})
+}
+
+// handlerMatchRoutingRules returns the active rules which match a result of the detector.
+// Without a matching rule, the notification is sent to all senders and recipients.
+//
+// Query params: detector_id (required), state (default CRITICAL), at (RFC 3339, default now)
+func (s *Server) handlerMatchRoutingRules(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := s.ServerContext(r.Context())
+	defer cancel()
+
+	qs := r.URL.Query()
+	v := validator.New()
+
+	detectorID, err := uuid.Parse(qs.Get("detector_id"))
+	v.Check(err == nil, "detector_id", "must be a valid uuid")
+
+	state := echosight.State(ReadString(qs, "state", string(echosight.StateCritical)))
+	v.Check(validator.PermittedValue(state, echosight.StateOK, echosight.StateWarn, echosight.StateCritical), "state", "must be OK, WARN or CRITICAL")
+
+	at := time.Now()
+	if atParam := ReadDateTime(qs, "at", v); atParam != nil {
+		at = *atParam
+	}
+
+	if !v.Valid() {
+		return echosight.ErrInvalidf("invalid query params").WithData(v.Errors)
+	}
+
+	detector, err := s.DetectorService.GetByID(ctx, detectorID)
+	if err != nil {
+		return err
+	}
+
+	// the stored rules are used, so the result doesn't depend on a pending reload
+	routingFilter := filter.NewDefaultRoutingFilter()
+	active := true
+	routingFilter.Active = &active
+	rules, err := s.RoutingService.List(ctx, routingFilter)
+	if err != nil {
+		return err
+	}
+
+	matched := echosight.MatchRoutingRules(rules, detector, state, at)
+	if matched == nil {
+		matched = make([]*echosight.RoutingRule, 0)
+	}
+
+	return writeJSON(w, http.StatusOK, Response{
+		Status: StatusOK,
+		Data: W{
+			"detectorId":   detector.ID,
+			"state":        state,
+			"at":           at,
+			"routingRules": matched,
+			"broadcast":    len(matched) == 0,
+		},
+	})
+}
+
+// reloadRouting reloads the routing rules after a rule has changed
+func (s *Server) reloadRouting(ctx context.Context) {
+	if s.Routing == nil {
+		return
+	}
+
+	if err := s.Routing.Reload(ctx); err != nil {
+		s.log.Errorf("failed to reload routing rules: %v", err)
+	}
+}
@@ -0,0 +1,298 @@
+package http
+
+import (
+	"net/http"
+
+	echosight "github.com/alexjoedt/echosight/internal"
+	"github.com/alexjoedt/echosight/internal/filter"
+	"github.com/alexjoedt/echosight/internal/validator"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+func (s *Server) registerSLORoutes(r *chi.Mux) {
+	r.With(s.requireAuth).Route("/slos", func(r chi.Router) {
+		r.Get("/", makeHandlerFunc(s.handlerGetSLOs))
+		r.Post("/", makeHandlerFunc(s.handlerCreateSLO))
+		r.Get("/{sloID}", makeHandlerFunc(s.handlerGetSLOByID))
+		r.Patch("/{sloID}", makeHandlerFunc(s.handlerUpdateSLO))
+		r.Delete("/{sloID}", makeHandlerFunc(s.handlerDeleteSLOByID))
+		r.Get("/{sloID}/status", makeHandlerFunc(s.handlerGetSLOStatus))
+	})
+}
+
+func (s *Server) handlerCreateSLO(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := s.ServerContext(r.Context())
+	defer cancel()
+
+	var input struct {
+		Name       string                   `json:"name"`
+		DetectorID *uuid.UUID               `json:"detectorId"`
+		Tag        string                   `json:"tag"`
+		Type       echosight.SLOType        `json:"type"`
+		Objective  float64                  `json:"objective"`
+		Window     *echosight.Duration      `json:"window"`
+		Threshold  *echosight.ThresholdRule `json:"threshold"`
+		Alerts     *bool                    `json:"alerts"`
+	}
+
+	err := readJSON(r, &input)
+	if err != nil {
+		s.log.Errorc("failed to read slo payload", err)
+		return err
+	}
+
+	slo := echosight.SLO{
+		Name:       input.Name,
+		DetectorID: input.DetectorID,
+		Tag:        input.Tag,
+		Type:       input.Type,
+		Objective:  input.Objective,
+		Window:     echosight.DefaultSLOWindow,
+		Threshold:  input.Threshold,
+		Alerts:     true,
+	}
+
+	if input.Window != nil {
+		slo.Window = *input.Window
+	}
+
+	if input.Alerts != nil {
+		slo.Alerts = *input.Alerts
+	}
+
+	v := validator.New()
+	echosight.ValidateSLO(v, &slo)
+	if !v.Valid() {
+		return echosight.ErrInvalidf("invalid slo payload").WithData(v.Errors)
+	}
+
+	err = s.SLOService.Create(ctx, &slo)
+	if err != nil {
+		return err
+	}
+
+	s.evaluateSLO()
+
+	return writeJSON(w, http.StatusOK, Response{
+		Status:  StatusOK,
+		Message: "slo created",
+		Data: W{
+			"slo": slo,
+		},
+	})
+}
+
+func (s *Server) handlerGetSLOByID(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := s.ServerContext(r.Context())
+	defer cancel()
+
+	sloID, err := ReadUUIDParam(r, "sloID")
+	if err != nil {
+		return err
+	}
+
+	slo, err := s.SLOService.GetByID(ctx, sloID)
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(w, http.StatusOK, Response{
+		Status: StatusOK,
+		Data: W{
+			"slo": slo,
+		},
+	})
+}
+
+// handlerGetSLOStatus returns the current status and the error budget of the SLO
+func (s *Server) handlerGetSLOStatus(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := s.ServerContext(r.Context())
+	defer cancel()
+
+	sloID, err := ReadUUIDParam(r, "sloID")
+	if err != nil {
+		return err
+	}
+
+	slo, err := s.SLOService.GetByID(ctx, sloID)
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(w, http.StatusOK, Response{
+		Status: StatusOK,
+		Data: W{
+			"status":      slo.Status,
+			"objective":   slo.Objective,
+			"window":      slo.Window,
+			"errorBudget": slo.ErrorBudget() * 100,
+		},
+	})
+}
+
+func (s *Server) handlerGetSLOs(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := s.ServerContext(r.Context())
+	defer cancel()
+
+	qs := r.URL.Query()
+	v := validator.New()
+
+	sloFilter := filter.NewDefaultSLOFilter()
+	if name := ReadString(qs, "name", ""); name != "" {
+		sloFilter.Name = &name
+	}
+
+	if tag := ReadString(qs, "tag", ""); tag != "" {
+		sloFilter.Tag = &tag
+	}
+
+	if raw := qs.Get("detector_id"); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			v.AddError("detector_id", "must be a valid uuid")
+		} else {
+			sloFilter.DetectorID = &id
+		}
+	}
+
+	if !v.Valid() {
+		return echosight.ErrInvalidf("invalid query params").WithData(v.Errors)
+	}
+
+	slos, err := s.SLOService.List(ctx, sloFilter)
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(w, http.StatusOK, Response{
+		Status: StatusOK,
+		Data: W{
+			"slos":       slos,
+			"pagination": sloFilter.Pagination,
+		},
+	})
+}
+
+func (s *Server) handlerUpdateSLO(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := s.ServerContext(r.Context())
+	defer cancel()
+
+	sloID, err := ReadUUIDParam(r, "sloID")
+	if err != nil {
+		return err
+	}
+
+	var input struct {
+		Name       *string                  `json:"name"`
+		DetectorID *uuid.UUID               `json:"detectorId"`
+		Tag        *string                  `json:"tag"`
+		Type       *echosight.SLOType       `json:"type"`
+		Objective  *float64                 `json:"objective"`
+		Window     *echosight.Duration      `json:"window"`
+		Threshold  *echosight.ThresholdRule `json:"threshold"`
+		Alerts     *bool                    `json:"alerts"`
+	}
+
+	err = readJSON(r, &input)
+	if err != nil {
+		s.log.Errorc("failed to read slo payload", err)
+		return err
+	}
+
+	slo, err := s.SLOService.GetByID(ctx, sloID)
+	if err != nil {
+		return err
+	}
+
+	if input.Name != nil {
+		slo.Name = *input.Name
+	}
+
+	// the scope is either a detector or a tag
+	if input.DetectorID != nil {
+		slo.DetectorID = input.DetectorID
+		slo.Tag = ""
+	}
+
+	if input.Tag != nil {
+		slo.Tag = *input.Tag
+		if slo.Tag != "" {
+			slo.DetectorID = nil
+		}
+	}
+
+	if input.Type != nil {
+		slo.Type = *input.Type
+	}
+
+	if input.Objective != nil {
+		slo.Objective = *input.Objective
+	}
+
+	if input.Window != nil {
+		slo.Window = *input.Window
+	}
+
+	if input.Threshold != nil {
+		slo.Threshold = input.Threshold
+	}
+
+	if input.Alerts != nil {
+		slo.Alerts = *input.Alerts
+	}
+
+	v := validator.New()
+	echosight.ValidateSLO(v, slo)
+	if !v.Valid() {
+		return echosight.ErrInvalidf("invalid slo payload").WithData(v.Errors)
+	}
+
+	err = s.SLOService.Update(ctx, slo)
+	if err != nil {
+		return err
+	}
+
+	s.evaluateSLO()
+
+	return writeJSON(w, http.StatusOK, Response{
+		Status:  StatusOK,
+		Message: "slo updated",
+		Data: W{
+			"slo": slo,
+		},
+	})
+}
+
+func (s *Server) handlerDeleteSLOByID(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := s.ServerContext(r.Context())
+	defer cancel()
+
+	sloID, err := ReadUUIDParam(r, "sloID")
+	if err != nil {
+		return err
+	}
+
+	slo, err := s.SLOService.DeleteByID(ctx, sloID)
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(w, http.StatusOK, Response{
+		Status:  StatusOK,
+		Message: "slo deleted",
+		Data: W{
+			"slo": slo,
+		},
+	})
+}
+
+// evaluateSLO lets the tracker calculate the status of a new or changed SLO,
+// the changed definition would otherwise be visible after the next tracker run
+func (s *Server) evaluateSLO() {
+	if s.SLOTracker == nil {
+		return
+	}
+
+	s.SLOTracker.Trigger()
+}
@@ -0,0 +1,348 @@
+package http
+
+import (
+	"context"
+	"net/http"
+
+	echosight "github.com/alexjoedt/echosight/internal"
+	"github.com/alexjoedt/echosight/internal/filter"
+	"github.com/alexjoedt/echosight/internal/notify"
+	"github.com/alexjoedt/echosight/internal/validator"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+func (s *Server) registerTemplateRoutes(r *chi.Mux) {
+	r.With(s.requireAuth).Route("/templates", func(r chi.Router) {
+		r.Get("/", makeHandlerFunc(s.handlerGetTemplates))
+		r.Post("/", makeHandlerFunc(s.handlerCreateTemplate))
+		r.Get("/defaults/{channel}", makeHandlerFunc(s.handlerGetDefaultTemplate))
+		r.Get("/{templateID}", makeHandlerFunc(s.handlerGetTemplateByID))
+		r.Patch("/{templateID}", makeHandlerFunc(s.handlerUpdateTemplate))
+		r.Delete("/{templateID}", makeHandlerFunc(s.handlerDeleteTemplateByID))
+		r.Post("/{templateID}/preview", makeHandlerFunc(s.handlerPreviewTemplate))
+	})
+}
+
+type templateInput struct {
+	Name    *string                     `json:"name"`
+	Channel *string                     `json:"channel"`
+	Type    *echosight.NotificationType `json:"type"`
+	Active  *bool                       `json:"active"`
+	Subject *string                     `json:"subject"`
+	Body    *string                     `json:"body"`
+	HTML    *string                     `json:"html"`
+}
+
+// apply sets the provided fields on the template
+func (input *templateInput) apply(tmpl *echosight.NotificationTemplate) {
+	if input.Name != nil {
+		tmpl.Name = *input.Name
+	}
+
+	if input.Channel != nil {
+		tmpl.Channel = *input.Channel
+	}
+
+	if input.Type != nil {
+		tmpl.Type = *input.Type
+	}
+
+	if input.Active != nil {
+		tmpl.Active = *input.Active
+	}
+
+	if input.Subject != nil {
+		tmpl.Subject = *input.Subject
+	}
+
+	if input.Body != nil {
+		tmpl.Body = *input.Body
+	}
+
+	if input.HTML != nil {
+		tmpl.HTML = *input.HTML
+	}
+}
+
+func (s *Server) handlerCreateTemplate(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := s.ServerContext(r.Context())
+	defer cancel()
+
+	var input templateInput
+	err := readJSON(r, &input)
+	if err != nil {
+		s.log.Errorc("failed to read template payload", err)
+		return err
+	}
+
+	tmpl := echosight.NotificationTemplate{
+		Active: true,
+	}
+	input.apply(&tmpl)
+
+	v := validator.New()
+	echosight.ValidateNotificationTemplate(v, &tmpl)
+	if !v.Valid() {
+		return echosight.ErrInvalidf("invalid template payload").WithData(v.Errors)
+	}
+
+	err = s.TemplateService.Create(ctx, &tmpl)
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(w, http.StatusOK, Response{
+		Status:  StatusOK,
+		Message: "template created",
+		Data: W{
+			"template": tmpl,
+		},
+	})
+}
+
+func (s *Server) handlerGetTemplateByID(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := s.ServerContext(r.Context())
+	defer cancel()
+
+	templateID, err := ReadUUIDParam(r, "templateID")
+	if err != nil {
+		return err
+	}
+
+	tmpl, err := s.TemplateService.GetByID(ctx, templateID)
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(w, http.StatusOK, Response{
+		Status: StatusOK,
+		Data: W{
+			"template": tmpl,
+		},
+	})
+}
+
+// handlerGetTemplates returns the stored templates.
+//
+// Query params: channel, type, active, page, page_size, sort
+func (s *Server) handlerGetTemplates(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := s.ServerContext(r.Context())
+	defer cancel()
+
+	qs := r.URL.Query()
+	v := validator.New()
+	templateFilter := filter.NewDefaultTemplateFilter()
+	if channel := ReadString(qs, "channel", ""); channel != "" {
+		templateFilter.Channel = &channel
+	}
+	if qs.Has("type") {
+		notificationType := ReadString(qs, "type", "")
+		templateFilter.Type = &notificationType
+	}
+	if qs.Has("active") {
+		active := ReadBool(qs, "active")
+		templateFilter.Active = &active
+	}
+	templateFilter.Page = ReadInt(qs, "page", templateFilter.Page, v)
+	templateFilter.PageSize = ReadInt(qs, "page_size", templateFilter.PageSize, v)
+	templateFilter.Sort = ReadString(qs, "sort", templateFilter.Sort)
+	filter.ValidateFilters(v, templateFilter.Filter)
+	if !v.Valid() {
+		return echosight.ErrInvalidf("invalid query params").WithData(v.Errors)
+	}
+
+	templates, err := s.TemplateService.List(ctx, templateFilter)
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(w, http.StatusOK, Response{
+		Status: StatusOK,
+		Data: W{
+			"templates":  templates,
+			"pagination": templateFilter.Pagination,
+		},
+	})
+}
+
+// handlerGetDefaultTemplate returns the built-in template of the channel,
+// e.g. as a starting point for an own template
+func (s *Server) handlerGetDefaultTemplate(w http.ResponseWriter, r *http.Request) error {
+	channel := chi.URLParam(r, "channel")
+
+	return writeJSON(w, http.StatusOK, Response{
+		Status: StatusOK,
+		Data: W{
+			"template": s.Renderer.Default(channel),
+		},
+	})
+}
+
+func (s *Server) handlerUpdateTemplate(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := s.ServerContext(r.Context())
+	defer cancel()
+
+	templateID, err := ReadUUIDParam(r, "templateID")
+	if err != nil {
+		return err
+	}
+
+	var input templateInput
+	err = readJSON(r, &input)
+	if err != nil {
+		s.log.Errorc("failed to read template payload", err)
+		return err
+	}
+
+	tmpl, err := s.TemplateService.GetByID(ctx, templateID)
+	if err != nil {
+		return err
+	}
+	input.apply(tmpl)
+
+	v := validator.New()
+	echosight.ValidateNotificationTemplate(v, tmpl)
+	if !v.Valid() {
+		return echosight.ErrInvalidf("invalid template payload").WithData(v.Errors)
+	}
+
+	err = s.TemplateService.Update(ctx, tmpl)
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(w, http.StatusOK, Response{
+		Status:  StatusOK,
+		Message: "template updated",
+		Data: W{
+			"template": tmpl,
+		},
+	})
+}
+
+func (s *Server) handlerDeleteTemplateByID(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := s.ServerContext(r.Context())
+	defer cancel()
+
+	templateID, err := ReadUUIDParam(r, "templateID")
+	if err != nil {
+		return err
+	}
+
+	tmpl, err := s.TemplateService.DeleteByID(ctx, templateID)
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(w, http.StatusOK, Response{
+		Status:  StatusOK,
+		Message: "template deleted",
+		Data: W{
+			"template": tmpl,
+		},
+	})
+}
+
+// handlerPreviewTemplate renders the template against the current state of a detector
+// or a sample result, if no detector is given.
+//
+// Payload: detectorId (optional), notification (default: type of the template or problem)
+func (s *Server) handlerPreviewTemplate(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := s.ServerContext(r.Context())
+	defer cancel()
+
+	templateID, err := ReadUUIDParam(r, "templateID")
+	if err != nil {
+		return err
+	}
+
+	var input struct {
+		DetectorID   *uuid.UUID                 `json:"detectorId"`
+		Notification echosight.NotificationType `json:"notification"`
+	}
+	err = readJSON(r, &input)
+	if err != nil {
+		s.log.Errorc("failed to read preview payload", err)
+		return err
+	}
+
+	tmpl, err := s.TemplateService.GetByID(ctx, templateID)
+	if err != nil {
+		return err
+	}
+
+	result := sampleResult()
+	if input.DetectorID != nil {
+		result, err = s.detectorResult(ctx, *input.DetectorID)
+		if err != nil {
+			return err
+		}
+	}
+
+	result.Notification = input.Notification
+	if result.Notification == "" {
+		result.Notification = tmpl.Type
+	}
+	if result.Notification == "" {
+		result.Notification = echosight.NotificationProblem
+	}
+
+	msg, err := notify.RenderTemplate(tmpl, s.Renderer.Data(ctx, result))
+	if err != nil {
+		return echosight.ErrInvalidf("failed to render template").WithData(map[string]string{
+			"template": err.Error(),
+		})
+	}
+
+	return writeJSON(w, http.StatusOK, Response{
+		Status: StatusOK,
+		Data: W{
+			"preview": msg,
+			"result":  result,
+		},
+	})
+}
+
+// sampleResult returns a result for the preview without a detector
+func sampleResult() *echosight.Result {
+	return &echosight.Result{
+		Host:       "web-01",
+		Detector:   "HTTP homepage",
+		DetectorID: uuid.Nil.String(),
+		State:      echosight.StateCritical,
+		Message:    "GET https://example.com: 503 Service Unavailable",
+		StateType:  echosight.StateTypeHard,
+		Attempt:    3,
+	}
+}
+
+// detectorResult returns a result with the current state and incident of the detector
+func (s *Server) detectorResult(ctx context.Context, detectorID uuid.UUID) (*echosight.Result, error) {
+	detector, err := s.DetectorService.GetByID(ctx, detectorID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &echosight.Result{
+		Host:       detector.HostName,
+		Detector:   detector.Name,
+		DetectorID: detector.ID.String(),
+		State:      detector.HardState,
+		Message:    detector.StatusMessage,
+		StateType:  detector.StateType,
+		Attempt:    detector.Attempt,
+		Flapping:   detector.Flapping,
+		Tags:       detector.Tags,
+	}
+
+	incident, err := s.IncidentService.GetUnresolved(ctx, detector.ID)
+	if err != nil && echosight.ErrorCode(err) != echosight.ENOTFOUND {
+		return nil, err
+	}
+	if incident != nil {
+		result.IncidentID = incident.ID.String()
+	}
+
+	return result, nil
+}
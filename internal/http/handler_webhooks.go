@@ -0,0 +1,273 @@
+package http
+
+import (
+	"net/http"
+
+	echosight "github.com/alexjoedt/echosight/internal"
+	"github.com/alexjoedt/echosight/internal/filter"
+	"github.com/alexjoedt/echosight/internal/validator"
+	"github.com/go-chi/chi/v5"
+)
+
+func (s *Server) registerWebhookRoutes(r *chi.Mux) {
+	r.With(s.requireAuth).Route("/webhooks", func(r chi.Router) {
+		r.Get("/", makeHandlerFunc(s.handlerGetWebhooks))
+		r.Post("/", makeHandlerFunc(s.handlerCreateWebhook))
+		r.Get("/{webhookID}", makeHandlerFunc(s.handlerGetWebhookByID))
+		r.Patch("/{webhookID}", makeHandlerFunc(s.handlerUpdateWebhook))
+		r.Delete("/{webhookID}", makeHandlerFunc(s.handlerDeleteWebhookByID))
+		r.Post("/{webhookID}/test", makeHandlerFunc(s.handlerTestWebhook))
+	})
+}
+
+type webhookInput struct {
+	Name    *string             `json:"name"`
+	URL     *string             `json:"url"`
+	Active  *bool               `json:"active"`
+	Headers map[string]string   `json:"headers"`
+	Body    *string             `json:"body"`
+	Timeout *echosight.Duration `json:"timeout"`
+	// Secret is encrypted before it's stored, an empty secret removes the signature
+	Secret *string `json:"secret"`
+}
+
+// apply sets the provided fields on the webhook and encrypts the secret
+func (input *webhookInput) apply(webhook *echosight.Webhook, crypter echosight.Crypter) error {
+	if input.Name != nil {
+		webhook.Name = *input.Name
+	}
+
+	if input.URL != nil {
+		webhook.URL = *input.URL
+	}
+
+	if input.Active != nil {
+		webhook.Active = *input.Active
+	}
+
+	if input.Headers != nil {
+		webhook.Headers = input.Headers
+	}
+
+	if input.Body != nil {
+		webhook.Body = *input.Body
+	}
+
+	if input.Timeout != nil {
+		webhook.Timeout = *input.Timeout
+	}
+
+	if input.Secret != nil {
+		webhook.SecretCrypt = ""
+		if *input.Secret != "" {
+			crypted, err := crypter.Encrypt(*input.Secret)
+			if err != nil {
+				return echosight.ErrInternalf("failed to encrypt webhook secret").WithError(err)
+			}
+			webhook.SecretCrypt = crypted
+		}
+	}
+	webhook.HasSecret = webhook.SecretCrypt != ""
+
+	return nil
+}
+
+func (s *Server) handlerCreateWebhook(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := s.ServerContext(r.Context())
+	defer cancel()
+
+	var input webhookInput
+	err := readJSON(r, &input)
+	if err != nil {
+		s.log.Errorc("failed to read webhook payload", err)
+		return err
+	}
+
+	webhook := echosight.Webhook{
+		Active: true,
+	}
+	if err := input.apply(&webhook, s.Crypter); err != nil {
+		return err
+	}
+
+	v := validator.New()
+	echosight.ValidateWebhook(v, &webhook)
+	if !v.Valid() {
+		return echosight.ErrInvalidf("invalid webhook payload").WithData(v.Errors)
+	}
+
+	err = s.WebhookService.Create(ctx, &webhook)
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(w, http.StatusOK, Response{
+		Status:  StatusOK,
+		Message: "webhook created",
+		Data: W{
+			"webhook": webhook,
+		},
+	})
+}
+
+func (s *Server) handlerGetWebhookByID(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := s.ServerContext(r.Context())
+	defer cancel()
+
+	webhookID, err := ReadUUIDParam(r, "webhookID")
+	if err != nil {
+		return err
+	}
+
+	webhook, err := s.WebhookService.GetByID(ctx, webhookID)
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(w, http.StatusOK, Response{
+		Status: StatusOK,
+		Data: W{
+			"webhook": webhook,
+		},
+	})
+}
+
+// handlerGetWebhooks returns the webhooks, the secrets are never returned.
+//
+// Query params: name, active, page, page_size, sort
+func (s *Server) handlerGetWebhooks(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := s.ServerContext(r.Context())
+	defer cancel()
+
+	qs := r.URL.Query()
+	v := validator.New()
+	webhookFilter := filter.NewDefaultWebhookFilter()
+	if name := ReadString(qs, "name", ""); name != "" {
+		webhookFilter.Name = &name
+	}
+	if qs.Has("active") {
+		active := ReadBool(qs, "active")
+		webhookFilter.Active = &active
+	}
+	webhookFilter.Page = ReadInt(qs, "page", webhookFilter.Page, v)
+	webhookFilter.PageSize = ReadInt(qs, "page_size", webhookFilter.PageSize, v)
+	webhookFilter.Sort = ReadString(qs, "sort", webhookFilter.Sort)
+	filter.ValidateFilters(v, webhookFilter.Filter)
+	if !v.Valid() {
+		return echosight.ErrInvalidf("invalid query params").WithData(v.Errors)
+	}
+
+	webhooks, err := s.WebhookService.List(ctx, webhookFilter)
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(w, http.StatusOK, Response{
+		Status: StatusOK,
+		Data: W{
+			"webhooks":   webhooks,
+			"pagination": webhookFilter.Pagination,
+		},
+	})
+}
+
+func (s *Server) handlerUpdateWebhook(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := s.ServerContext(r.Context())
+	defer cancel()
+
+	webhookID, err := ReadUUIDParam(r, "webhookID")
+	if err != nil {
+		return err
+	}
+
+	var input webhookInput
+	err = readJSON(r, &input)
+	if err != nil {
+		s.log.Errorc("failed to read webhook payload", err)
+		return err
+	}
+
+	webhook, err := s.WebhookService.GetByID(ctx, webhookID)
+	if err != nil {
+		return err
+	}
+
+	if err := input.apply(webhook, s.Crypter); err != nil {
+		return err
+	}
+
+	v := validator.New()
+	echosight.ValidateWebhook(v, webhook)
+	if !v.Valid() {
+		return echosight.ErrInvalidf("invalid webhook payload").WithData(v.Errors)
+	}
+
+	err = s.WebhookService.Update(ctx, webhook)
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(w, http.StatusOK, Response{
+		Status:  StatusOK,
+		Message: "webhook updated",
+		Data: W{
+			"webhook": webhook,
+		},
+	})
+}
+
+func (s *Server) handlerDeleteWebhookByID(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := s.ServerContext(r.Context())
+	defer cancel()
+
+	webhookID, err := ReadUUIDParam(r, "webhookID")
+	if err != nil {
+		return err
+	}
+
+	webhook, err := s.WebhookService.DeleteByID(ctx, webhookID)
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(w, http.StatusOK, Response{
+		Status:  StatusOK,
+		Message: "webhook deleted",
+		Data: W{
+			"webhook": webhook,
+		},
+	})
+}
+
+// handlerTestWebhook posts a sample result to the webhook, also if it's inactive
+func (s *Server) handlerTestWebhook(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := s.ServerContext(r.Context())
+	defer cancel()
+
+	if s.Webhooks == nil {
+		return echosight.ErrInternalf("webhook sender is not initialized")
+	}
+
+	webhookID, err := ReadUUIDParam(r, "webhookID")
+	if err != nil {
+		return err
+	}
+
+	webhook, err := s.WebhookService.GetByID(ctx, webhookID)
+	if err != nil {
+		return err
+	}
+
+	result := sampleResult()
+	result.Notification = echosight.NotificationProblem
+	if err := s.Webhooks.Post(ctx, webhook, result); err != nil {
+		return echosight.ErrInvalidf("webhook test failed").WithData(map[string]string{
+			"webhook": err.Error(),
+		})
+	}
+
+	return writeJSON(w, http.StatusOK, Response{
+		Status:  StatusOK,
+		Message: "webhook test sent",
+	})
+}
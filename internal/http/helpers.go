@@ -69,6 +69,25 @@ func ReadTime(qs url.Values, key string, defaulValue time.Time) time.Time {
 	return t
 }
 
+// ReadDateTime reads a RFC3339 timestamp or a date from the query.
+// Returns nil if the key is not present and adds an error to the validator
+// if the value is not valid.
+func ReadDateTime(qs url.Values, key string, v *validator.Validator) *time.Time {
+	s := qs.Get(key)
+	if s == "" {
+		return nil
+	}
+
+	for _, layout := range []string{time.RFC3339, time.DateOnly} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return &t
+		}
+	}
+
+	v.AddError(key, "must be a RFC3339 timestamp or a date (YYYY-MM-DD)")
+	return nil
+}
+
 func ReadTimeString(qs url.Values, key string, defaulValue string) string {
 	s := qs.Get(key)
 	if s == "" {
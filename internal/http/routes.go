@@ -45,6 +45,8 @@ func (s *Server) registerDetectorRoutes(r *chi.Mux) {
 
 		r.Post("/{detectorID}/activate", makeHandlerFunc(s.handlerActivateDetector))
 		r.Post("/{detectorID}/deactivate", makeHandlerFunc(s.handlerDeactivateDetector))
+
+		r.Get("/{detectorID}/history", makeHandlerFunc(s.handlerGetDetectorHistory))
 	})
 }
 
@@ -11,9 +11,17 @@ import (
 	"time"
 
 	echosight "github.com/alexjoedt/echosight/internal"
+	"github.com/alexjoedt/echosight/internal/escalation"
 	"github.com/alexjoedt/echosight/internal/eventflow"
+	"github.com/alexjoedt/echosight/internal/incident"
 	"github.com/alexjoedt/echosight/internal/logger"
+	"github.com/alexjoedt/echosight/internal/maintenance"
+	"github.com/alexjoedt/echosight/internal/notify"
 	"github.com/alexjoedt/echosight/internal/observer"
+	"github.com/alexjoedt/echosight/internal/oncall"
+	"github.com/alexjoedt/echosight/internal/outbox"
+	"github.com/alexjoedt/echosight/internal/routing"
+	"github.com/alexjoedt/echosight/internal/slo"
 	"github.com/go-chi/chi/v5"
 	"github.com/rs/cors"
 )
@@ -45,15 +53,47 @@ type Server struct {
 
 	RateLimiter RateLimiter
 
-	UserService       echosight.UserService
-	HostService       echosight.HostService
-	DetectorService   echosight.DetectorService
-	RecipientService  echosight.RecipientService
-	PreferenceService echosight.PreferenceService
-	SessionService    echosight.SessionService
+	UserService         echosight.UserService
+	HostService         echosight.HostService
+	DetectorService     echosight.DetectorService
+	RecipientService    echosight.RecipientService
+	PreferenceService   echosight.PreferenceService
+	SessionService      echosight.SessionService
+	HistoryService      echosight.HistoryService
+	SLOService          echosight.SLOService
+	IncidentService     echosight.IncidentService
+	MaintenanceService  echosight.MaintenanceService
+	EscalationService   echosight.EscalationService
+	OnCallService       echosight.OnCallService
+	RoutingService      echosight.RoutingService
+	NotificationService echosight.NotificationService
+	TemplateService     echosight.TemplateService
+	WebhookService      echosight.WebhookService
+	ChannelService      echosight.NotificationChannelService
 
 	MetricReader echosight.MetricReader
 	Scheduler    *observer.Scheduler
+	SLOTracker   *slo.Tracker
+	// IncidentManager handles the lifecycle changes of incidents
+	IncidentManager *incident.Manager
+	// Maintenance holds the maintenance windows in memory
+	Maintenance *maintenance.Calendar
+	// Escalations holds the escalation policies in memory
+	Escalations *escalation.Escalator
+	// OnCall calculates the shifts of the on-call schedules
+	OnCall *oncall.Resolver
+	// Routing holds the routing rules in memory
+	Routing *routing.Router
+	// Notifier holds the registered senders
+	Notifier *notify.Notifier
+	// Outbox delivers the notifications, optional
+	Outbox *outbox.Dispatcher
+	// Renderer renders the notification templates
+	Renderer *notify.Renderer
+	// Webhooks posts the notifications to the webhooks
+	Webhooks *notify.WebhookSender
+	// Channels registers the senders of the notification channels
+	Channels     *notify.Channels
 	EventHandler *eventflow.Engine
 	Crypter      echosight.Crypter
 }
@@ -119,6 +159,39 @@ func NewServer(opts ServerOpts) (*Server, error) {
 	// preference routes
 	s.registerPreferencesRoutes(apiV1Router)
 
+	// report routes, e.g. uptime and SLA
+	s.registerReportRoutes(apiV1Router)
+
+	// slo routes
+	s.registerSLORoutes(apiV1Router)
+
+	// incident routes
+	s.registerIncidentRoutes(apiV1Router)
+
+	// maintenance routes
+	s.registerMaintenanceRoutes(apiV1Router)
+
+	// escalation routes
+	s.registerEscalationRoutes(apiV1Router)
+
+	// on-call routes
+	s.registerOnCallRoutes(apiV1Router)
+
+	// notification routing rules
+	s.registerRoutingRoutes(apiV1Router)
+
+	// notification outbox
+	s.registerNotificationRoutes(apiV1Router)
+
+	// notification templates
+	s.registerTemplateRoutes(apiV1Router)
+
+	// outbound webhooks
+	s.registerWebhookRoutes(apiV1Router)
+
+	// notification channels
+	s.registerChannelRoutes(apiV1Router)
+
 	s.mux.Mount("/api/v1", apiV1Router)
 
 	// WebSocket Router
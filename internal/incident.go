@@ -0,0 +1,118 @@
+package echosight
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+const (
+	EventIncident = "incident"
+
+	// IncidentTopic is the topic of the incident events of all detectors
+	IncidentTopic = "incidents"
+)
+
+type IncidentStatus string
+
+const (
+	IncidentOpen         IncidentStatus = "open"
+	IncidentAcknowledged IncidentStatus = "acknowledged"
+	IncidentResolved     IncidentStatus = "resolved"
+)
+
+func (s IncidentStatus) String() string {
+	return string(s)
+}
+
+// Incident is opened when a detector changes to a problem state
+// and is open until it's resolved, manually or on recovery.
+// A detector has at most one unresolved incident.
+type Incident struct {
+	bun.BaseModel `bun:"table:incidents"`
+	ID            uuid.UUID      `json:"id" bun:"type:uuid,pk,default:uuid_generate_v4()"`
+	LookupVersion int            `json:"lookupVersion" bun:",default:1"`
+	DetectorID    uuid.UUID      `json:"detectorId" bun:"type:uuid"`
+	DetectorName  string         `json:"detectorName"`
+	HostID        uuid.UUID      `json:"hostId" bun:"type:uuid"`
+	HostName      string         `json:"hostName"`
+	Status        IncidentStatus `json:"status"`
+	// State is the current state of the detector
+	State State `json:"state"`
+	// WorstState is the worst state of the detector during the incident
+	WorstState State  `json:"worstState"`
+	Message    string `json:"message"`
+
+	AssigneeID     *uuid.UUID `json:"assigneeId,omitempty" bun:"type:uuid"`
+	AcknowledgedBy *uuid.UUID `json:"acknowledgedBy,omitempty" bun:"type:uuid"`
+	AcknowledgedAt *time.Time `json:"acknowledgedAt,omitempty"`
+	ResolvedBy     *uuid.UUID `json:"resolvedBy,omitempty" bun:"type:uuid"`
+	ResolvedAt     *time.Time `json:"resolvedAt,omitempty"`
+
+	Timeline []*IncidentEntry `json:"timeline,omitempty" bun:"rel:has-many,join:id=incident_id"`
+
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// NewIncident creates an open incident for the detector
+func NewIncident(d *Detector, r *Result) *Incident {
+	return &Incident{
+		DetectorID:   d.ID,
+		DetectorName: d.Name,
+		HostID:       d.HostID,
+		HostName:     d.HostName,
+		Status:       IncidentOpen,
+		State:        r.State,
+		WorstState:   r.State,
+		Message:      r.Message,
+	}
+}
+
+// IsResolved reports if the incident is resolved
+func (i *Incident) IsResolved() bool {
+	return i.Status == IncidentResolved
+}
+
+// IsAcknowledged reports if the incident is acknowledged and not resolved
+func (i *Incident) IsAcknowledged() bool {
+	return i.Status == IncidentAcknowledged
+}
+
+type IncidentEntryType string
+
+const (
+	IncidentEntryOpened       IncidentEntryType = "opened"
+	IncidentEntryStateChanged IncidentEntryType = "state_changed"
+	IncidentEntryAcknowledged IncidentEntryType = "acknowledged"
+	IncidentEntryAssigned     IncidentEntryType = "assigned"
+	IncidentEntryComment      IncidentEntryType = "comment"
+	IncidentEntryResolved     IncidentEntryType = "resolved"
+)
+
+// IncidentEntry is an entry in the timeline of an incident
+type IncidentEntry struct {
+	bun.BaseModel `bun:"table:incident_timeline"`
+	ID            uuid.UUID         `json:"id" bun:"type:uuid,pk,default:uuid_generate_v4()"`
+	IncidentID    uuid.UUID         `json:"incidentId" bun:"type:uuid"`
+	Type          IncidentEntryType `json:"type"`
+	// UserID is nil for entries created by echosight, e.g. state changes
+	UserID    *uuid.UUID `json:"userId,omitempty" bun:"type:uuid"`
+	State     State      `json:"state,omitempty" bun:",nullzero"`
+	Message   string     `json:"message"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+// WithIncident sets the incident of the entry and returns the entry
+func (e *IncidentEntry) WithIncident(incident *Incident) *IncidentEntry {
+	e.IncidentID = incident.ID
+	return e
+}
+
+// IncidentEvent is published on the incident topic and the topic of the detector
+type IncidentEvent struct {
+	Type     IncidentEntryType
+	Incident *Incident
+	Entry    *IncidentEntry
+}
@@ -0,0 +1,334 @@
+package incident
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	es "github.com/alexjoedt/echosight/internal"
+	"github.com/alexjoedt/echosight/internal/eventflow"
+	"github.com/alexjoedt/echosight/internal/logger"
+	"github.com/alexjoedt/echosight/internal/notify"
+	"github.com/alexjoedt/echosight/internal/routing"
+	"github.com/google/uuid"
+)
+
+// Manager handles the lifecycle of incidents.
+// Every change is added to the timeline and published on the incident topic
+// and the topic of the detector. Changes by users are sent through the notifier.
+type Manager struct {
+	incidents    es.IncidentService
+	eventHandler *eventflow.Engine
+	notifier     *notify.Notifier
+	log          *logger.Logger
+
+	// AutoResolve resolves the incident when the detector recovers,
+	// otherwise the incident stays open until it's resolved by a user
+	AutoResolve bool
+	// Routing sends the notifications through the routing rules, optional
+	Routing *routing.Router
+}
+
+func NewManager(is es.IncidentService, eh *eventflow.Engine, n *notify.Notifier) *Manager {
+	m := &Manager{
+		incidents:    is,
+		eventHandler: eh,
+		notifier:     n,
+		log:          logger.New("Incident-Manager"),
+		AutoResolve:  true,
+	}
+
+	if _, err := eh.NewTopic(es.IncidentTopic); err != nil {
+		m.log.Errorf("failed to create incident topic: %v", err)
+	}
+
+	return m
+}
+
+// OnStateChange is called when the hard state of a detector has changed.
+// A problem state opens an incident or updates the unresolved incident,
+// a recovery resolves the incident if AutoResolve is enabled.
+// It returns the unresolved incident of the detector or nil.
+func (m *Manager) OnStateChange(ctx context.Context, d *es.Detector, r *es.Result) (*es.Incident, error) {
+	incident, err := m.incidents.GetUnresolved(ctx, d.ID)
+	if err != nil && es.ErrorCode(err) != es.ENOTFOUND {
+		return nil, err
+	}
+
+	switch r.State {
+	case es.StateWarn, es.StateCritical:
+		if incident == nil {
+			return m.open(ctx, d, r)
+		}
+
+		incident.State = r.State
+		incident.WorstState = es.WorseState(incident.WorstState, r.State)
+		incident.Message = r.Message
+		err = m.update(ctx, incident, &es.IncidentEntry{
+			Type:    es.IncidentEntryStateChanged,
+			State:   r.State,
+			Message: r.Message,
+		})
+		return incident, err
+
+	case es.StateOK:
+		if incident == nil {
+			return nil, nil
+		}
+
+		incident.State = r.State
+		entry := &es.IncidentEntry{
+			Type:    es.IncidentEntryStateChanged,
+			State:   r.State,
+			Message: r.Message,
+		}
+
+		if !m.AutoResolve {
+			return incident, m.update(ctx, incident, entry)
+		}
+
+		// the recovery is already notified by the scheduler
+		if err := m.incidents.AddEntry(ctx, entry.WithIncident(incident)); err != nil {
+			return nil, err
+		}
+		m.publish(incident, entry)
+
+		err = m.resolve(ctx, incident, nil, "resolved on recovery")
+		return nil, err
+	}
+
+	return incident, nil
+}
+
+func (m *Manager) open(ctx context.Context, d *es.Detector, r *es.Result) (*es.Incident, error) {
+	incident := es.NewIncident(d, r)
+	if err := m.incidents.Create(ctx, incident); err != nil {
+		return nil, err
+	}
+
+	entry := &es.IncidentEntry{
+		Type:    es.IncidentEntryOpened,
+		State:   r.State,
+		Message: r.Message,
+	}
+	if err := m.incidents.AddEntry(ctx, entry.WithIncident(incident)); err != nil {
+		return nil, err
+	}
+
+	m.publish(incident, entry)
+	return incident, nil
+}
+
+// Acknowledge marks the incident as acknowledged by the user,
+// no further problem notifications are sent for the incident.
+// A user without an id, e.g. a chat user of a bot, is only named in the timeline.
+func (m *Manager) Acknowledge(ctx context.Context, id uuid.UUID, user *es.User) (*es.Incident, error) {
+	incident, err := m.unresolved(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if incident.IsAcknowledged() {
+		return nil, es.ErrConflictf("incident is already acknowledged")
+	}
+
+	now := time.Now()
+	incident.Status = es.IncidentAcknowledged
+	incident.AcknowledgedAt = &now
+
+	entry := &es.IncidentEntry{
+		Type:    es.IncidentEntryAcknowledged,
+		Message: fmt.Sprintf("acknowledged by %s", userName(user)),
+	}
+
+	if user.ID != uuid.Nil {
+		incident.AcknowledgedBy = &user.ID
+		entry.UserID = &user.ID
+	}
+	if err := m.update(ctx, incident, entry); err != nil {
+		return nil, err
+	}
+
+	m.notify(ctx, incident, es.NotificationIncidentAcknowledged, entry.Message)
+	return incident, nil
+}
+
+// Assign assigns the incident to the assignee
+func (m *Manager) Assign(ctx context.Context, id uuid.UUID, user *es.User, assignee *es.User) (*es.Incident, error) {
+	incident, err := m.unresolved(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	incident.AssigneeID = &assignee.ID
+
+	entry := &es.IncidentEntry{
+		Type:    es.IncidentEntryAssigned,
+		UserID:  &user.ID,
+		Message: fmt.Sprintf("assigned to %s by %s", userName(assignee), userName(user)),
+	}
+	if err := m.update(ctx, incident, entry); err != nil {
+		return nil, err
+	}
+
+	m.notify(ctx, incident, es.NotificationIncidentAssigned, entry.Message)
+	return incident, nil
+}
+
+// Comment adds a comment of the user to the timeline
+func (m *Manager) Comment(ctx context.Context, id uuid.UUID, user *es.User, message string) (*es.IncidentEntry, error) {
+	incident, err := m.incidents.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &es.IncidentEntry{
+		Type:    es.IncidentEntryComment,
+		UserID:  &user.ID,
+		Message: message,
+	}
+	if err := m.incidents.AddEntry(ctx, entry.WithIncident(incident)); err != nil {
+		return nil, err
+	}
+
+	m.publish(incident, entry)
+	return entry, nil
+}
+
+// Resolve resolves the incident by the user
+func (m *Manager) Resolve(ctx context.Context, id uuid.UUID, user *es.User, message string) (*es.Incident, error) {
+	incident, err := m.unresolved(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if message == "" {
+		message = fmt.Sprintf("resolved by %s", userName(user))
+	}
+
+	if err := m.resolve(ctx, incident, user, message); err != nil {
+		return nil, err
+	}
+
+	m.notify(ctx, incident, es.NotificationIncidentResolved, message)
+	return incident, nil
+}
+
+func (m *Manager) resolve(ctx context.Context, incident *es.Incident, user *es.User, message string) error {
+	now := time.Now()
+	incident.Status = es.IncidentResolved
+	incident.ResolvedAt = &now
+
+	entry := &es.IncidentEntry{
+		Type:    es.IncidentEntryResolved,
+		Message: message,
+	}
+
+	if user != nil {
+		incident.ResolvedBy = &user.ID
+		entry.UserID = &user.ID
+	}
+
+	return m.update(ctx, incident, entry)
+}
+
+// Unresolved returns the open or acknowledged incident of the detector or nil
+func (m *Manager) Unresolved(ctx context.Context, detectorID uuid.UUID) (*es.Incident, error) {
+	incident, err := m.incidents.GetUnresolved(ctx, detectorID)
+	if err != nil {
+		if es.ErrorCode(err) == es.ENOTFOUND {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return incident, nil
+}
+
+// unresolved returns the incident, if it's not resolved
+func (m *Manager) unresolved(ctx context.Context, id uuid.UUID) (*es.Incident, error) {
+	incident, err := m.incidents.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if incident.IsResolved() {
+		return nil, es.ErrConflictf("incident is already resolved")
+	}
+
+	return incident, nil
+}
+
+// update persists the incident, adds the entry to the timeline and publishes the change
+func (m *Manager) update(ctx context.Context, incident *es.Incident, entry *es.IncidentEntry) error {
+	if err := m.incidents.Update(ctx, incident); err != nil {
+		return err
+	}
+
+	if err := m.incidents.AddEntry(ctx, entry.WithIncident(incident)); err != nil {
+		return err
+	}
+
+	m.publish(incident, entry)
+	return nil
+}
+
+// publish publishes the change on the incident topic and the topic of the detector
+func (m *Manager) publish(incident *es.Incident, entry *es.IncidentEntry) {
+	payload, err := json.Marshal(es.IncidentEvent{
+		Type:     entry.Type,
+		Incident: incident,
+		Entry:    entry,
+	})
+	if err != nil {
+		m.log.Errorf("failed to marshal payload: %v", err)
+		return
+	}
+
+	event := &eventflow.Event{
+		Type:    es.EventIncident,
+		Payload: payload,
+	}
+
+	// INFO: publish blocks until the event is read from all subscribers
+	for _, topicID := range []string{es.IncidentTopic, incident.DetectorID.String()} {
+		if err := m.eventHandler.Publish(topicID, event); err != nil {
+			m.log.Debugf("failed to publish incident event: %v", err)
+		}
+	}
+}
+
+// notify sends the lifecycle change through the notifier
+func (m *Manager) notify(ctx context.Context, incident *es.Incident, notification es.NotificationType, message string) {
+	if m.notifier == nil {
+		return
+	}
+
+	result := &es.Result{
+		Host:         incident.HostName,
+		Detector:     incident.DetectorName,
+		State:        incident.State,
+		Message:      message,
+		Notification: notification,
+		DetectorID:   incident.DetectorID.String(),
+		IncidentID:   incident.ID.String(),
+	}
+
+	var err error
+	if m.Routing != nil {
+		err = m.Routing.NotifyDetector(ctx, incident.DetectorID, result)
+	} else {
+		err = m.notifier.Send(ctx, result)
+	}
+	if err != nil {
+		m.log.Errorf("failed to send notifications: %v", err)
+	}
+}
+
+func userName(user *es.User) string {
+	name := fmt.Sprintf("%s %s", user.FirstName, user.LastName)
+	if name == " " {
+		return user.Email
+	}
+	return name
+}
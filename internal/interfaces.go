@@ -2,6 +2,7 @@ package echosight
 
 import (
 	"context"
+	"time"
 
 	"github.com/alexjoedt/echosight/internal/eventflow"
 	"github.com/alexjoedt/echosight/internal/filter"
@@ -60,6 +61,127 @@ type PreferenceService interface {
 	DeleteByName(ctx context.Context, name string) error
 }
 
+// HistoryService persists the state changes and the check results of detectors
+type HistoryService interface {
+	AddStateChange(ctx context.Context, change *StateChange) error
+	ListStateChanges(ctx context.Context, historyFilter *filter.HistoryFilter) ([]*StateChange, error)
+	AddResult(ctx context.Context, result *ResultLog) error
+	ListResults(ctx context.Context, historyFilter *filter.HistoryFilter) ([]*ResultLog, error)
+}
+
+// SLOService represents a service for managing service level objectives.
+type SLOService interface {
+	Create(ctx context.Context, slo *SLO) error
+	GetByID(ctx context.Context, id uuid.UUID) (*SLO, error)
+	Update(ctx context.Context, slo *SLO) error
+	UpdateStatus(ctx context.Context, slo *SLO) error
+	DeleteByID(ctx context.Context, id uuid.UUID) (*SLO, error)
+	List(ctx context.Context, sloFilter *filter.SLOFilter) ([]*SLO, error)
+}
+
+// IncidentService represents a service for managing incidents and their timeline.
+type IncidentService interface {
+	Create(ctx context.Context, incident *Incident) error
+	GetByID(ctx context.Context, id uuid.UUID) (*Incident, error)
+	// GetUnresolved returns the open or acknowledged incident of the detector
+	GetUnresolved(ctx context.Context, detectorID uuid.UUID) (*Incident, error)
+	Update(ctx context.Context, incident *Incident) error
+	List(ctx context.Context, incidentFilter *filter.IncidentFilter) ([]*Incident, error)
+	AddEntry(ctx context.Context, entry *IncidentEntry) error
+}
+
+// MaintenanceService represents a service for managing maintenance windows.
+type MaintenanceService interface {
+	Create(ctx context.Context, window *MaintenanceWindow) error
+	GetByID(ctx context.Context, id uuid.UUID) (*MaintenanceWindow, error)
+	Update(ctx context.Context, window *MaintenanceWindow) error
+	DeleteByID(ctx context.Context, id uuid.UUID) (*MaintenanceWindow, error)
+	List(ctx context.Context, maintenanceFilter *filter.MaintenanceFilter) ([]*MaintenanceWindow, error)
+}
+
+// EscalationService persists the escalation policies and the state of running escalations.
+type EscalationService interface {
+	CreatePolicy(ctx context.Context, policy *EscalationPolicy) error
+	GetPolicyByID(ctx context.Context, id uuid.UUID) (*EscalationPolicy, error)
+	UpdatePolicy(ctx context.Context, policy *EscalationPolicy) error
+	DeletePolicyByID(ctx context.Context, id uuid.UUID) (*EscalationPolicy, error)
+	ListPolicies(ctx context.Context, escalationFilter *filter.EscalationFilter) ([]*EscalationPolicy, error)
+
+	CreateEscalation(ctx context.Context, escalation *Escalation) error
+	UpdateEscalation(ctx context.Context, escalation *Escalation) error
+	// ListDue returns the pending escalations with a due level
+	ListDue(ctx context.Context, now time.Time) ([]*Escalation, error)
+	// ListPending returns the pending escalations of the incident
+	ListPending(ctx context.Context, incidentID uuid.UUID) ([]*Escalation, error)
+	ListEscalations(ctx context.Context, escalationFilter *filter.EscalationFilter) ([]*Escalation, error)
+}
+
+// OnCallService persists the on-call schedules and their overrides.
+type OnCallService interface {
+	Create(ctx context.Context, schedule *OnCallSchedule) error
+	GetByID(ctx context.Context, id uuid.UUID) (*OnCallSchedule, error)
+	Update(ctx context.Context, schedule *OnCallSchedule) error
+	DeleteByID(ctx context.Context, id uuid.UUID) (*OnCallSchedule, error)
+	List(ctx context.Context, oncallFilter *filter.OnCallFilter) ([]*OnCallSchedule, error)
+
+	CreateOverride(ctx context.Context, override *OnCallOverride) error
+	DeleteOverride(ctx context.Context, scheduleID uuid.UUID, id uuid.UUID) (*OnCallOverride, error)
+	// ListOverrides returns the overrides sorted by creation
+	ListOverrides(ctx context.Context, oncallFilter *filter.OnCallFilter) ([]*OnCallOverride, error)
+}
+
+// RoutingService persists the notification routing rules.
+type RoutingService interface {
+	Create(ctx context.Context, rule *RoutingRule) error
+	GetByID(ctx context.Context, id uuid.UUID) (*RoutingRule, error)
+	Update(ctx context.Context, rule *RoutingRule) error
+	DeleteByID(ctx context.Context, id uuid.UUID) (*RoutingRule, error)
+	List(ctx context.Context, routingFilter *filter.RoutingFilter) ([]*RoutingRule, error)
+}
+
+// TemplateService persists the notification templates.
+type TemplateService interface {
+	Create(ctx context.Context, tmpl *NotificationTemplate) error
+	GetByID(ctx context.Context, id uuid.UUID) (*NotificationTemplate, error)
+	Update(ctx context.Context, tmpl *NotificationTemplate) error
+	DeleteByID(ctx context.Context, id uuid.UUID) (*NotificationTemplate, error)
+	List(ctx context.Context, templateFilter *filter.TemplateFilter) ([]*NotificationTemplate, error)
+	// Find returns the active template of the channel and type, or the template
+	// of the channel for all types
+	Find(ctx context.Context, channel string, notificationType NotificationType) (*NotificationTemplate, error)
+}
+
+// WebhookService persists the outbound webhooks.
+type WebhookService interface {
+	Create(ctx context.Context, webhook *Webhook) error
+	GetByID(ctx context.Context, id uuid.UUID) (*Webhook, error)
+	Update(ctx context.Context, webhook *Webhook) error
+	DeleteByID(ctx context.Context, id uuid.UUID) (*Webhook, error)
+	List(ctx context.Context, webhookFilter *filter.WebhookFilter) ([]*Webhook, error)
+}
+
+// NotificationChannelService persists the configured notification channels.
+type NotificationChannelService interface {
+	Create(ctx context.Context, channel *NotificationChannel) error
+	GetByID(ctx context.Context, id uuid.UUID) (*NotificationChannel, error)
+	Update(ctx context.Context, channel *NotificationChannel) error
+	DeleteByID(ctx context.Context, id uuid.UUID) (*NotificationChannel, error)
+	List(ctx context.Context, channelFilter *filter.NotificationChannelFilter) ([]*NotificationChannel, error)
+}
+
+// NotificationService is the outbox of the notifications.
+type NotificationService interface {
+	Create(ctx context.Context, notification *Notification) error
+	// GetByID returns the notification with the log of all attempts
+	GetByID(ctx context.Context, id uuid.UUID) (*Notification, error)
+	Update(ctx context.Context, notification *Notification) error
+	List(ctx context.Context, notificationFilter *filter.NotificationFilter) ([]*Notification, error)
+	// Claim returns up to limit pending notifications which are due and
+	// defers their next attempt by the lease, so they are not claimed twice
+	Claim(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]*Notification, error)
+	AddAttempt(ctx context.Context, attempt *DeliveryAttempt) error
+}
+
 type SessionService interface {
 	Put(ctx context.Context, session *Session) error
 	Get(ctx context.Context, token string) (*Session, bool, error)
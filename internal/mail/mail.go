@@ -16,9 +16,13 @@ import (
 	"github.com/alexjoedt/echosight/internal/logger"
 	"github.com/alexjoedt/echosight/internal/notify"
 	"github.com/go-mail/mail"
+	"github.com/google/uuid"
 )
 
-var _ notify.Sender = (*Mailer)(nil)
+var (
+	_ notify.Sender    = (*Mailer)(nil)
+	_ notify.Deliverer = (*Mailer)(nil)
+)
 
 //go:embed "templates"
 var templateFS embed.FS
@@ -43,6 +47,20 @@ type Opts struct {
 	AppPreferences echosight.PreferenceService
 	Recipients     echosight.RecipientService
 	Crypter        echosight.Crypter
+	// Renderer renders the notifications, the built-in template is used without a renderer
+	Renderer *notify.Renderer
+}
+
+// DefaultTemplate returns the built-in template of the notification mails
+func DefaultTemplate() *echosight.NotificationTemplate {
+	return &echosight.NotificationTemplate{
+		Name:    "default",
+		Channel: "mail",
+		Active:  true,
+		Subject: notify.MustReadTemplate(templateFS, "templates/notification_subject.tmpl"),
+		Body:    notify.MustReadTemplate(templateFS, "templates/notification_body.tmpl"),
+		HTML:    notify.MustReadTemplate(templateFS, "templates/notification_html.tmpl"),
+	}
 }
 
 func New(opts Opts) (*Mailer, error) {
@@ -81,6 +99,27 @@ func (m *Mailer) Send(ctx context.Context, result *echosight.Result) error {
 	return nil
 }
 
+// Deliver sends the mail synchronously
+func (m *Mailer) Deliver(ctx context.Context, result *echosight.Result) error {
+	return m.sendNotification(ctx, result)
+}
+
+// sendNotification renders the result with the mail template and sends it
+func (m *Mailer) sendNotification(ctx context.Context, result *echosight.Result) error {
+	var msg *notify.Message
+	var err error
+	if m.Renderer != nil {
+		msg, err = m.Renderer.Render(ctx, "mail", result)
+	} else {
+		msg, err = notify.RenderTemplate(DefaultTemplate(), &echosight.TemplateData{Result: result})
+	}
+	if err != nil {
+		return err
+	}
+
+	return m.send(result.Recipients, msg)
+}
+
 func (m *Mailer) Enabled() bool {
 	// TODO: redundant db access...
 	config, err := m.getSMTPConfig()
@@ -158,13 +197,15 @@ func (m *Mailer) connect() error {
 	return nil
 }
 
-func (m *Mailer) getRecipients() ([]string, error) {
+// getRecipients returns the active recipients, limited to the ids if given
+func (m *Mailer) getRecipients(ids []uuid.UUID) ([]string, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
 	defer cancel()
 
 	f := filter.NewDefaultRecipientFilter()
 	active := true
 	f.Active = &active
+	f.IDs = ids
 	result, err := m.Recipients.List(ctx, f)
 	if err != nil {
 		return nil, err
@@ -176,22 +217,17 @@ func (m *Mailer) getRecipients() ([]string, error) {
 
 	recipients := make([]string, len(result))
 	for i := range result {
-		recipients[i] = result[i].Name
+		recipients[i] = result[i].Email
 	}
 
 	return recipients, nil
 }
 
 func (m *Mailer) SendTemplate(templateFile string, data any) error {
-	if err := m.connect(); err != nil {
-		return err
-	}
-
-	recipients, err := m.getRecipients()
-	if err != nil {
-		return err
-	}
+	return m.sendTemplate(nil, templateFile, data)
+}
 
+func (m *Mailer) sendTemplate(recipientIDs []uuid.UUID, templateFile string, data any) error {
 	tmpl, err := template.New("email").ParseFS(templateFS, "templates/"+templateFile)
 	if err != nil {
 		return err
@@ -215,12 +251,32 @@ func (m *Mailer) SendTemplate(templateFile string, data any) error {
 		return err
 	}
 
+	return m.send(recipientIDs, &notify.Message{
+		Subject: subject.String(),
+		Body:    plainBody.String(),
+		HTML:    htmlBody.String(),
+	})
+}
+
+// send sends the message to the recipients, to all active recipients if no ids are given
+func (m *Mailer) send(recipientIDs []uuid.UUID, message *notify.Message) error {
+	if err := m.connect(); err != nil {
+		return err
+	}
+
+	recipients, err := m.getRecipients(recipientIDs)
+	if err != nil {
+		return err
+	}
+
 	msg := mail.NewMessage()
 	msg.SetHeader("To", recipients...)
 	msg.SetHeader("From", m.sender)
-	msg.SetHeader("Subject", subject.String())
-	msg.SetBody("text/plain", plainBody.String())
-	msg.AddAlternative("text/html", htmlBody.String())
+	msg.SetHeader("Subject", message.Subject)
+	msg.SetBody("text/plain", message.Body)
+	if message.HTML != "" {
+		msg.AddAlternative("text/html", message.HTML)
+	}
 
 	// three attempts
 	for i := 0; i < 3; i++ {
@@ -247,7 +303,7 @@ func (m *Mailer) worker() {
 				m.log.Debugf("mail channel closed")
 				return
 			}
-			err := m.SendTemplate("state_changed.tmpl", payload)
+			err := m.sendNotification(context.Background(), payload)
 			if err != nil {
 				m.log.Errorf("send mail failed: %v", err)
 			} else {
@@ -0,0 +1,239 @@
+package echosight
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/alexjoedt/echosight/internal/validator"
+	"github.com/google/uuid"
+	"github.com/robfig/cron/v3"
+	"github.com/teambition/rrule-go"
+	"github.com/uptrace/bun"
+)
+
+const (
+	// maxOccurrences limits the calculated occurrences of a recurring window
+	maxOccurrences int = 10_000
+	// nextOccurrenceRange is the time range to search for the next occurrence
+	nextOccurrenceRange time.Duration = time.Hour * 24 * 366
+)
+
+// MaintenanceWindow is a scheduled downtime for a host, a detector or all detectors with a tag.
+// During a window the checks are running, but no notifications are sent
+// and the time is excluded from the uptime.
+//
+// A one-off window lasts from StartsAt to EndsAt.
+// A recurring window starts at every occurrence of the recurrence (RRULE or cron expression)
+// after StartsAt in the timezone and lasts for Duration. EndsAt ends the recurrence.
+type MaintenanceWindow struct {
+	bun.BaseModel `bun:"table:maintenance_windows"`
+	ID            uuid.UUID  `json:"id" bun:"type:uuid,pk,default:uuid_generate_v4()"`
+	LookupVersion int        `json:"lookupVersion" bun:",default:1"`
+	Name          string     `json:"name"`
+	Description   string     `json:"description"`
+	HostID        *uuid.UUID `json:"hostId,omitempty" bun:"type:uuid"`
+	DetectorID    *uuid.UUID `json:"detectorId,omitempty" bun:"type:uuid"`
+	Tag           string     `json:"tag,omitempty" bun:",nullzero"`
+
+	StartsAt time.Time  `json:"startsAt"`
+	EndsAt   *time.Time `json:"endsAt,omitempty"`
+	// Recurrence is a RRULE (e.g. FREQ=WEEKLY;BYDAY=SU) or a cron expression (e.g. 0 2 * * 0)
+	Recurrence string `json:"recurrence,omitempty" bun:",nullzero"`
+	// Duration of every occurrence of a recurring window
+	Duration Duration `json:"duration,omitempty"`
+	Timezone string   `json:"timezone"`
+
+	CreatedBy *uuid.UUID `json:"createdBy,omitempty" bun:"type:uuid"`
+	CreatedAt time.Time  `json:"createdAt"`
+	UpdatedAt time.Time  `json:"updatedAt"`
+
+	// Active and Next are calculated on responses, see SetStatus
+	Active bool       `json:"active" bun:"-"`
+	Next   *TimeRange `json:"next,omitempty" bun:"-"`
+}
+
+// IsRecurring reports if the window has a recurrence
+func (mw *MaintenanceWindow) IsRecurring() bool {
+	return mw.Recurrence != ""
+}
+
+// MatchesMaintenance reports if the maintenance window applies to the detector
+func (d *Detector) MatchesMaintenance(mw *MaintenanceWindow) bool {
+	switch {
+	case mw.DetectorID != nil:
+		return *mw.DetectorID == d.ID
+	case mw.HostID != nil:
+		return *mw.HostID == d.HostID
+	case mw.Tag != "":
+		return slices.Contains(d.Tags, mw.Tag)
+	}
+	return false
+}
+
+// MatchesMaintenance reports if the maintenance window applies to the host
+func (h *Host) MatchesMaintenance(mw *MaintenanceWindow) bool {
+	switch {
+	case mw.HostID != nil:
+		return *mw.HostID == h.ID
+	case mw.Tag != "":
+		return slices.Contains(h.Tags, mw.Tag)
+	}
+	return false
+}
+
+// Finished reports if the window has ended and will not occur again
+func (mw *MaintenanceWindow) Finished(now time.Time) bool {
+	return mw.EndsAt != nil && !mw.EndsAt.After(now)
+}
+
+// ActiveAt reports if the window is active at the given time
+func (mw *MaintenanceWindow) ActiveAt(t time.Time) bool {
+	return len(mw.Occurrences(t, t.Add(time.Nanosecond))) > 0
+}
+
+// SetStatus sets the Active and Next field for the given time
+func (mw *MaintenanceWindow) SetStatus(now time.Time) {
+	mw.Active = mw.ActiveAt(now)
+	mw.Next = nil
+
+	if occurrences := mw.Occurrences(now, now.Add(nextOccurrenceRange)); len(occurrences) > 0 {
+		mw.Next = &occurrences[0]
+	}
+}
+
+// Occurrences returns all occurrences of the window which overlaps the time range [from, to)
+func (mw *MaintenanceWindow) Occurrences(from time.Time, to time.Time) []TimeRange {
+	if !mw.IsRecurring() {
+		if mw.EndsAt == nil {
+			return nil
+		}
+
+		tr := TimeRange{From: mw.StartsAt, To: *mw.EndsAt}
+		if tr.overlap(from, to) == 0 {
+			return nil
+		}
+		return []TimeRange{tr}
+	}
+
+	duration := time.Duration(mw.Duration)
+	starts, err := mw.starts(from.Add(-duration), to)
+	if err != nil {
+		return nil
+	}
+
+	occurrences := make([]TimeRange, 0, len(starts))
+	for _, start := range starts {
+		tr := TimeRange{From: start, To: start.Add(duration)}
+		if mw.EndsAt != nil && tr.To.After(*mw.EndsAt) {
+			tr.To = *mw.EndsAt
+		}
+
+		if tr.overlap(from, to) > 0 {
+			occurrences = append(occurrences, tr)
+		}
+	}
+
+	return occurrences
+}
+
+// starts returns the start times of the recurrence within [after, before]
+func (mw *MaintenanceWindow) starts(after time.Time, before time.Time) ([]time.Time, error) {
+	loc, err := time.LoadLocation(mw.Timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	if after.Before(mw.StartsAt) {
+		after = mw.StartsAt
+	}
+
+	if mw.EndsAt != nil && before.After(*mw.EndsAt) {
+		before = *mw.EndsAt
+	}
+
+	if after.After(before) {
+		return nil, nil
+	}
+
+	if isRRule(mw.Recurrence) {
+		option, err := rrule.StrToROptionInLocation(strings.TrimPrefix(mw.Recurrence, "RRULE:"), loc)
+		if err != nil {
+			return nil, err
+		}
+		option.Dtstart = mw.StartsAt.In(loc)
+
+		rule, err := rrule.NewRRule(*option)
+		if err != nil {
+			return nil, err
+		}
+
+		starts := rule.Between(after, before, true)
+		if len(starts) > maxOccurrences {
+			starts = starts[:maxOccurrences]
+		}
+		return starts, nil
+	}
+
+	schedule, err := cron.ParseStandard(mw.Recurrence)
+	if err != nil {
+		return nil, err
+	}
+
+	var starts []time.Time
+	// Next returns the next time after the given time, one second earlier includes after
+	t := after.In(loc).Add(-time.Second)
+	for len(starts) < maxOccurrences {
+		t = schedule.Next(t)
+		if t.IsZero() || t.After(before) {
+			break
+		}
+		starts = append(starts, t)
+	}
+
+	return starts, nil
+}
+
+func isRRule(recurrence string) bool {
+	return strings.Contains(strings.ToUpper(recurrence), "FREQ=")
+}
+
+func ValidateMaintenanceWindow(v *validator.Validator, mw *MaintenanceWindow) {
+	v.Check(len(mw.Name) > 3, "name", "name too short")
+
+	scopes := 0
+	if mw.HostID != nil {
+		scopes++
+	}
+	if mw.DetectorID != nil {
+		scopes++
+	}
+	if mw.Tag != "" {
+		scopes++
+	}
+	v.Check(scopes == 1, "scope", "exactly one of hostId, detectorId or tag must be provided")
+
+	v.Check(!mw.StartsAt.IsZero(), "startsAt", "must be provided")
+	_, err := time.LoadLocation(mw.Timezone)
+	v.Check(err == nil, "timezone", "invalid timezone")
+
+	if !mw.IsRecurring() {
+		v.Check(mw.EndsAt != nil, "endsAt", "must be provided for a one-off window")
+		if mw.EndsAt != nil {
+			v.Check(mw.EndsAt.After(mw.StartsAt), "endsAt", "must be after startsAt")
+		}
+		return
+	}
+
+	v.Check(mw.Duration > 0, "duration", "must be provided for a recurring window")
+	if mw.EndsAt != nil {
+		v.Check(mw.EndsAt.After(mw.StartsAt), "endsAt", "must be after startsAt")
+	}
+
+	if isRRule(mw.Recurrence) {
+		_, err = rrule.StrToROption(strings.TrimPrefix(mw.Recurrence, "RRULE:"))
+	} else {
+		_, err = cron.ParseStandard(mw.Recurrence)
+	}
+	v.Check(err == nil, "recurrence", "must be a valid RRULE or cron expression")
+}
@@ -0,0 +1,110 @@
+package maintenance
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	es "github.com/alexjoedt/echosight/internal"
+	"github.com/alexjoedt/echosight/internal/filter"
+	"github.com/alexjoedt/echosight/internal/logger"
+)
+
+// Calendar holds all maintenance windows in memory.
+// It must be reloaded after a window was created, changed or deleted.
+type Calendar struct {
+	service es.MaintenanceService
+	log     *logger.Logger
+
+	mu      sync.RWMutex
+	windows []*es.MaintenanceWindow
+}
+
+func NewCalendar(ms es.MaintenanceService) *Calendar {
+	return &Calendar{
+		service: ms,
+		log:     logger.New("Maintenance-Calendar"),
+	}
+}
+
+// Reload loads all maintenance windows
+func (c *Calendar) Reload(ctx context.Context) error {
+	windows, err := c.service.List(ctx, filter.NewDefaultMaintenanceFilter())
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.windows = windows
+	c.mu.Unlock()
+	return nil
+}
+
+// Active returns the active maintenance window of the detector at the given time or nil
+func (c *Calendar) Active(d *es.Detector, t time.Time) *es.MaintenanceWindow {
+	if c == nil {
+		return nil
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, mw := range c.windows {
+		if d.MatchesMaintenance(mw) && mw.ActiveAt(t) {
+			return mw
+		}
+	}
+	return nil
+}
+
+// DetectorWindows returns the active and upcoming windows of the detector
+func (c *Calendar) DetectorWindows(d *es.Detector, now time.Time) []*es.MaintenanceWindow {
+	return c.upcoming(now, d.MatchesMaintenance)
+}
+
+// HostWindows returns the active and upcoming windows of the host
+func (c *Calendar) HostWindows(h *es.Host, now time.Time) []*es.MaintenanceWindow {
+	return c.upcoming(now, h.MatchesMaintenance)
+}
+
+// upcoming returns copies of the matching windows which are active or occur again
+func (c *Calendar) upcoming(now time.Time, match func(*es.MaintenanceWindow) bool) []*es.MaintenanceWindow {
+	if c == nil {
+		return nil
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var windows []*es.MaintenanceWindow
+	for _, mw := range c.windows {
+		if !match(mw) || mw.Finished(now) {
+			continue
+		}
+
+		w := *mw
+		w.SetStatus(now)
+		if w.Active || w.Next != nil {
+			windows = append(windows, &w)
+		}
+	}
+	return windows
+}
+
+// Excluded returns the maintenance time ranges of the detector within [from, to)
+func (c *Calendar) Excluded(d *es.Detector, from time.Time, to time.Time) []es.TimeRange {
+	if c == nil {
+		return nil
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var excluded []es.TimeRange
+	for _, mw := range c.windows {
+		if d.MatchesMaintenance(mw) {
+			excluded = append(excluded, mw.Occurrences(from, to)...)
+		}
+	}
+	return excluded
+}
@@ -0,0 +1,176 @@
+package echosight
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// NotificationType describes why a notification is sent
+type NotificationType string
+
+const (
+	NotificationProblem       NotificationType = "problem"
+	NotificationRecovery      NotificationType = "recovery"
+	NotificationFlappingStart NotificationType = "flapping_start"
+	NotificationFlappingStop  NotificationType = "flapping_stop"
+	NotificationSLOFastBurn   NotificationType = "slo_fast_burn"
+	NotificationSLOSlowBurn   NotificationType = "slo_slow_burn"
+	NotificationSLORecovery   NotificationType = "slo_recovery"
+
+	NotificationIncidentAcknowledged NotificationType = "incident_acknowledged"
+	NotificationIncidentAssigned     NotificationType = "incident_assigned"
+	NotificationIncidentResolved     NotificationType = "incident_resolved"
+
+	NotificationEscalation NotificationType = "escalation"
+	// NotificationReminder is sent while a problem persists and is not acknowledged
+	NotificationReminder NotificationType = "reminder"
+
+	// NotificationGroup combines the results which arrived within the grouping window
+	NotificationGroup NotificationType = "group"
+	// NotificationDropped summarizes the results which exceeded the rate limit of a channel
+	NotificationDropped NotificationType = "dropped"
+	// NotificationDigest is the periodic summary of the non-OK detectors of a routing rule
+	NotificationDigest NotificationType = "digest"
+)
+
+func (nt NotificationType) String() string {
+	return string(nt)
+}
+
+// CombineResults combines the results into one notification.
+// The state is the worst state of the results, the message lists all results.
+func CombineResults(notification NotificationType, title string, results []*Result) *Result {
+	combined := &Result{
+		Host:         title,
+		Detector:     fmt.Sprintf("%d detectors", len(results)),
+		State:        StateOK,
+		Notification: notification,
+		Group:        results,
+	}
+
+	lines := make([]string, len(results))
+	for i, r := range results {
+		combined.State = WorseState(combined.State, r.State)
+		lines[i] = fmt.Sprintf("%s - %s: %s", r.Host, r.Detector, r.State)
+		if r.Message != "" {
+			lines[i] += " " + r.Message
+		}
+	}
+	combined.Message = strings.Join(lines, "\n")
+
+	return combined
+}
+
+// ElapsedText returns the elapsed duration of the problem rounded to minutes, e.g. 1h30m
+func (r *Result) ElapsedText() string {
+	s := time.Duration(r.Elapsed).Round(time.Minute).String()
+	return strings.TrimSuffix(s, "0s")
+}
+
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliveryDelivered DeliveryStatus = "delivered"
+	// DeliveryDead is the final state after the last failed attempt
+	DeliveryDead DeliveryStatus = "dead"
+)
+
+// Notification is a notification in the outbox for one channel.
+// It's delivered by the outbox workers and retried with an exponential backoff
+// until it's delivered or the max attempts are reached.
+type Notification struct {
+	bun.BaseModel `bun:"table:notifications"`
+	ID            uuid.UUID `json:"id" bun:"type:uuid,pk,default:uuid_generate_v4()"`
+	// Channel is the id of the sender, e.g. mail or telegram
+	Channel string           `json:"channel"`
+	Type    NotificationType `json:"type" bun:",nullzero"`
+	// Recipients limits the notification to these recipients, all if empty
+	Recipients []uuid.UUID `json:"recipients" bun:"type:uuid[],array"`
+	Payload    *Result     `json:"payload" bun:"type:jsonb"`
+
+	Status        DeliveryStatus `json:"status"`
+	Attempts      int            `json:"attempts"`
+	MaxAttempts   int            `json:"maxAttempts"`
+	NextAttemptAt time.Time      `json:"nextAttemptAt"`
+	LastError     string         `json:"lastError,omitempty" bun:",nullzero"`
+	DeliveredAt   *time.Time     `json:"deliveredAt,omitempty"`
+
+	Log []*DeliveryAttempt `json:"log,omitempty" bun:"rel:has-many,join:id=notification_id"`
+
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// NewNotification creates a pending notification of the result for the channel
+func NewNotification(channel string, result *Result, maxAttempts int) *Notification {
+	return &Notification{
+		Channel:       channel,
+		Type:          result.Notification,
+		Recipients:    result.Recipients,
+		Payload:       result,
+		Status:        DeliveryPending,
+		MaxAttempts:   maxAttempts,
+		NextAttemptAt: time.Now(),
+	}
+}
+
+// Result returns the payload with the recipients and the delivery id
+func (n *Notification) Result() *Result {
+	if n.Payload == nil {
+		return &Result{Recipients: n.Recipients, DeliveryID: n.ID.String()}
+	}
+	result := *n.Payload
+	result.Recipients = n.Recipients
+	result.DeliveryID = n.ID.String()
+	return &result
+}
+
+// Delivered marks the notification as delivered
+func (n *Notification) Delivered(now time.Time) {
+	n.Attempts++
+	n.Status = DeliveryDelivered
+	n.DeliveredAt = &now
+	n.LastError = ""
+}
+
+// Failed records a failed attempt and schedules the next attempt with an exponential backoff,
+// after the last attempt the notification is dead
+func (n *Notification) Failed(err error, now time.Time, base time.Duration, max time.Duration) {
+	n.Attempts++
+	n.LastError = err.Error()
+
+	if n.Attempts >= n.MaxAttempts {
+		n.Status = DeliveryDead
+		return
+	}
+	n.NextAttemptAt = now.Add(Backoff(n.Attempts, base, max))
+}
+
+// Backoff returns base * 2^(attempt-1), limited to max
+func Backoff(attempt int, base time.Duration, max time.Duration) time.Duration {
+	d := base
+	for i := 1; i < attempt; i++ {
+		d *= 2
+		if d >= max {
+			return max
+		}
+	}
+	return min(d, max)
+}
+
+// DeliveryAttempt is an attempt to deliver a notification
+type DeliveryAttempt struct {
+	bun.BaseModel  `bun:"table:notification_attempts"`
+	ID             uuid.UUID `json:"id" bun:"type:uuid,pk,default:uuid_generate_v4()"`
+	NotificationID uuid.UUID `json:"notificationId" bun:"type:uuid"`
+	Attempt        int       `json:"attempt"`
+	Success        bool      `json:"success"`
+	Error          string    `json:"error,omitempty" bun:",nullzero"`
+	Duration       Duration  `json:"duration"`
+	CreatedAt      time.Time `json:"createdAt"`
+}
@@ -0,0 +1,100 @@
+package echosight
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/alexjoedt/echosight/internal/validator"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// channelNameRX permits names which are usable as sender ids in routing rules
+var channelNameRX = regexp.MustCompile("^[a-z0-9][-_a-z0-9]{1,62}$")
+
+// ChannelSecretSuffixes are the suffixes of the settings which are stored encrypted
+var ChannelSecretSuffixes = []string{"password", "key", "secret", "token", "webhook_url"}
+
+// NotificationChannel is a configured sender, there can be several channels of the same type,
+// e.g. two Telegram bots. The name is the id of the sender in the routing rules and the outbox.
+//
+// The settings are the preferences of the sender without its prefix, e.g. chat_ids instead of
+// telegram_chat_ids. The secret settings are stored encrypted and are never returned.
+type NotificationChannel struct {
+	bun.BaseModel `bun:"table:notification_channels"`
+	ID            uuid.UUID         `json:"id" bun:"type:uuid,pk,default:uuid_generate_v4()"`
+	LookupVersion int               `json:"lookupVersion" bun:",default:1"`
+	Type          string            `json:"type"`
+	Name          string            `json:"name"`
+	Enabled       bool              `json:"enabled"`
+	Settings      map[string]string `json:"settings" bun:"type:jsonb"`
+
+	// Secrets are the encrypted secret settings
+	Secrets map[string]string `json:"-" bun:"type:jsonb"`
+	// SecretKeys are the keys of the stored secrets
+	SecretKeys []string `json:"secrets" bun:"-"`
+
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// IsChannelSecret reports if the setting is stored encrypted
+func IsChannelSecret(key string) bool {
+	for _, suffix := range ChannelSecretSuffixes {
+		if strings.HasSuffix(key, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetSettings replaces the settings and encrypts the secrets. The stored secrets
+// which aren't in the settings are kept, an empty secret removes it.
+func (c *NotificationChannel) SetSettings(crypter Crypter, settings map[string]string) error {
+	c.Settings = make(map[string]string)
+	if c.Secrets == nil {
+		c.Secrets = make(map[string]string)
+	}
+
+	for key, value := range settings {
+		if !IsChannelSecret(key) {
+			c.Settings[key] = value
+			continue
+		}
+
+		if value == "" {
+			delete(c.Secrets, key)
+			continue
+		}
+
+		crypted, err := crypter.Encrypt(value)
+		if err != nil {
+			return ErrInternalf("failed to encrypt setting %s", key).WithError(err)
+		}
+		c.Secrets[key] = crypted
+	}
+
+	c.SetSecretKeys()
+	return nil
+}
+
+// SetSecretKeys sets the sorted keys of the stored secrets
+func (c *NotificationChannel) SetSecretKeys() {
+	c.SecretKeys = make([]string, 0, len(c.Secrets))
+	for key := range c.Secrets {
+		c.SecretKeys = append(c.SecretKeys, key)
+	}
+	slices.Sort(c.SecretKeys)
+}
+
+func ValidateNotificationChannel(v *validator.Validator, c *NotificationChannel) {
+	v.Check(c.Type != "", "type", "must be provided")
+	v.Check(validator.Matches(c.Name, channelNameRX), "name", "must be 2-63 lowercase letters, digits, - or _")
+
+	for key := range c.Settings {
+		v.Check(key != "" && !strings.HasSuffix(key, "_crypt"), "settings", "invalid setting "+key)
+		v.Check(key != "enabled", "settings", "use the enabled field of the channel")
+	}
+}
@@ -0,0 +1,68 @@
+package echosight
+
+import (
+	htmltemplate "html/template"
+	"text/template"
+	"time"
+
+	"github.com/alexjoedt/echosight/internal/validator"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// NotificationTemplate renders the notifications of a channel.
+// Subject and Body are rendered with text/template, HTML with html/template.
+// A template with an empty type applies to all types of the channel without their own template,
+// without a stored template the built-in default of the channel is used.
+type NotificationTemplate struct {
+	bun.BaseModel `bun:"table:notification_templates"`
+	ID            uuid.UUID `json:"id" bun:"type:uuid,pk,default:uuid_generate_v4()"`
+	LookupVersion int       `json:"lookupVersion" bun:",default:1"`
+	Name          string    `json:"name"`
+	// Channel is the id of the sender, e.g. mail or telegram
+	Channel string           `json:"channel"`
+	Type    NotificationType `json:"type"`
+	Active  bool             `json:"active"`
+
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+	// HTML is the html body of mails, optional
+	HTML string `json:"html"`
+
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// TemplateData is the context of the notification templates.
+// The fields of the result are available directly, e.g. {{.Host}}, {{.Detector}}, {{.State}},
+// {{.Message}}, {{.Notification}}, {{.Reminder}}, {{.ElapsedText}} or {{range .Group}}.
+type TemplateData struct {
+	*Result
+	// Incident is the unresolved incident of the detector, nil without an incident
+	Incident *Incident
+	Links    TemplateLinks
+}
+
+// TemplateLinks are the links to the web client, empty without a public url
+type TemplateLinks struct {
+	Detector string `json:"detector,omitempty"`
+	Incident string `json:"incident,omitempty"`
+}
+
+func ValidateNotificationTemplate(v *validator.Validator, t *NotificationTemplate) {
+	v.Check(len(t.Name) > 3, "name", "name too short")
+	v.Check(t.Channel != "", "channel", "must be provided")
+	v.Check(t.Body != "", "body", "must be provided")
+
+	if _, err := template.New("subject").Parse(t.Subject); err != nil {
+		v.AddError("subject", err.Error())
+	}
+
+	if _, err := template.New("body").Parse(t.Body); err != nil {
+		v.AddError("body", err.Error())
+	}
+
+	if _, err := htmltemplate.New("html").Parse(t.HTML); err != nil {
+		v.AddError("html", err.Error())
+	}
+}
@@ -0,0 +1,263 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
+	es "github.com/alexjoedt/echosight/internal"
+	"github.com/alexjoedt/echosight/internal/filter"
+	"github.com/alexjoedt/echosight/internal/validator"
+	"github.com/google/uuid"
+)
+
+const (
+	// botCheckWait is the maximum time /check waits for the result
+	botCheckWait time.Duration = time.Second * 30
+	// botMaxSilence is the maximum duration of /silence
+	botMaxSilence time.Duration = time.Hour * 24 * 7
+)
+
+// Acknowledger acknowledges incidents, e.g. the incident manager
+type Acknowledger interface {
+	Acknowledge(ctx context.Context, id uuid.UUID, user *es.User) (*es.Incident, error)
+}
+
+// Checker runs the check of a detector immediately, e.g. the scheduler
+type Checker interface {
+	CheckNow(detectorID uuid.UUID) error
+}
+
+// Reloader reloads the data held in memory, e.g. the maintenance calendar
+type Reloader interface {
+	Reload(ctx context.Context) error
+}
+
+// BotCommands answers the commands of the chat bots with html text.
+// A command without its dependencies answers that it's not available.
+type BotCommands struct {
+	Detectors    es.DetectorService
+	Incidents    es.IncidentService
+	Acknowledger Acknowledger
+	Checker      Checker
+	Maintenance  es.MaintenanceService
+	// Calendar is reloaded after a silence was created
+	Calendar Reloader
+}
+
+const botHelp = `<b>Commands</b>
+/status - detectors with problems
+/ack &lt;incident or detector&gt; - acknowledge the incident
+/silence &lt;detector&gt; &lt;duration&gt; - no notifications for the detector, e.g. /silence web-http 1h
+/check &lt;detector&gt; - run the check now`
+
+// Run runs the command of the text, from names the user in the incident timeline and the maintenance window
+func (b *BotCommands) Run(ctx context.Context, text string, from string) string {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return botHelp
+	}
+
+	// commands in groups are sent as /command@botname
+	command, _, _ := strings.Cut(strings.ToLower(fields[0]), "@")
+	args := fields[1:]
+
+	var answer string
+	var err error
+	switch command {
+	case "/status":
+		answer, err = b.status(ctx)
+	case "/ack":
+		answer, err = b.ack(ctx, args, from)
+	case "/silence":
+		answer, err = b.silence(ctx, args, from)
+	case "/check":
+		answer, err = b.check(ctx, args)
+	default:
+		return botHelp
+	}
+
+	if err != nil {
+		return "❌ " + html.EscapeString(err.Error())
+	}
+	return answer
+}
+
+func (b *BotCommands) status(ctx context.Context) (string, error) {
+	if b.Detectors == nil {
+		return "", fmt.Errorf("/status is not available")
+	}
+
+	f := filter.NewDefaultDetectorFilter()
+	active := true
+	f.Active = &active
+	detectors, err := b.Detectors.List(ctx, f)
+	if err != nil {
+		return "", err
+	}
+
+	var lines []string
+	for _, d := range detectors {
+		if d.HardState == es.StateOK || d.HardState == es.StateInactive || d.HardState == "" {
+			continue
+		}
+
+		line := fmt.Sprintf("%s <b>%s - %s</b>: %s", stateEmoji(d.HardState), html.EscapeString(d.HostName), html.EscapeString(d.Name), d.HardState)
+		if d.InMaintenance {
+			line += " (maintenance)"
+		}
+		if d.StatusMessage != "" {
+			line += "\n<code>" + html.EscapeString(d.StatusMessage) + "</code>"
+		}
+		lines = append(lines, line)
+	}
+
+	if len(lines) == 0 {
+		return fmt.Sprintf("✅ All %d detectors are OK", len(detectors)), nil
+	}
+	return fmt.Sprintf("<b>%d of %d detectors with problems</b>\n", len(lines), len(detectors)) + strings.Join(lines, "\n"), nil
+}
+
+// ack acknowledges the incident by id or the unresolved incident of the detector
+func (b *BotCommands) ack(ctx context.Context, args []string, from string) (string, error) {
+	if b.Acknowledger == nil || b.Incidents == nil || b.Detectors == nil {
+		return "", fmt.Errorf("/ack is not available")
+	}
+	if len(args) == 0 {
+		return "", fmt.Errorf("usage: /ack <incident or detector>")
+	}
+
+	incidentID, err := uuid.Parse(args[0])
+	if err != nil {
+		detector, err := b.detector(ctx, strings.Join(args, " "))
+		if err != nil {
+			return "", err
+		}
+
+		incident, err := b.Incidents.GetUnresolved(ctx, detector.ID)
+		if err != nil {
+			return "", err
+		}
+		incidentID = incident.ID
+	}
+
+	incident, err := b.Acknowledger.Acknowledge(ctx, incidentID, &es.User{FirstName: from})
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("👍 Incident <b>%s - %s</b> acknowledged", html.EscapeString(incident.HostName), html.EscapeString(incident.DetectorName)), nil
+}
+
+// silence creates a maintenance window for the detector from now
+func (b *BotCommands) silence(ctx context.Context, args []string, from string) (string, error) {
+	if b.Maintenance == nil || b.Detectors == nil {
+		return "", fmt.Errorf("/silence is not available")
+	}
+	if len(args) < 2 {
+		return "", fmt.Errorf("usage: /silence <detector> <duration>")
+	}
+
+	duration, err := time.ParseDuration(args[len(args)-1])
+	if err != nil || duration <= 0 || duration > botMaxSilence {
+		return "", fmt.Errorf("invalid duration %s, e.g. 30m or 2h, max %s", args[len(args)-1], botMaxSilence)
+	}
+
+	detector, err := b.detector(ctx, strings.Join(args[:len(args)-1], " "))
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	endsAt := now.Add(duration)
+	window := &es.MaintenanceWindow{
+		Name:        "Silence " + detector.Name,
+		Description: "silenced by " + from,
+		DetectorID:  &detector.ID,
+		StartsAt:    now,
+		EndsAt:      &endsAt,
+		Timezone:    "UTC",
+	}
+
+	v := validator.New()
+	es.ValidateMaintenanceWindow(v, window)
+	if !v.Valid() {
+		return "", fmt.Errorf("invalid silence: %v", v.Errors)
+	}
+
+	if err := b.Maintenance.Create(ctx, window); err != nil {
+		return "", err
+	}
+
+	if b.Calendar != nil {
+		if err := b.Calendar.Reload(ctx); err != nil {
+			return "", err
+		}
+	}
+
+	return fmt.Sprintf("🔕 <b>%s - %s</b> silenced until %s", html.EscapeString(detector.HostName), html.EscapeString(detector.Name), endsAt.Format("2006-01-02 15:04 MST")), nil
+}
+
+// check runs the check of the detector and waits for the result
+func (b *BotCommands) check(ctx context.Context, args []string) (string, error) {
+	if b.Checker == nil || b.Detectors == nil {
+		return "", fmt.Errorf("/check is not available")
+	}
+	if len(args) == 0 {
+		return "", fmt.Errorf("usage: /check <detector>")
+	}
+
+	detector, err := b.detector(ctx, strings.Join(args, " "))
+	if err != nil {
+		return "", err
+	}
+
+	lastChecked := detector.LastCheckedAt
+	if err := b.Checker.CheckNow(detector.ID); err != nil {
+		return "", err
+	}
+
+	deadline := time.Now().Add(botCheckWait)
+	for !detector.LastCheckedAt.After(lastChecked) {
+		if time.Now().After(deadline) {
+			return fmt.Sprintf("⏳ Check of <b>%s - %s</b> is still running", html.EscapeString(detector.HostName), html.EscapeString(detector.Name)), nil
+		}
+		if !sleep(ctx, time.Second) {
+			return "", ctx.Err()
+		}
+
+		detector, err = b.Detectors.GetByID(ctx, detector.ID)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	answer := fmt.Sprintf("%s <b>%s - %s</b>: %s", stateEmoji(detector.State), html.EscapeString(detector.HostName), html.EscapeString(detector.Name), detector.State)
+	if detector.StatusMessage != "" {
+		answer += "\n<code>" + html.EscapeString(detector.StatusMessage) + "</code>"
+	}
+	return answer, nil
+}
+
+// detector returns the detector by id or name
+func (b *BotCommands) detector(ctx context.Context, arg string) (*es.Detector, error) {
+	if id, err := uuid.Parse(arg); err == nil {
+		return b.Detectors.GetByID(ctx, id)
+	}
+	return b.Detectors.GetByName(ctx, arg)
+}
+
+func stateEmoji(state es.State) string {
+	switch state {
+	case es.StateOK:
+		return "✅"
+	case es.StateWarn:
+		return "⚠️"
+	case es.StateCritical:
+		return "🔴"
+	default:
+		return "ℹ️"
+	}
+}
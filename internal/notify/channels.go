@@ -0,0 +1,528 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	es "github.com/alexjoedt/echosight/internal"
+	"github.com/alexjoedt/echosight/internal/filter"
+	"github.com/alexjoedt/echosight/internal/logger"
+	"github.com/alexjoedt/echosight/internal/validator"
+	"github.com/google/uuid"
+)
+
+// ChannelFactory creates the sender of a channel. The preferences hold the settings
+// of the channel with the prefix of the type, e.g. telegram_chat_ids for chat_ids.
+type ChannelFactory func(channel *es.NotificationChannel, prefService es.PreferenceService) (Sender, error)
+
+type channelType struct {
+	prefix  string
+	factory ChannelFactory
+}
+
+// channelSender is a registered sender of a channel
+type channelSender struct {
+	name          string
+	lookupVersion int
+	sender        Sender
+}
+
+// Channels registers the senders of the enabled notification channels in the notifier.
+// It must be reloaded after a channel has changed, the senders of unchanged channels are kept.
+// The senders are started and stopped, if they implement Start and Stop.
+type Channels struct {
+	service  es.NotificationChannelService
+	notifier *Notifier
+	log      *logger.Logger
+
+	mu      sync.Mutex
+	types   map[string]channelType
+	senders map[uuid.UUID]*channelSender
+
+	// Crypter decrypts the secrets of the channels
+	Crypter es.Crypter
+	// Renderer renders the messages of the senders, optional
+	Renderer *Renderer
+	// Recipients are required for the ntfy and Gotify channels
+	Recipients es.RecipientService
+	// Commands answers the bot commands of the Telegram channels, optional
+	Commands *BotCommands
+	// Webhooks posts the notifications of the webhook channels
+	Webhooks *WebhookSender
+}
+
+func NewChannels(cs es.NotificationChannelService, n *Notifier) *Channels {
+	c := &Channels{
+		service:  cs,
+		notifier: n,
+		log:      logger.New("Channels"),
+		types:    make(map[string]channelType),
+		senders:  make(map[uuid.UUID]*channelSender),
+	}
+	c.registerDefaults()
+	return c
+}
+
+// Register adds a type of channels, the settings are passed with the prefix to the factory
+func (c *Channels) Register(name string, prefix string, factory ChannelFactory) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.types[name] = channelType{prefix: prefix, factory: factory}
+}
+
+// Supports reports if the channel type is registered
+func (c *Channels) Supports(name string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.types[name]
+	return ok
+}
+
+// Types returns the sorted registered channel types
+func (c *Channels) Types() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	types := make([]string, 0, len(c.types))
+	for t := range c.types {
+		types = append(types, t)
+	}
+	slices.Sort(types)
+	return types
+}
+
+// Reserved reports if the name is the id of a sender which isn't a channel, e.g. the built-in mail
+func (c *Channels) Reserved(name string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.reserved(name)
+}
+
+func (c *Channels) reserved(name string) bool {
+	for _, cs := range c.senders {
+		if cs.name == name {
+			return false
+		}
+	}
+	return c.notifier.Has(name)
+}
+
+// Validate builds the sender of the channel without registering it. It fails, if the settings
+// are invalid or incomplete, e.g. a missing token, so the channel should not be saved.
+func (c *Channels) Validate(channel *es.NotificationChannel) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	sender, err := c.build(channel)
+	if err != nil {
+		return err
+	}
+	// e.g. the mailer starts its workers with the build
+	defer stopSender(sender)
+
+	if !sender.Enabled() {
+		return fmt.Errorf("the settings of %s are incomplete", channel.Type)
+	}
+	return nil
+}
+
+// Reload loads the enabled channels and replaces the senders of the changed channels in the notifier.
+// A changed channel which fails to build keeps its previous sender, a new one is skipped.
+// The errors are returned joined.
+func (c *Channels) Reload(ctx context.Context) error {
+	f := filter.NewDefaultNotificationChannelFilter()
+	enabled := true
+	f.Enabled = &enabled
+
+	channels, err := c.service.List(ctx, f)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var buildErr []error
+	senders := make(map[uuid.UUID]*channelSender, len(channels))
+	added := make(map[string]Sender)
+	for _, channel := range channels {
+		if cs, ok := c.senders[channel.ID]; ok && cs.lookupVersion == channel.LookupVersion {
+			senders[channel.ID] = cs
+			continue
+		}
+
+		// a channel can't take the name of a built-in sender
+		if c.reserved(channel.Name) {
+			buildErr = append(buildErr, fmt.Errorf("notification channel %s: the name is used by a built-in sender", channel.Name))
+			continue
+		}
+
+		sender, err := c.build(channel)
+		if err != nil {
+			buildErr = append(buildErr, fmt.Errorf("notification channel %s: %w", channel.Name, err))
+			// the channel keeps sending with the previous settings, if it wasn't renamed
+			if cs, ok := c.senders[channel.ID]; ok && cs.name == channel.Name {
+				senders[channel.ID] = cs
+			}
+			continue
+		}
+
+		senders[channel.ID] = &channelSender{name: channel.Name, lookupVersion: channel.LookupVersion, sender: sender}
+		added[channel.Name] = sender
+	}
+
+	var removed []Sender
+	var removedNames []string
+	for id, cs := range c.senders {
+		if senders[id] != cs {
+			removed = append(removed, cs.sender)
+			removedNames = append(removedNames, cs.name)
+		}
+	}
+
+	if err := c.notifier.ReplaceSenders(removedNames, added); err != nil {
+		buildErr = append(buildErr, err)
+	}
+	c.senders = senders
+
+	for _, sender := range removed {
+		stopSender(sender)
+	}
+	for _, sender := range added {
+		if s, ok := sender.(interface{ Start() }); ok {
+			s.Start()
+		}
+	}
+
+	if len(added) > 0 || len(removed) > 0 {
+		c.log.Debugw("notification channels reloaded", logger.Int("added", len(added)), logger.Int("removed", len(removed)))
+	}
+
+	return errors.Join(buildErr...)
+}
+
+// preferenceChannels are the types of the preference based senders of older versions
+var preferenceChannels = []string{"telegram", "slack", "mattermost", "teams", "pagerduty", "opsgenie", "ntfy", "gotify", "matrix"}
+
+// MigratePreferences moves the configurations of the preference based senders of older versions
+// to channels named like the sender, e.g. telegram_chat_ids becomes the setting chat_ids of the
+// channel telegram. The plain secrets are encrypted, an enabled channel which fails the validation
+// is created disabled. The migrated preferences are removed.
+func (c *Channels) MigratePreferences(ctx context.Context, prefService es.PreferenceService) error {
+	prefs, err := prefService.AllPreferences(ctx)
+	if err != nil {
+		return err
+	}
+	all := prefs.Map()
+
+	var migrateErr []error
+	for _, name := range preferenceChannels {
+		if _, ok := all[name+"_enabled"]; !ok {
+			continue
+		}
+
+		if err := c.migratePreferences(ctx, prefService, name, all); err != nil {
+			migrateErr = append(migrateErr, fmt.Errorf("preferences of %s: %w", name, err))
+		}
+	}
+
+	return errors.Join(migrateErr...)
+}
+
+func (c *Channels) migratePreferences(ctx context.Context, prefService es.PreferenceService, name string, all map[string]string) error {
+	f := filter.NewDefaultNotificationChannelFilter()
+	f.Name = &name
+	existing, err := c.service.List(ctx, f)
+	if err != nil {
+		return err
+	}
+	if len(existing) > 0 {
+		c.log.Warnf("the preferences of %s aren't migrated, a notification channel with the name exists", name)
+		return nil
+	}
+
+	var keys []string
+	settings := make(map[string]string)
+	crypted := make(map[string]string)
+	for key, value := range all {
+		setting, ok := strings.CutPrefix(key, name+"_")
+		if !ok {
+			continue
+		}
+		keys = append(keys, key)
+
+		switch {
+		case setting == "enabled":
+		case strings.HasSuffix(setting, "_crypt"):
+			crypted[strings.TrimSuffix(setting, "_crypt")] = value
+		default:
+			settings[setting] = value
+		}
+	}
+
+	channel := &es.NotificationChannel{
+		Type:    name,
+		Name:    name,
+		Enabled: all[name+"_enabled"] == "true",
+	}
+	if err := channel.SetSettings(c.Crypter, settings); err != nil {
+		return err
+	}
+	// the encrypted secrets are moved as they are, they replace the plain secrets
+	for key, value := range crypted {
+		channel.Secrets[key] = value
+	}
+	channel.SetSecretKeys()
+
+	if channel.Enabled {
+		if err := c.Validate(channel); err != nil {
+			c.log.Warnf("the migrated notification channel %s is disabled, it has to be completed: %v", name, err)
+			channel.Enabled = false
+		}
+	}
+
+	if err := c.service.Create(ctx, channel); err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		if err := prefService.DeleteByName(ctx, key); err != nil {
+			c.log.Errorf("failed to delete the migrated preference %s: %v", key, err)
+		}
+	}
+
+	c.log.Infof("the preferences of %s are migrated to a notification channel", name)
+	return nil
+}
+
+// Stop removes the senders of the channels from the notifier and stops them
+func (c *Channels) Stop() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	names := make([]string, 0, len(c.senders))
+	for _, cs := range c.senders {
+		names = append(names, cs.name)
+	}
+	c.notifier.ReplaceSenders(names, nil)
+
+	for _, cs := range c.senders {
+		stopSender(cs.sender)
+	}
+	c.senders = make(map[uuid.UUID]*channelSender)
+}
+
+func (c *Channels) build(channel *es.NotificationChannel) (Sender, error) {
+	t, ok := c.types[channel.Type]
+	if !ok {
+		return nil, fmt.Errorf("unknown type %s", channel.Type)
+	}
+
+	return t.factory(channel, newChannelPreferences(channel, t.prefix))
+}
+
+func stopSender(sender Sender) {
+	switch s := sender.(type) {
+	case interface{ Stop() }:
+		s.Stop()
+	case interface{ Stop() error }:
+		if err := s.Stop(); err != nil {
+			logger.Errorf("failed to stop sender: %v", err)
+		}
+	}
+}
+
+// registerDefaults registers the channel types of this package, the mail channels
+// are registered by the app
+func (c *Channels) registerDefaults() {
+	c.types["telegram"] = channelType{prefix: "telegram", factory: func(_ *es.NotificationChannel, prefService es.PreferenceService) (Sender, error) {
+		tele, err := NewTelegramBot(prefService, c.Crypter)
+		if err != nil {
+			return nil, err
+		}
+		tele.Renderer = c.Renderer
+		tele.Commands = c.Commands
+		return tele, nil
+	}}
+
+	for _, name := range []string{"slack", "mattermost"} {
+		c.types[name] = channelType{prefix: name, factory: func(_ *es.NotificationChannel, prefService es.PreferenceService) (Sender, error) {
+			slack, err := NewSlack(name, prefService, c.Crypter)
+			if err != nil {
+				return nil, err
+			}
+			slack.Renderer = c.Renderer
+			return slack, nil
+		}}
+	}
+
+	c.types["teams"] = channelType{prefix: "teams", factory: func(_ *es.NotificationChannel, prefService es.PreferenceService) (Sender, error) {
+		teams, err := NewTeams(prefService, c.Crypter)
+		if err != nil {
+			return nil, err
+		}
+		teams.Renderer = c.Renderer
+		return teams, nil
+	}}
+
+	c.types["pagerduty"] = channelType{prefix: "pagerduty", factory: func(_ *es.NotificationChannel, prefService es.PreferenceService) (Sender, error) {
+		pagerDuty, err := NewPagerDuty(prefService, c.Crypter)
+		if err != nil {
+			return nil, err
+		}
+		pagerDuty.Renderer = c.Renderer
+		return pagerDuty, nil
+	}}
+
+	c.types["opsgenie"] = channelType{prefix: "opsgenie", factory: func(_ *es.NotificationChannel, prefService es.PreferenceService) (Sender, error) {
+		opsgenie, err := NewOpsgenie(prefService, c.Crypter)
+		if err != nil {
+			return nil, err
+		}
+		opsgenie.Renderer = c.Renderer
+		return opsgenie, nil
+	}}
+
+	c.types["ntfy"] = channelType{prefix: "ntfy", factory: func(_ *es.NotificationChannel, prefService es.PreferenceService) (Sender, error) {
+		ntfy, err := NewNtfy(prefService, c.Recipients, c.Crypter)
+		if err != nil {
+			return nil, err
+		}
+		ntfy.Renderer = c.Renderer
+		return ntfy, nil
+	}}
+
+	c.types["gotify"] = channelType{prefix: "gotify", factory: func(_ *es.NotificationChannel, prefService es.PreferenceService) (Sender, error) {
+		gotify, err := NewGotify(prefService, c.Recipients, c.Crypter)
+		if err != nil {
+			return nil, err
+		}
+		gotify.Renderer = c.Renderer
+		return gotify, nil
+	}}
+
+	c.types["matrix"] = channelType{prefix: "matrix", factory: func(_ *es.NotificationChannel, prefService es.PreferenceService) (Sender, error) {
+		matrix, err := NewMatrix(prefService, c.Crypter)
+		if err != nil {
+			return nil, err
+		}
+		matrix.Renderer = c.Renderer
+		return matrix, nil
+	}}
+
+	c.types["webhook"] = channelType{prefix: "webhook", factory: func(channel *es.NotificationChannel, _ es.PreferenceService) (Sender, error) {
+		if c.Webhooks == nil {
+			return nil, fmt.Errorf("webhook sender is not initialized")
+		}
+		return newWebhookChannel(c.Webhooks, channel)
+	}}
+}
+
+// webhookChannel posts all notifications to the webhook of a channel.
+// The settings are url, body and timeout, the secret signs the body.
+type webhookChannel struct {
+	sender  *WebhookSender
+	webhook *es.Webhook
+}
+
+func newWebhookChannel(ws *WebhookSender, channel *es.NotificationChannel) (*webhookChannel, error) {
+	webhook := &es.Webhook{
+		Name:        channel.Name,
+		URL:         channel.Settings["url"],
+		Active:      true,
+		Body:        channel.Settings["body"],
+		SecretCrypt: channel.Secrets["secret"],
+	}
+
+	if timeout := channel.Settings["timeout"]; timeout != "" {
+		d, err := time.ParseDuration(timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timeout: %w", err)
+		}
+		webhook.Timeout = es.Duration(d)
+	}
+
+	v := validator.New()
+	es.ValidateWebhookTarget(v, webhook)
+	if !v.Valid() {
+		return nil, fmt.Errorf("invalid webhook: %v", v.Errors)
+	}
+
+	return &webhookChannel{sender: ws, webhook: webhook}, nil
+}
+
+func (wc *webhookChannel) Send(ctx context.Context, result *es.Result) error {
+	return wc.sender.Post(ctx, wc.webhook, result)
+}
+
+// Enabled is always true, disabled channels aren't registered
+func (wc *webhookChannel) Enabled() bool {
+	return true
+}
+
+var _ es.PreferenceService = (*channelPreferences)(nil)
+
+// channelPreferences provides the settings of a channel to the senders, which read their preferences.
+// The secrets are provided encrypted with the _crypt suffix, the preferences can't be changed.
+type channelPreferences struct {
+	prefs *es.Preferences
+}
+
+var errChannelPreferences = errors.New("the preferences of a notification channel are read only")
+
+func newChannelPreferences(channel *es.NotificationChannel, prefix string) *channelPreferences {
+	prefs := &es.Preferences{}
+	for key, value := range channel.Settings {
+		prefs.Set(prefix+"_"+key, value)
+	}
+	for key, value := range channel.Secrets {
+		prefs.Set(prefix+"_"+key+"_crypt", value)
+	}
+	prefs.Set(prefix+"_enabled", "true")
+
+	return &channelPreferences{prefs: prefs}
+}
+
+// clone returns a copy, the senders must not change the settings
+func (cp *channelPreferences) clone() *es.Preferences {
+	prefs := &es.Preferences{}
+	for key, value := range cp.prefs.Map() {
+		prefs.Set(key, value)
+	}
+	return prefs
+}
+
+func (cp *channelPreferences) AllPreferences(ctx context.Context) (*es.Preferences, error) {
+	return cp.clone(), nil
+}
+
+func (cp *channelPreferences) GetByName(ctx context.Context, name string) (*es.Preference, error) {
+	if !cp.prefs.Has(name) {
+		return nil, es.ErrNotfoundf("no preference found")
+	}
+	return &es.Preference{Name: name, Value: cp.prefs.Get(name)}, nil
+}
+
+func (cp *channelPreferences) List(ctx context.Context, prefFilter *filter.PreferenceFilter) (*es.Preferences, error) {
+	return cp.clone(), nil
+}
+
+func (cp *channelPreferences) Set(ctx context.Context, pref *es.Preference) error {
+	return errChannelPreferences
+}
+
+func (cp *channelPreferences) Update(ctx context.Context, pref *es.Preference) error {
+	return errChannelPreferences
+}
+
+func (cp *channelPreferences) SetAll(ctx context.Context, prefs *es.Preferences) error {
+	return errChannelPreferences
+}
+
+func (cp *channelPreferences) DeleteByName(ctx context.Context, name string) error {
+	return errChannelPreferences
+}
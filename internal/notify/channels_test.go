@@ -0,0 +1,142 @@
+package notify
+
+import (
+	"context"
+	"testing"
+
+	es "github.com/alexjoedt/echosight/internal"
+	"github.com/alexjoedt/echosight/internal/filter"
+	"github.com/google/uuid"
+)
+
+type fakeChannels struct {
+	es.NotificationChannelService
+	channels []*es.NotificationChannel
+}
+
+func (f *fakeChannels) List(ctx context.Context, channelFilter *filter.NotificationChannelFilter) ([]*es.NotificationChannel, error) {
+	var channels []*es.NotificationChannel
+	for _, channel := range f.channels {
+		if channelFilter.Name == nil || *channelFilter.Name == channel.Name {
+			channels = append(channels, channel)
+		}
+	}
+	return channels, nil
+}
+
+func (f *fakeChannels) Create(ctx context.Context, channel *es.NotificationChannel) error {
+	f.channels = append(f.channels, channel)
+	return nil
+}
+
+type fakePreferences struct {
+	es.PreferenceService
+	prefs *es.Preferences
+}
+
+func (f *fakePreferences) AllPreferences(ctx context.Context) (*es.Preferences, error) {
+	return f.prefs, nil
+}
+
+func (f *fakePreferences) DeleteByName(ctx context.Context, name string) error {
+	f.prefs.Delete(name)
+	return nil
+}
+
+func TestChannelsValidate(t *testing.T) {
+	c := NewChannels(&fakeChannels{}, NewNotifier())
+	c.Crypter = fakeCrypter{}
+
+	incomplete := &es.NotificationChannel{Type: "pagerduty", Name: "pd", Enabled: true, Settings: map[string]string{}}
+	if err := c.Validate(incomplete); err == nil {
+		t.Fatal("channel without routing key is valid")
+	}
+
+	complete := &es.NotificationChannel{Type: "pagerduty", Name: "pd", Enabled: true, Secrets: map[string]string{"routing_key": "crypt:key"}}
+	if err := c.Validate(complete); err != nil {
+		t.Fatalf("valid channel: %v", err)
+	}
+}
+
+func TestChannelsValidateShortWebhookName(t *testing.T) {
+	c := NewChannels(&fakeChannels{}, NewNotifier())
+	c.Webhooks = NewWebhookSender(&fakeWebhooks{}, fakeCrypter{})
+
+	// the name is valid for a channel, but too short for a stored webhook
+	channel := &es.NotificationChannel{Type: "webhook", Name: "ops", Enabled: true, Settings: map[string]string{"url": "https://example.com/hook"}}
+	if err := c.Validate(channel); err != nil {
+		t.Fatalf("valid webhook channel: %v", err)
+	}
+}
+
+func TestChannelsReloadKeepsSenderOfFailedRebuild(t *testing.T) {
+	channel := &es.NotificationChannel{
+		ID:            uuid.New(),
+		LookupVersion: 1,
+		Type:          "webhook",
+		Name:          "hook",
+		Enabled:       true,
+		Settings:      map[string]string{"url": "https://example.com/hook"},
+	}
+	service := &fakeChannels{channels: []*es.NotificationChannel{channel}}
+
+	n := NewNotifier()
+	c := NewChannels(service, n)
+	c.Webhooks = NewWebhookSender(&fakeWebhooks{}, fakeCrypter{})
+	if err := c.Reload(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	broken := *channel
+	broken.LookupVersion = 2
+	broken.Settings = map[string]string{"url": "https://example.com/hook", "timeout": "soon"}
+	service.channels = []*es.NotificationChannel{&broken}
+	if err := c.Reload(context.Background()); err == nil {
+		t.Fatal("expected the build error of the channel")
+	}
+
+	if !n.Has("hook") {
+		t.Fatal("failed rebuild dropped the sender of the channel")
+	}
+}
+
+func TestChannelsMigratePreferences(t *testing.T) {
+	prefs := &es.Preferences{}
+	prefs.Set("smtp_host", "mail.example.com")
+	// older versions stored the token of the bot plain
+	prefs.Set("telegram_bot_token", "token")
+	prefs.Set("telegram_chat_ids", "1,2")
+	prefs.Set("telegram_enabled", "true")
+	// the routing key is missing
+	prefs.Set("pagerduty_base_url", "https://events.example.com")
+	prefs.Set("pagerduty_enabled", "true")
+
+	service := &fakeChannels{}
+	c := NewChannels(service, NewNotifier())
+	c.Crypter = fakeCrypter{}
+	if err := c.MigratePreferences(context.Background(), &fakePreferences{prefs: prefs}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(service.channels) != 2 {
+		t.Fatalf("expected 2 migrated channels, got %d", len(service.channels))
+	}
+
+	telegram := service.channels[0]
+	if !telegram.Enabled || telegram.Secrets["bot_token"] != "crypt:token" || telegram.Settings["chat_ids"] != "1,2" {
+		t.Fatalf("invalid telegram channel: enabled %t, secrets %v, settings %v", telegram.Enabled, telegram.Secrets, telegram.Settings)
+	}
+
+	if pagerDuty := service.channels[1]; pagerDuty.Enabled {
+		t.Fatal("incomplete pagerduty channel is enabled")
+	}
+
+	if !prefs.Has("smtp_host") || prefs.Has("telegram_bot_token") || prefs.Has("pagerduty_enabled") {
+		t.Fatalf("unexpected preferences after the migration: %v", prefs.Map())
+	}
+
+	// the migration runs once
+	if err := c.MigratePreferences(context.Background(), &fakePreferences{prefs: prefs}); err != nil || len(service.channels) != 2 {
+		t.Fatalf("second migration: %v, %d channels", err, len(service.channels))
+	}
+}
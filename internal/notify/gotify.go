@@ -0,0 +1,163 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	es "github.com/alexjoedt/echosight/internal"
+)
+
+const gotifyTimeout time.Duration = time.Second * 10
+
+var (
+	ErrNoGotifyURL   error = errors.New("no gotify url")
+	ErrNoGotifyToken error = errors.New("no gotify app token")
+)
+
+var _ Sender = (*Gotify)(nil)
+
+// gotifyPriorities are the default priorities of the states,
+// the Gotify apps notify with sound from 4 and show high priorities from 8
+var gotifyPriorities = map[es.State]int{
+	es.StateOK:       2,
+	es.StateWarn:     5,
+	es.StateCritical: 8,
+}
+
+// GotifyMessage is the body of a Gotify message
+type GotifyMessage struct {
+	Title    string         `json:"title"`
+	Message  string         `json:"message"`
+	Priority int            `json:"priority"`
+	Extras   map[string]any `json:"extras,omitempty"`
+}
+
+// Gotify sends the notifications with the app tokens of the recipients to a Gotify server.
+// The configuration is read from the preferences:
+//
+//	gotify_enabled          "true" to enable the sender
+//	gotify_url              the Gotify server
+//	gotify_token_crypt      the encrypted app token for notifications without recipients, optional
+//	gotify_priority_<state> overrides the priority of the state (ok, warn, critical), optional
+type Gotify struct {
+	prefs      *prefs
+	recipients es.RecipientService
+	client     *http.Client
+
+	// Renderer renders the title and the message, optional
+	Renderer *Renderer
+}
+
+func NewGotify(prefService es.PreferenceService, rs es.RecipientService, crypter es.Crypter) (*Gotify, error) {
+	if prefService == nil {
+		return nil, fmt.Errorf("preference service is nil")
+	}
+
+	return &Gotify{
+		prefs:      &prefs{name: "gotify", service: prefService, crypter: crypter},
+		recipients: rs,
+		client:     &http.Client{Timeout: gotifyTimeout},
+	}, nil
+}
+
+func (g *Gotify) Send(ctx context.Context, result *es.Result) error {
+	loaded, err := g.prefs.load(ctx, true)
+	if err != nil {
+		return err
+	}
+
+	server := strings.TrimSuffix(g.prefs.get(loaded, "url"), "/")
+	if server == "" {
+		return ErrNoGotifyURL
+	}
+
+	tokens, err := g.tokens(ctx, loaded, result)
+	if err != nil {
+		return err
+	}
+	if len(tokens) == 0 {
+		return ErrNoGotifyToken
+	}
+
+	msg, err := g.Renderer.Render(ctx, "gotify", result)
+	if err != nil {
+		return err
+	}
+
+	message := &GotifyMessage{
+		Title:    msg.Subject,
+		Message:  strings.TrimSpace(msg.Body),
+		Priority: pushPriority(g.prefs, loaded, result.State, gotifyPriorities),
+	}
+	if link := g.Renderer.Data(ctx, result).Links.Detector; link != "" {
+		message.Extras = map[string]any{
+			"client::notification": map[string]any{
+				"click": map[string]string{"url": link},
+			},
+		}
+	}
+
+	var sendErr []error
+	for _, token := range tokens {
+		header := http.Header{}
+		header.Set("X-Gotify-Key", token)
+		if err := postJSON(ctx, g.client, "gotify", server+"/message", header, message); err != nil {
+			sendErr = append(sendErr, err)
+		}
+	}
+
+	return errors.Join(sendErr...)
+}
+
+// Enabled reports if the sender is enabled and has a server
+func (g *Gotify) Enabled() bool {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	loaded, err := g.prefs.load(ctx, false)
+	return err == nil && g.prefs.enabled(loaded) && g.prefs.get(loaded, "url") != ""
+}
+
+// tokens returns the decrypted app tokens of the recipients, the default token is used
+// if the result isn't limited to recipients
+func (g *Gotify) tokens(ctx context.Context, loaded *es.Preferences, result *es.Result) ([]string, error) {
+	var tokens []string
+	seen := make(map[string]bool)
+	add := func(token string) {
+		if token != "" && !seen[token] {
+			seen[token] = true
+			tokens = append(tokens, token)
+		}
+	}
+
+	if len(result.Recipients) == 0 {
+		token, err := g.prefs.decrypt(loaded, "token")
+		if err != nil {
+			return nil, err
+		}
+		add(token)
+	}
+
+	if g.recipients != nil {
+		recipients, err := pushRecipients(ctx, g.recipients, result.Recipients)
+		if err != nil {
+			return nil, err
+		}
+		for _, recipient := range recipients {
+			if recipient.GotifyTokenCrypt == "" {
+				continue
+			}
+			token, err := g.prefs.crypter.Decrypt(recipient.GotifyTokenCrypt)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decrypt gotify token of %s: %w", recipient.Name, err)
+			}
+			add(token)
+		}
+	}
+
+	return tokens, nil
+}
@@ -0,0 +1,127 @@
+package notify
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	es "github.com/alexjoedt/echosight/internal"
+	"github.com/google/uuid"
+)
+
+// GroupBy is the key to group the notifications
+type GroupBy string
+
+const (
+	GroupByHost GroupBy = "host"
+	// GroupByTag groups by the first tag of the detector, detectors without tags are grouped by host
+	GroupByTag GroupBy = "tag"
+)
+
+// group holds the results of a channel which arrived within the window
+type group struct {
+	channel string
+	name    string
+	results []*es.Result
+	timer   *time.Timer
+}
+
+// grouper collects the results by channel, group key and recipients
+// and flushes a group when its window has passed
+type grouper struct {
+	window time.Duration
+	by     GroupBy
+	flush  func(channel string, name string, results []*es.Result)
+
+	mu     sync.Mutex
+	groups map[string]*group
+}
+
+func newGrouper(window time.Duration, by GroupBy, flush func(channel string, name string, results []*es.Result)) *grouper {
+	return &grouper{
+		window: window,
+		by:     by,
+		flush:  flush,
+		groups: make(map[string]*group),
+	}
+}
+
+// groupable reports if the result is a state change, other notifications are sent immediately
+func groupable(result *es.Result) bool {
+	switch result.Notification {
+	case "", es.NotificationProblem, es.NotificationRecovery:
+		return true
+	default:
+		return false
+	}
+}
+
+func (g *grouper) name(result *es.Result) string {
+	if g.by == GroupByTag && len(result.Tags) > 0 {
+		return result.Tags[0]
+	}
+	return result.Host
+}
+
+// key separates the groups by channel, name, recipients and webhooks,
+// only results for the same recipients can be combined
+func (g *grouper) key(channel string, name string, result *es.Result) string {
+	return channel + "|" + name + "|" + joinIDs(result.Recipients) + "|" + joinIDs(result.Webhooks)
+}
+
+func joinIDs(ids []uuid.UUID) string {
+	s := make([]string, len(ids))
+	for i, id := range ids {
+		s[i] = id.String()
+	}
+	slices.Sort(s)
+	return strings.Join(s, ",")
+}
+
+func (g *grouper) add(channel string, result *es.Result) {
+	name := g.name(result)
+	key := g.key(channel, name, result)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if grp, ok := g.groups[key]; ok {
+		grp.results = append(grp.results, result)
+		return
+	}
+
+	grp := &group{
+		channel: channel,
+		name:    name,
+		results: []*es.Result{result},
+	}
+	grp.timer = time.AfterFunc(g.window, func() {
+		g.flushKey(key)
+	})
+	g.groups[key] = grp
+}
+
+func (g *grouper) flushKey(key string) {
+	g.mu.Lock()
+	grp, ok := g.groups[key]
+	delete(g.groups, key)
+	g.mu.Unlock()
+
+	if ok {
+		g.flush(grp.channel, grp.name, grp.results)
+	}
+}
+
+// flushAll flushes all pending groups immediately
+func (g *grouper) flushAll() {
+	g.mu.Lock()
+	groups := g.groups
+	g.groups = make(map[string]*group)
+	g.mu.Unlock()
+
+	for _, grp := range groups {
+		grp.timer.Stop()
+		g.flush(grp.channel, grp.name, grp.results)
+	}
+}
@@ -0,0 +1,112 @@
+package notify
+
+import (
+	"sync"
+	"time"
+
+	es "github.com/alexjoedt/echosight/internal"
+	"github.com/google/uuid"
+)
+
+const (
+	limitWindow time.Duration = time.Minute
+	// maxDroppedListed limits the results listed in the summary
+	maxDroppedListed int = 50
+)
+
+// bucket counts the notifications of a channel within the current window
+type bucket struct {
+	start   time.Time
+	count   int
+	dropped []*es.Result
+	total   int
+}
+
+// limiter caps the notifications per channel and minute.
+// The results above the limit are dropped and summarized after the window.
+type limiter struct {
+	limit   int
+	summary func(channel string, result *es.Result)
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+func newLimiter(limit int, summary func(channel string, result *es.Result)) *limiter {
+	return &limiter{
+		limit:   limit,
+		summary: summary,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// allow reports if the result can be sent, otherwise it's added to the summary
+func (l *limiter) allow(channel string, result *es.Result, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.buckets[channel]
+	if !ok || !now.Before(b.start.Add(limitWindow)) {
+		// the summary of the previous window is sent by its timer
+		if ok && b.total > 0 {
+			return l.drop(channel, b, result)
+		}
+		b = &bucket{start: now}
+		l.buckets[channel] = b
+	}
+
+	if b.count < l.limit {
+		b.count++
+		return true
+	}
+
+	return l.drop(channel, b, result)
+}
+
+func (l *limiter) drop(channel string, b *bucket, result *es.Result) bool {
+	if b.total == 0 {
+		time.AfterFunc(time.Until(b.start.Add(limitWindow)), func() {
+			l.flush(channel, b)
+		})
+	}
+
+	b.total++
+	if len(b.dropped) < maxDroppedListed {
+		b.dropped = append(b.dropped, result)
+	}
+	return false
+}
+
+// flush sends the summary of the dropped results and starts a new window
+func (l *limiter) flush(channel string, b *bucket) {
+	l.mu.Lock()
+	if l.buckets[channel] == b {
+		delete(l.buckets, channel)
+	}
+	dropped, total := b.dropped, b.total
+	l.mu.Unlock()
+
+	summary := es.CombineResults(es.NotificationDropped, channel, dropped)
+	summary.Dropped = total
+	summary.Recipients = droppedRecipients(dropped)
+	l.summary(channel, summary)
+}
+
+// droppedRecipients returns the recipients of all dropped results,
+// nil if one of the results was sent to all recipients
+func droppedRecipients(results []*es.Result) []uuid.UUID {
+	var recipients []uuid.UUID
+	seen := make(map[uuid.UUID]bool)
+	for _, r := range results {
+		if len(r.Recipients) == 0 {
+			return nil
+		}
+		for _, id := range r.Recipients {
+			if !seen[id] {
+				seen[id] = true
+				recipients = append(recipients, id)
+			}
+		}
+	}
+	return recipients
+}
@@ -0,0 +1,273 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	es "github.com/alexjoedt/echosight/internal"
+	"github.com/alexjoedt/echosight/internal/logger"
+	"github.com/google/uuid"
+)
+
+const (
+	matrixTimeout    time.Duration = time.Second * 10
+	matrixAttempts   int           = 3
+	matrixRetryDelay time.Duration = time.Second
+)
+
+var (
+	ErrNoMatrixHomeserver error = errors.New("no matrix homeserver")
+	ErrNoMatrixRoom       error = errors.New("no matrix room")
+	ErrNoMatrixToken      error = errors.New("no matrix access token")
+)
+
+var _ Sender = (*Matrix)(nil)
+
+// MatrixContent is the content of a m.room.message event with a html body
+type MatrixContent struct {
+	MsgType       string `json:"msgtype"`
+	Body          string `json:"body"`
+	Format        string `json:"format,omitempty"`
+	FormattedBody string `json:"formatted_body,omitempty"`
+
+	// NewContent and RelatesTo replace an earlier event
+	NewContent *MatrixContent   `json:"m.new_content,omitempty"`
+	RelatesTo  *MatrixRelatesTo `json:"m.relates_to,omitempty"`
+}
+
+type MatrixRelatesTo struct {
+	RelType string `json:"rel_type"`
+	EventID string `json:"event_id"`
+}
+
+// Matrix posts the notifications to a room with the client-server API.
+// The transaction id is derived from the delivery id, so a retried delivery isn't posted twice.
+// The configuration is read from the preferences:
+//
+//	matrix_enabled             "true" to enable the sender
+//	matrix_homeserver          the url of the homeserver, e.g. https://matrix.example.com
+//	matrix_room_id             the id of the room, e.g. !abc:example.com
+//	matrix_access_token_crypt  the encrypted access token of the bot user
+//	matrix_edit_on_recovery    "true" to replace the problem message on recovery, optional
+type Matrix struct {
+	prefs  *prefs
+	client *http.Client
+	log    *logger.Logger
+
+	// problems holds the event ids of the problem messages by detector,
+	// they are lost on restart, then the recovery is posted as a new message
+	mu       sync.Mutex
+	problems map[string]string
+
+	// Renderer renders the messages, optional
+	Renderer *Renderer
+}
+
+func NewMatrix(prefService es.PreferenceService, crypter es.Crypter) (*Matrix, error) {
+	if prefService == nil {
+		return nil, fmt.Errorf("preference service is nil")
+	}
+
+	return &Matrix{
+		prefs:    &prefs{name: "matrix", service: prefService, crypter: crypter},
+		client:   &http.Client{Timeout: matrixTimeout},
+		log:      logger.New("Matrix"),
+		problems: make(map[string]string),
+	}, nil
+}
+
+func (m *Matrix) Send(ctx context.Context, result *es.Result) error {
+	loaded, err := m.prefs.load(ctx, true)
+	if err != nil {
+		return err
+	}
+
+	homeserver := strings.TrimSuffix(m.prefs.get(loaded, "homeserver"), "/")
+	if homeserver == "" {
+		return ErrNoMatrixHomeserver
+	}
+
+	roomID := m.prefs.get(loaded, "room_id")
+	if roomID == "" {
+		return ErrNoMatrixRoom
+	}
+
+	token, err := m.prefs.decrypt(loaded, "access_token")
+	if err != nil {
+		return err
+	}
+	if token == "" {
+		return ErrNoMatrixToken
+	}
+
+	content, err := m.Content(ctx, result)
+	if err != nil {
+		return err
+	}
+
+	txnID := result.DeliveryID
+	if txnID == "" {
+		txnID = uuid.New().String()
+	}
+
+	// the recovery replaces the problem message, if the event is known
+	if result.Notification == es.NotificationRecovery && m.prefs.get(loaded, "edit_on_recovery") == "true" {
+		if eventID, ok := m.problem(result.DetectorID); ok {
+			content = &MatrixContent{
+				MsgType:       content.MsgType,
+				Body:          "* " + content.Body,
+				Format:        content.Format,
+				FormattedBody: "* " + content.FormattedBody,
+				NewContent:    content,
+				RelatesTo:     &MatrixRelatesTo{RelType: "m.replace", EventID: eventID},
+			}
+		}
+	}
+
+	sendURL := fmt.Sprintf("%s/_matrix/client/v3/rooms/%s/send/m.room.message/%s",
+		homeserver, url.PathEscape(roomID), url.PathEscape("echosight-"+txnID))
+	eventID, err := m.put(ctx, sendURL, token, content)
+	if err != nil {
+		return err
+	}
+
+	switch result.Notification {
+	case es.NotificationProblem:
+		m.setProblem(result.DetectorID, eventID)
+	case es.NotificationRecovery:
+		m.setProblem(result.DetectorID, "")
+	}
+
+	return nil
+}
+
+// Enabled reports if the sender is enabled and has a homeserver and a room
+func (m *Matrix) Enabled() bool {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	loaded, err := m.prefs.load(ctx, false)
+	return err == nil && m.prefs.enabled(loaded) &&
+		m.prefs.get(loaded, "homeserver") != "" && m.prefs.get(loaded, "room_id") != ""
+}
+
+// Content returns the message with the plain and the html body.
+// Without a html template, the html body is the escaped body with the first line in bold.
+func (m *Matrix) Content(ctx context.Context, result *es.Result) (*MatrixContent, error) {
+	msg, err := m.Renderer.Render(ctx, "matrix", result)
+	if err != nil {
+		return nil, err
+	}
+
+	formatted := msg.HTML
+	if formatted == "" {
+		first, rest, _ := strings.Cut(strings.TrimSpace(msg.Body), "\n")
+		formatted = "<strong>" + html.EscapeString(first) + "</strong>"
+		if rest != "" {
+			formatted += "<br>" + strings.ReplaceAll(html.EscapeString(rest), "\n", "<br>")
+		}
+		if link := m.Renderer.Data(ctx, result).Links.Detector; link != "" {
+			formatted += `<br><a href="` + html.EscapeString(link) + `">Open detector</a>`
+		}
+	}
+
+	return &MatrixContent{
+		MsgType:       "m.text",
+		Body:          strings.TrimSpace(msg.Body),
+		Format:        "org.matrix.custom.html",
+		FormattedBody: formatted,
+	}, nil
+}
+
+func (m *Matrix) problem(detectorID string) (string, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	eventID, ok := m.problems[detectorID]
+	return eventID, ok
+}
+
+func (m *Matrix) setProblem(detectorID string, eventID string) {
+	if detectorID == "" {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if eventID == "" {
+		delete(m.problems, detectorID)
+		return
+	}
+	m.problems[detectorID] = eventID
+}
+
+// put sends the event with retries, the homeserver ignores a transaction id it has already seen
+// and returns the event id of the first request
+func (m *Matrix) put(ctx context.Context, sendURL string, token string, content *MatrixContent) (string, error) {
+	data, err := json.Marshal(content)
+	if err != nil {
+		return "", err
+	}
+
+	for attempt := 1; ; attempt++ {
+		eventID, retry, err := m.request(ctx, sendURL, token, data)
+		if err == nil {
+			return eventID, nil
+		}
+
+		if !retry || attempt >= matrixAttempts {
+			return "", err
+		}
+
+		m.log.Debugw("matrix request failed, retrying", logger.Str("error", err.Error()))
+		select {
+		case <-time.After(matrixRetryDelay * time.Duration(attempt)):
+		case <-ctx.Done():
+			return "", err
+		}
+	}
+}
+
+// request sends one request and reports if a failure can be retried
+func (m *Matrix) request(ctx context.Context, sendURL string, token string, data []byte) (string, bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, sendURL, bytes.NewReader(data))
+	if err != nil {
+		return "", false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	res, err := m.client.Do(req)
+	if err != nil {
+		return "", true, err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, 1<<16))
+	if err != nil {
+		return "", true, err
+	}
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		err := fmt.Errorf("matrix responded with status %d: %s", res.StatusCode, body)
+		return "", res.StatusCode >= 500 || res.StatusCode == http.StatusTooManyRequests, err
+	}
+
+	var event struct {
+		EventID string `json:"event_id"`
+	}
+	if err := json.Unmarshal(body, &event); err != nil {
+		return "", false, fmt.Errorf("invalid matrix response: %w", err)
+	}
+
+	return event.EventID, false, nil
+}
@@ -0,0 +1,123 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	es "github.com/alexjoedt/echosight/internal"
+)
+
+// fakeHomeserver returns the same event id for a known transaction id like a homeserver,
+// the first request of a transaction fails with the status failFirst, if set
+type fakeHomeserver struct {
+	mu        sync.Mutex
+	failFirst int
+	txns      map[string]string
+	paths     []string
+	contents  []MatrixContent
+}
+
+func (h *fakeHomeserver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.paths = append(h.paths, r.URL.Path)
+	if h.failFirst != 0 {
+		w.WriteHeader(h.failFirst)
+		h.failFirst = 0
+		return
+	}
+
+	var content MatrixContent
+	json.NewDecoder(r.Body).Decode(&content)
+	h.contents = append(h.contents, content)
+
+	eventID, ok := h.txns[r.URL.Path]
+	if !ok {
+		eventID = fmt.Sprintf("$event%d", len(h.txns)+1)
+		h.txns[r.URL.Path] = eventID
+	}
+	json.NewEncoder(w).Encode(map[string]string{"event_id": eventID})
+}
+
+func newTestMatrix(t *testing.T, h *fakeHomeserver, settings map[string]string) *Matrix {
+	t.Helper()
+
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+
+	settings["homeserver"] = srv.URL
+	settings["room_id"] = "!room:example.com"
+	m, err := NewMatrix(newChannelPreferences(&es.NotificationChannel{
+		Settings: settings,
+		Secrets:  map[string]string{"access_token": "crypt:token"},
+	}, "matrix"), fakeCrypter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestMatrixTransactionIDOfDelivery(t *testing.T) {
+	h := &fakeHomeserver{failFirst: http.StatusBadGateway, txns: make(map[string]string)}
+	m := newTestMatrix(t, h, map[string]string{})
+
+	// the retry of the failed request and the redelivery use the transaction id of the delivery
+	result := &es.Result{Notification: es.NotificationProblem, DetectorID: "d1", DeliveryID: "delivery1", State: es.StateCritical}
+	if err := m.Send(context.Background(), result); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Send(context.Background(), result); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(h.paths) != 3 {
+		t.Fatalf("expected 3 requests, got %v", h.paths)
+	}
+	for _, path := range h.paths {
+		if !strings.HasSuffix(path, "/send/m.room.message/echosight-delivery1") || path != h.paths[0] {
+			t.Fatalf("transaction id isn't stable: %v", h.paths)
+		}
+	}
+	if len(h.txns) != 1 {
+		t.Fatalf("delivery posted %d events", len(h.txns))
+	}
+}
+
+func TestMatrixEditOnRecovery(t *testing.T) {
+	h := &fakeHomeserver{txns: make(map[string]string)}
+	m := newTestMatrix(t, h, map[string]string{"edit_on_recovery": "true"})
+
+	ctx := context.Background()
+	problem := &es.Result{Notification: es.NotificationProblem, DetectorID: "d1", DeliveryID: "problem", State: es.StateCritical}
+	recovery := &es.Result{Notification: es.NotificationRecovery, DetectorID: "d1", DeliveryID: "recovery", State: es.StateOK}
+	if err := m.Send(ctx, problem); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Send(ctx, recovery); err != nil {
+		t.Fatal(err)
+	}
+
+	edit := h.contents[1]
+	if edit.RelatesTo == nil || edit.RelatesTo.RelType != "m.replace" || edit.RelatesTo.EventID != h.txns[h.paths[0]] {
+		t.Fatalf("recovery doesn't replace the problem event %s: %+v", h.txns[h.paths[0]], edit.RelatesTo)
+	}
+	if edit.NewContent == nil || !strings.HasPrefix(edit.Body, "* ") {
+		t.Fatalf("invalid edit content %+v", edit)
+	}
+
+	// the next recovery has no problem event to replace
+	recovery.DeliveryID = "recovery2"
+	if err := m.Send(ctx, recovery); err != nil {
+		t.Fatal(err)
+	}
+	if h.contents[2].RelatesTo != nil {
+		t.Fatalf("second recovery replaces an event: %+v", h.contents[2].RelatesTo)
+	}
+}
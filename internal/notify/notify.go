@@ -5,8 +5,10 @@ import (
 	"errors"
 	"fmt"
 	"sync"
+	"time"
 
 	es "github.com/alexjoedt/echosight/internal"
+	"github.com/alexjoedt/echosight/internal/logger"
 )
 
 type Sender interface {
@@ -14,11 +16,34 @@ type Sender interface {
 	Enabled() bool
 }
 
+// Deliverer is implemented by senders which queue in Send.
+// Deliver sends the result synchronously, so the outcome can be recorded.
+type Deliverer interface {
+	Deliver(ctx context.Context, result *es.Result) error
+}
+
+// Splitter splits a result into one result per target of the sender, e.g. per webhook.
+// The outbox stores every part, so a failed target is retried without resending to the others.
+type Splitter interface {
+	Split(ctx context.Context, result *es.Result) ([]*es.Result, error)
+}
+
+// Outbox persists the notification of a sender for a durable delivery
+type Outbox interface {
+	Enqueue(ctx context.Context, senderID string, result *es.Result) error
+}
+
 type Notifier struct {
 	mu       sync.RWMutex
 	registry map[string]Sender
+	outbox   Outbox
+	grouper  *grouper
+	limiter  *limiter
 }
 
+// flushTimeout limits the sending of a flushed group or summary
+const flushTimeout time.Duration = time.Second * 30
+
 func NewNotifier() *Notifier {
 	return &Notifier{
 		mu:       sync.RWMutex{},
@@ -36,15 +61,51 @@ func (n *Notifier) AddSender(id string, sender Sender) error {
 	return nil
 }
 
+// SetOutbox enables the durable delivery, the results are added to the outbox
+// instead of being sent directly
+func (n *Notifier) SetOutbox(outbox Outbox) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.outbox = outbox
+}
+
+// SetGrouping combines the state changes which arrive within the window
+// by host or tag into a single notification per channel
+func (n *Notifier) SetGrouping(window time.Duration, by GroupBy) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.grouper = newGrouper(window, by, n.flushGroup)
+}
+
+// SetRateLimit caps the notifications per channel and minute,
+// the dropped results are summarized in one notification after the minute.
+// The paging services aren't limited.
+func (n *Notifier) SetRateLimit(perMinute int) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.limiter = newLimiter(perMinute, n.flushSummary)
+}
+
+// Flush sends the pending groups immediately, it's called on shutdown
+func (n *Notifier) Flush() {
+	n.mu.RLock()
+	g := n.grouper
+	n.mu.RUnlock()
+
+	if g != nil {
+		g.flushAll()
+	}
+}
+
 // Send sends the result to all registered sender
 func (n *Notifier) Send(ctx context.Context, result *es.Result) error {
 	var sendErrors []error
 	n.mu.Lock()
 	defer n.mu.Unlock()
 
-	for _, s := range n.registry {
+	for id, s := range n.registry {
 		if s.Enabled() {
-			if err := s.Send(ctx, result); err != nil {
+			if err := n.send(ctx, id, s, result); err != nil {
 				sendErrors = append(sendErrors, err)
 			}
 		}
@@ -55,3 +116,174 @@ func (n *Notifier) Send(ctx context.Context, result *es.Result) error {
 	}
 	return nil
 }
+
+// SendTo sends the result to the registered senders with the given ids,
+// to all senders if no ids are given
+func (n *Notifier) SendTo(ctx context.Context, ids []string, result *es.Result) error {
+	if len(ids) == 0 {
+		return n.Send(ctx, result)
+	}
+
+	var sendErrors []error
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	for _, id := range ids {
+		s, ok := n.registry[id]
+		if !ok {
+			sendErrors = append(sendErrors, fmt.Errorf("no sender with id '%s' registered", id))
+			continue
+		}
+
+		if s.Enabled() {
+			if err := n.send(ctx, id, s, result); err != nil {
+				sendErrors = append(sendErrors, err)
+			}
+		}
+	}
+
+	return errors.Join(sendErrors...)
+}
+
+// send adds the result to a group or passes it to the rate limit
+func (n *Notifier) send(ctx context.Context, id string, s Sender, result *es.Result) error {
+	if n.grouper != nil && groupable(result) {
+		n.grouper.add(id, result)
+		return nil
+	}
+
+	if n.limiter != nil && !pages(s) && !n.limiter.allow(id, result, time.Now()) {
+		return nil
+	}
+
+	return n.dispatch(ctx, id, s, result)
+}
+
+// dispatch adds the result to the outbox or sends it directly without an outbox
+func (n *Notifier) dispatch(ctx context.Context, id string, s Sender, result *es.Result) error {
+	if n.outbox == nil {
+		return s.Send(ctx, result)
+	}
+
+	parts := []*es.Result{result}
+	if splitter, ok := s.(Splitter); ok {
+		var err error
+		parts, err = splitter.Split(ctx, result)
+		if err != nil {
+			return err
+		}
+	}
+
+	var errs []error
+	for _, part := range parts {
+		if err := n.outbox.Enqueue(ctx, id, part); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// flushGroup sends the results of a group, a single result is sent unchanged
+func (n *Notifier) flushGroup(id string, name string, results []*es.Result) {
+	result := results[0]
+	if len(results) > 1 {
+		result = es.CombineResults(es.NotificationGroup, name, results)
+		result.Recipients = results[0].Recipients
+		result.Webhooks = results[0].Webhooks
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), flushTimeout)
+	defer cancel()
+
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	s, ok := n.registry[id]
+	if !ok {
+		return
+	}
+
+	if n.limiter != nil && !pages(s) && !n.limiter.allow(id, result, time.Now()) {
+		return
+	}
+
+	if err := n.dispatch(ctx, id, s, result); err != nil {
+		logger.Errorf("failed to send grouped notification via %s: %v", id, err)
+	}
+}
+
+// flushSummary sends the summary of the dropped results, it's not limited
+func (n *Notifier) flushSummary(id string, summary *es.Result) {
+	ctx, cancel := context.WithTimeout(context.Background(), flushTimeout)
+	defer cancel()
+
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	s, ok := n.registry[id]
+	if !ok {
+		return
+	}
+
+	if err := n.dispatch(ctx, id, s, summary); err != nil {
+		logger.Errorf("failed to send summary of dropped notifications via %s: %v", id, err)
+	}
+}
+
+// Deliver sends the result synchronously with the sender, it's used by the outbox
+func (n *Notifier) Deliver(ctx context.Context, id string, result *es.Result) error {
+	n.mu.RLock()
+	s, ok := n.registry[id]
+	n.mu.RUnlock()
+
+	if !ok {
+		return fmt.Errorf("no sender with id '%s' registered", id)
+	}
+
+	if !s.Enabled() {
+		return fmt.Errorf("sender '%s' is disabled", id)
+	}
+
+	if d, ok := s.(Deliverer); ok {
+		return d.Deliver(ctx, result)
+	}
+	return s.Send(ctx, result)
+}
+
+// Has reports if a sender with the id is registered
+func (n *Notifier) Has(id string) bool {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	_, ok := n.registry[id]
+	return ok
+}
+
+// Sender returns the registered sender with the id
+func (n *Notifier) Sender(id string) (Sender, bool) {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	s, ok := n.registry[id]
+	return s, ok
+}
+
+// ReplaceSenders removes the senders with the ids and adds the senders in one step,
+// so no notification is sent while a sender is missing
+func (n *Notifier) ReplaceSenders(remove []string, add map[string]Sender) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	for _, id := range remove {
+		delete(n.registry, id)
+	}
+
+	var addErr []error
+	for id, sender := range add {
+		if _, ok := n.registry[id]; ok {
+			addErr = append(addErr, fmt.Errorf("sender with id '%s' already registered", id))
+			continue
+		}
+		n.registry[id] = sender
+	}
+
+	return errors.Join(addErr...)
+}
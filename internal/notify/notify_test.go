@@ -0,0 +1,14 @@
+package notify
+
+import (
+	"os"
+	"testing"
+
+	"github.com/alexjoedt/echosight/internal/logger"
+)
+
+func TestMain(m *testing.M) {
+	// only fatal messages, the tests don't write log files
+	logger.Init("fatal", false)
+	os.Exit(m.Run())
+}
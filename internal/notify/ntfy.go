@@ -0,0 +1,216 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	es "github.com/alexjoedt/echosight/internal"
+)
+
+const (
+	ntfyTimeout time.Duration = time.Second * 10
+	// NtfyBaseURL is the default ntfy server
+	NtfyBaseURL = "https://ntfy.sh"
+)
+
+var ErrNoNtfyTopic error = errors.New("no ntfy topic")
+
+var _ Sender = (*Ntfy)(nil)
+
+// ntfyPriorities are the default priorities of the states, 5 is the highest
+var ntfyPriorities = map[es.State]int{
+	es.StateOK:       3,
+	es.StateWarn:     4,
+	es.StateCritical: 5,
+}
+
+// Ntfy publishes the notifications to the ntfy topics of the recipients.
+// The configuration is read from the preferences:
+//
+//	ntfy_enabled          "true" to enable the sender
+//	ntfy_url              the ntfy server, default https://ntfy.sh
+//	ntfy_topic            the topic for notifications without recipients, optional
+//	ntfy_token_crypt      the encrypted access token for the server, optional
+//	ntfy_tags             additional comma separated tags, optional
+//	ntfy_priority_<state> overrides the priority of the state (ok, warn, critical), optional
+type Ntfy struct {
+	prefs      *prefs
+	recipients es.RecipientService
+	client     *http.Client
+
+	// Renderer renders the title and the message, optional
+	Renderer *Renderer
+}
+
+func NewNtfy(prefService es.PreferenceService, rs es.RecipientService, crypter es.Crypter) (*Ntfy, error) {
+	if prefService == nil {
+		return nil, fmt.Errorf("preference service is nil")
+	}
+
+	return &Ntfy{
+		prefs:      &prefs{name: "ntfy", service: prefService, crypter: crypter},
+		recipients: rs,
+		client:     &http.Client{Timeout: ntfyTimeout},
+	}, nil
+}
+
+func (n *Ntfy) Send(ctx context.Context, result *es.Result) error {
+	loaded, err := n.prefs.load(ctx, true)
+	if err != nil {
+		return err
+	}
+
+	token, err := n.prefs.decrypt(loaded, "token")
+	if err != nil {
+		return err
+	}
+
+	topics, err := n.topics(ctx, loaded, result)
+	if err != nil {
+		return err
+	}
+	if len(topics) == 0 {
+		return ErrNoNtfyTopic
+	}
+
+	msg, err := n.Renderer.Render(ctx, "ntfy", result)
+	if err != nil {
+		return err
+	}
+	links := n.Renderer.Data(ctx, result).Links
+
+	header := http.Header{}
+	header.Set("Title", mime.QEncoding.Encode("utf-8", msg.Subject))
+	header.Set("Priority", strconv.Itoa(pushPriority(n.prefs, loaded, result.State, ntfyPriorities)))
+	header.Set("Tags", strings.Join(n.tags(loaded, result), ","))
+	if links.Detector != "" {
+		header.Set("Click", links.Detector)
+	}
+	if links.Incident != "" {
+		header.Set("Actions", "view, Open incident, "+links.Incident)
+	}
+
+	server := n.server(loaded)
+	var sendErr []error
+	for _, topic := range topics {
+		h := header.Clone()
+		// the token is only sent to the configured server
+		if token != "" && strings.HasPrefix(topic, server+"/") {
+			h.Set("Authorization", "Bearer "+token)
+		}
+
+		if err := n.publish(ctx, topic, h, strings.TrimSpace(msg.Body)); err != nil {
+			sendErr = append(sendErr, fmt.Errorf("ntfy topic %s: %w", topic, err))
+		}
+	}
+
+	return errors.Join(sendErr...)
+}
+
+// Enabled reports if the sender is enabled
+func (n *Ntfy) Enabled() bool {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	loaded, err := n.prefs.load(ctx, false)
+	return err == nil && n.prefs.enabled(loaded)
+}
+
+func (n *Ntfy) server(loaded *es.Preferences) string {
+	server := n.prefs.get(loaded, "url")
+	if server == "" {
+		server = NtfyBaseURL
+	}
+	return strings.TrimSuffix(server, "/")
+}
+
+// topics returns the topic urls of the recipients, the default topic is used
+// if the result isn't limited to recipients
+func (n *Ntfy) topics(ctx context.Context, loaded *es.Preferences, result *es.Result) ([]string, error) {
+	server := n.server(loaded)
+	topicURL := func(topic string) string {
+		if strings.Contains(topic, "://") {
+			return strings.TrimSuffix(topic, "/")
+		}
+		return server + "/" + topic
+	}
+
+	var topics []string
+	seen := make(map[string]bool)
+	add := func(topic string) {
+		if topic != "" && !seen[topicURL(topic)] {
+			seen[topicURL(topic)] = true
+			topics = append(topics, topicURL(topic))
+		}
+	}
+
+	if len(result.Recipients) == 0 {
+		add(n.prefs.get(loaded, "topic"))
+	}
+
+	if n.recipients != nil {
+		recipients, err := pushRecipients(ctx, n.recipients, result.Recipients)
+		if err != nil {
+			return nil, err
+		}
+		for _, recipient := range recipients {
+			add(recipient.NtfyTopic)
+		}
+	}
+
+	return topics, nil
+}
+
+// tags returns the emoji tag of the state, the tags of the detector and the configured tags
+func (n *Ntfy) tags(loaded *es.Preferences, result *es.Result) []string {
+	var tags []string
+	switch result.State {
+	case es.StateOK:
+		tags = append(tags, "white_check_mark")
+	case es.StateWarn:
+		tags = append(tags, "warning")
+	case es.StateCritical:
+		tags = append(tags, "rotating_light")
+	}
+
+	tags = append(tags, result.Tags...)
+	for _, tag := range strings.Split(n.prefs.get(loaded, "tags"), ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+
+	return tags
+}
+
+func (n *Ntfy) publish(ctx context.Context, topicURL string, header http.Header, message string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, topicURL, strings.NewReader(message))
+	if err != nil {
+		return err
+	}
+	req.Header = header
+
+	res, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, 1<<16))
+	if err != nil {
+		return err
+	}
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("ntfy responded with status %d: %s", res.StatusCode, body)
+	}
+
+	return nil
+}
@@ -0,0 +1,176 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	es "github.com/alexjoedt/echosight/internal"
+)
+
+const (
+	opsgenieTimeout time.Duration = time.Second * 10
+	// OpsgenieBaseURL is the default base url of the Alert API, EU accounts use https://api.eu.opsgenie.com
+	OpsgenieBaseURL = "https://api.opsgenie.com"
+	// opsgenieMessageLength is the maximum length of the alert message
+	opsgenieMessageLength = 130
+)
+
+var ErrNoOpsgenieAPIKey error = errors.New("no opsgenie api key")
+
+var _ Sender = (*Opsgenie)(nil)
+
+// OpsgenieAlert is the body to create an alert with the Alert API
+type OpsgenieAlert struct {
+	Message     string            `json:"message"`
+	Alias       string            `json:"alias"`
+	Description string            `json:"description,omitempty"`
+	Priority    string            `json:"priority"`
+	Source      string            `json:"source"`
+	Entity      string            `json:"entity,omitempty"`
+	Tags        []string          `json:"tags,omitempty"`
+	Details     map[string]string `json:"details,omitempty"`
+}
+
+// OpsgenieAction is the body to acknowledge or close an alert
+type OpsgenieAction struct {
+	Source string `json:"source"`
+	Note   string `json:"note,omitempty"`
+}
+
+// Opsgenie creates, acknowledges and closes the alerts of the detectors with the Alert API.
+// The alias is derived from the detector id. The configuration is read from the preferences:
+//
+//	opsgenie_enabled          "true" to enable the sender
+//	opsgenie_api_key_crypt    the encrypted api key of the integration
+//	opsgenie_base_url         overrides the base url, e.g. for EU accounts or a mock, optional
+type Opsgenie struct {
+	prefs  *prefs
+	client *http.Client
+
+	// Renderer provides the links to the detector and the incident, optional
+	Renderer *Renderer
+}
+
+func NewOpsgenie(prefService es.PreferenceService, crypter es.Crypter) (*Opsgenie, error) {
+	if prefService == nil {
+		return nil, fmt.Errorf("preference service is nil")
+	}
+
+	return &Opsgenie{
+		prefs:  &prefs{name: "opsgenie", service: prefService, crypter: crypter},
+		client: &http.Client{Timeout: opsgenieTimeout},
+	}, nil
+}
+
+func (o *Opsgenie) Send(ctx context.Context, result *es.Result) error {
+	loaded, err := o.prefs.load(ctx, true)
+	if err != nil {
+		return err
+	}
+
+	apiKey, err := o.prefs.decrypt(loaded, "api_key")
+	if err != nil {
+		return err
+	}
+	if apiKey == "" {
+		return ErrNoOpsgenieAPIKey
+	}
+
+	baseURL := o.prefs.get(loaded, "base_url")
+	if baseURL == "" {
+		baseURL = OpsgenieBaseURL
+	}
+	baseURL = strings.TrimSuffix(baseURL, "/") + "/v2/alerts"
+
+	header := http.Header{}
+	header.Set("Authorization", "GenieKey "+apiKey)
+
+	var sendErr []error
+	for _, r := range pagingResults(result) {
+		action, ok := pagingActionOf(r)
+		if !ok {
+			continue
+		}
+
+		var err error
+		switch action {
+		case pagingTrigger:
+			err = postJSON(ctx, o.client, "opsgenie", baseURL, header, o.alert(ctx, r))
+		case pagingAcknowledge:
+			err = postJSON(ctx, o.client, "opsgenie", alertActionURL(baseURL, r, "acknowledge"), header, &OpsgenieAction{
+				Source: "EchoSight",
+				Note:   r.Message,
+			})
+		case pagingResolve:
+			err = postJSON(ctx, o.client, "opsgenie", alertActionURL(baseURL, r, "close"), header, &OpsgenieAction{
+				Source: "EchoSight",
+				Note:   r.Message,
+			})
+		}
+		if err != nil {
+			sendErr = append(sendErr, err)
+		}
+	}
+
+	return errors.Join(sendErr...)
+}
+
+// Enabled reports if the sender is enabled and has an api key
+func (o *Opsgenie) Enabled() bool {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	loaded, err := o.prefs.load(ctx, false)
+	return err == nil && o.prefs.enabled(loaded) && loaded.Has("opsgenie_api_key_crypt")
+}
+
+// alertActionURL returns the url of an action on the alert of the detector, identified by the alias
+func alertActionURL(baseURL string, result *es.Result, action string) string {
+	return baseURL + "/" + url.PathEscape(DedupKey(result)) + "/" + action + "?identifierType=alias"
+}
+
+// alert returns the alert of the problem, an open alert with the same alias is deduplicated by Opsgenie
+func (o *Opsgenie) alert(ctx context.Context, result *es.Result) *OpsgenieAlert {
+	priority := "P1"
+	if result.State == es.StateWarn {
+		priority = "P3"
+	}
+
+	message := fmt.Sprintf("%s - %s: %s", result.Host, result.Detector, result.State)
+	if len(message) > opsgenieMessageLength {
+		message = message[:opsgenieMessageLength]
+	}
+
+	alert := &OpsgenieAlert{
+		Message:     message,
+		Alias:       DedupKey(result),
+		Description: result.Message,
+		Priority:    priority,
+		Source:      "EchoSight",
+		Entity:      result.Host,
+		Tags:        result.Tags,
+		Details: map[string]string{
+			"state":    string(result.State),
+			"detector": result.Detector,
+		},
+	}
+
+	if result.IncidentID != "" {
+		alert.Details["incidentId"] = result.IncidentID
+	}
+
+	links := o.Renderer.Data(ctx, result).Links
+	if links.Detector != "" {
+		alert.Details["detectorUrl"] = links.Detector
+	}
+	if links.Incident != "" {
+		alert.Details["incidentUrl"] = links.Incident
+	}
+
+	return alert
+}
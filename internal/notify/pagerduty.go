@@ -0,0 +1,167 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	es "github.com/alexjoedt/echosight/internal"
+)
+
+const (
+	pagerDutyTimeout time.Duration = time.Second * 10
+	// PagerDutyBaseURL is the default base url of the Events API v2
+	PagerDutyBaseURL = "https://events.pagerduty.com"
+)
+
+var ErrNoPagerDutyRoutingKey error = errors.New("no pagerduty routing key")
+
+var _ Sender = (*PagerDuty)(nil)
+
+// PagerDutyEvent is an event of the PagerDuty Events API v2
+type PagerDutyEvent struct {
+	RoutingKey  string            `json:"routing_key"`
+	EventAction string            `json:"event_action"`
+	DedupKey    string            `json:"dedup_key"`
+	Payload     *PagerDutyPayload `json:"payload,omitempty"`
+	Links       []PagerDutyLink   `json:"links,omitempty"`
+}
+
+type PagerDutyPayload struct {
+	Summary       string         `json:"summary"`
+	Source        string         `json:"source"`
+	Severity      string         `json:"severity"`
+	Component     string         `json:"component,omitempty"`
+	Class         string         `json:"class,omitempty"`
+	CustomDetails map[string]any `json:"custom_details,omitempty"`
+}
+
+type PagerDutyLink struct {
+	Href string `json:"href"`
+	Text string `json:"text"`
+}
+
+// PagerDuty triggers, acknowledges and resolves the alerts of the detectors with the Events API v2.
+// The dedup key is derived from the detector id. The configuration is read from the preferences:
+//
+//	pagerduty_enabled             "true" to enable the sender
+//	pagerduty_routing_key_crypt   the encrypted integration key of the service
+//	pagerduty_base_url            overrides the base url, e.g. for a mock, optional
+type PagerDuty struct {
+	prefs  *prefs
+	client *http.Client
+
+	// Renderer provides the links to the detector and the incident, optional
+	Renderer *Renderer
+}
+
+func NewPagerDuty(prefService es.PreferenceService, crypter es.Crypter) (*PagerDuty, error) {
+	if prefService == nil {
+		return nil, fmt.Errorf("preference service is nil")
+	}
+
+	return &PagerDuty{
+		prefs:  &prefs{name: "pagerduty", service: prefService, crypter: crypter},
+		client: &http.Client{Timeout: pagerDutyTimeout},
+	}, nil
+}
+
+func (pd *PagerDuty) Send(ctx context.Context, result *es.Result) error {
+	loaded, err := pd.prefs.load(ctx, true)
+	if err != nil {
+		return err
+	}
+
+	routingKey, err := pd.prefs.decrypt(loaded, "routing_key")
+	if err != nil {
+		return err
+	}
+	if routingKey == "" {
+		return ErrNoPagerDutyRoutingKey
+	}
+
+	baseURL := pd.prefs.get(loaded, "base_url")
+	if baseURL == "" {
+		baseURL = PagerDutyBaseURL
+	}
+	url := strings.TrimSuffix(baseURL, "/") + "/v2/enqueue"
+
+	var sendErr []error
+	for _, r := range pagingResults(result) {
+		action, ok := pagingActionOf(r)
+		if !ok {
+			continue
+		}
+
+		event := pd.event(ctx, routingKey, action, r)
+		if err := postJSON(ctx, pd.client, "pagerduty", url, nil, event); err != nil {
+			sendErr = append(sendErr, err)
+		}
+	}
+
+	return errors.Join(sendErr...)
+}
+
+// Enabled reports if the sender is enabled and has a routing key
+func (pd *PagerDuty) Enabled() bool {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	loaded, err := pd.prefs.load(ctx, false)
+	return err == nil && pd.prefs.enabled(loaded) && loaded.Has("pagerduty_routing_key_crypt")
+}
+
+// event returns the event of the action, the payload is only sent with a trigger
+func (pd *PagerDuty) event(ctx context.Context, routingKey string, action pagingAction, result *es.Result) *PagerDutyEvent {
+	event := &PagerDutyEvent{
+		RoutingKey:  routingKey,
+		EventAction: string(action),
+		DedupKey:    DedupKey(result),
+	}
+
+	if action != pagingTrigger {
+		return event
+	}
+
+	severity := "critical"
+	if result.State == es.StateWarn {
+		severity = "warning"
+	}
+
+	summary := fmt.Sprintf("%s - %s: %s", result.Host, result.Detector, result.State)
+	if result.Message != "" {
+		summary += " " + result.Message
+	}
+	// PagerDuty accepts summaries up to 1024 characters
+	if len(summary) > 1024 {
+		summary = summary[:1024]
+	}
+
+	event.Payload = &PagerDutyPayload{
+		Summary:   summary,
+		Source:    result.Host,
+		Severity:  severity,
+		Component: result.Detector,
+		Class:     string(result.Notification),
+		CustomDetails: map[string]any{
+			"state":      result.State,
+			"message":    result.Message,
+			"attempt":    result.Attempt,
+			"flapping":   result.Flapping,
+			"incidentId": result.IncidentID,
+		},
+	}
+
+	links := pd.Renderer.Data(ctx, result).Links
+	if links.Detector != "" {
+		event.Links = append(event.Links, PagerDutyLink{Href: links.Detector, Text: "Detector"})
+	}
+	if links.Incident != "" {
+		event.Links = append(event.Links, PagerDutyLink{Href: links.Incident, Text: "Incident"})
+	}
+
+	return event
+}
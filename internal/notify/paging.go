@@ -0,0 +1,61 @@
+package notify
+
+import (
+	es "github.com/alexjoedt/echosight/internal"
+)
+
+// pagingAction is the action of a paging service for a notification
+type pagingAction string
+
+const (
+	pagingTrigger     pagingAction = "trigger"
+	pagingAcknowledge pagingAction = "acknowledge"
+	pagingResolve     pagingAction = "resolve"
+)
+
+// DedupKey returns the stable key of the detector for the alerts of the paging services,
+// so a flapping detector updates the open alert instead of creating a new one
+func DedupKey(result *es.Result) string {
+	if result.DetectorID != "" {
+		return "echosight-" + result.DetectorID
+	}
+	return "echosight-" + result.Host + "/" + result.Detector
+}
+
+// pagingResults returns the results of the notification, a group notification is split up,
+// so every detector gets its own alert
+func pagingResults(result *es.Result) []*es.Result {
+	if result.Notification == es.NotificationGroup {
+		return result.Group
+	}
+	return []*es.Result{result}
+}
+
+// pagingActionOf returns the action for the notification, false if it doesn't page,
+// e.g. flapping, SLO or digest notifications
+func pagingActionOf(result *es.Result) (pagingAction, bool) {
+	switch result.Notification {
+	case "", es.NotificationProblem, es.NotificationReminder, es.NotificationEscalation:
+		if result.State == es.StateOK {
+			return pagingResolve, true
+		}
+		return pagingTrigger, true
+	case es.NotificationRecovery, es.NotificationIncidentResolved:
+		return pagingResolve, true
+	case es.NotificationIncidentAcknowledged:
+		return pagingAcknowledge, true
+	default:
+		return "", false
+	}
+}
+
+// pages reports if the sender alerts with a paging service. These senders aren't rate limited,
+// a dropped problem would never page and the services deduplicate the alerts by themselves.
+func pages(s Sender) bool {
+	switch s.(type) {
+	case *PagerDuty, *Opsgenie:
+		return true
+	default:
+		return false
+	}
+}
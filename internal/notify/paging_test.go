@@ -0,0 +1,152 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	es "github.com/alexjoedt/echosight/internal"
+)
+
+// pagingRequest is a request received by the fake paging service
+type pagingRequest struct {
+	path   string
+	query  string
+	header http.Header
+	body   map[string]any
+}
+
+func pagingServer(t *testing.T) (*httptest.Server, *[]pagingRequest) {
+	t.Helper()
+
+	var requests []pagingRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := pagingRequest{path: r.URL.Path, query: r.URL.RawQuery, header: r.Header}
+		json.NewDecoder(r.Body).Decode(&req.body)
+		requests = append(requests, req)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &requests
+}
+
+func pagingPrefs(prefix string, baseURL string, secret string, value string) es.PreferenceService {
+	return newChannelPreferences(&es.NotificationChannel{
+		Settings: map[string]string{"base_url": baseURL},
+		Secrets:  map[string]string{secret: "crypt:" + value},
+	}, prefix)
+}
+
+func TestPagerDutyTriggerAndResolve(t *testing.T) {
+	srv, requests := pagingServer(t)
+	pd, err := NewPagerDuty(pagingPrefs("pagerduty", srv.URL, "routing_key", "key"), fakeCrypter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	problem := &es.Result{Notification: es.NotificationProblem, DetectorID: "d1", Host: "web", Detector: "http", State: es.StateCritical}
+	recovery := &es.Result{Notification: es.NotificationRecovery, DetectorID: "d1", Host: "web", Detector: "http", State: es.StateOK}
+	if err := pd.Send(ctx, problem); err != nil {
+		t.Fatal(err)
+	}
+	if err := pd.Send(ctx, recovery); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(*requests) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(*requests))
+	}
+
+	trigger, resolve := (*requests)[0], (*requests)[1]
+	if trigger.path != "/v2/enqueue" || trigger.body["event_action"] != "trigger" || trigger.body["routing_key"] != "key" || trigger.body["payload"] == nil {
+		t.Fatalf("invalid trigger %s %v", trigger.path, trigger.body)
+	}
+	if resolve.body["event_action"] != "resolve" || resolve.body["payload"] != nil {
+		t.Fatalf("invalid resolve %v", resolve.body)
+	}
+
+	// the resolve must close the alert of the trigger
+	if trigger.body["dedup_key"] != "echosight-d1" || resolve.body["dedup_key"] != trigger.body["dedup_key"] {
+		t.Fatalf("dedup keys %v and %v", trigger.body["dedup_key"], resolve.body["dedup_key"])
+	}
+}
+
+func TestOpsgenieCreateAndClose(t *testing.T) {
+	srv, requests := pagingServer(t)
+	o, err := NewOpsgenie(pagingPrefs("opsgenie", srv.URL, "api_key", "key"), fakeCrypter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	problem := &es.Result{Notification: es.NotificationProblem, DetectorID: "d1", Host: "web", Detector: "http", State: es.StateCritical}
+	recovery := &es.Result{Notification: es.NotificationRecovery, DetectorID: "d1", Host: "web", Detector: "http", State: es.StateOK}
+	if err := o.Send(ctx, problem); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Send(ctx, recovery); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(*requests) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(*requests))
+	}
+
+	create, close := (*requests)[0], (*requests)[1]
+	if create.path != "/v2/alerts" || create.body["alias"] != "echosight-d1" || create.body["priority"] != "P1" {
+		t.Fatalf("invalid create %s %v", create.path, create.body)
+	}
+	if create.header.Get("Authorization") != "GenieKey key" {
+		t.Fatalf("invalid authorization %q", create.header.Get("Authorization"))
+	}
+
+	// the close addresses the alert of the create by its alias
+	if close.path != "/v2/alerts/echosight-d1/close" || close.query != "identifierType=alias" {
+		t.Fatalf("invalid close %s?%s", close.path, close.query)
+	}
+}
+
+func TestPagingGroupTriggersEveryDetector(t *testing.T) {
+	srv, requests := pagingServer(t)
+	pd, err := NewPagerDuty(pagingPrefs("pagerduty", srv.URL, "routing_key", "key"), fakeCrypter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	group := es.CombineResults(es.NotificationGroup, "web", []*es.Result{
+		{Notification: es.NotificationProblem, DetectorID: "d1", State: es.StateCritical},
+		{Notification: es.NotificationProblem, DetectorID: "d2", State: es.StateWarn},
+	})
+	if err := pd.Send(context.Background(), group); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(*requests) != 2 || (*requests)[0].body["dedup_key"] != "echosight-d1" || (*requests)[1].body["dedup_key"] != "echosight-d2" {
+		t.Fatalf("expected a trigger per detector, got %v", *requests)
+	}
+}
+
+func TestPagingIsNotRateLimited(t *testing.T) {
+	srv, requests := pagingServer(t)
+	pd, err := NewPagerDuty(pagingPrefs("pagerduty", srv.URL, "routing_key", "key"), fakeCrypter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n := NewNotifier()
+	n.SetRateLimit(1)
+	n.AddSender("pagerduty", pd)
+
+	for _, id := range []string{"d1", "d2", "d3"} {
+		if err := n.Send(context.Background(), &es.Result{Notification: es.NotificationProblem, DetectorID: id, State: es.StateCritical}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(*requests) != 3 {
+		t.Fatalf("expected a trigger per problem, got %d", len(*requests))
+	}
+}
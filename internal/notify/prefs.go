@@ -0,0 +1,69 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	es "github.com/alexjoedt/echosight/internal"
+	"github.com/alexjoedt/echosight/internal/filter"
+)
+
+const prefsCache time.Duration = time.Second * 30
+
+// prefs reads the preferences of a sender with its name as prefix, e.g. slack_enabled.
+// The preferences are cached, so Enabled doesn't query the database for every notification.
+type prefs struct {
+	name    string
+	service es.PreferenceService
+	crypter es.Crypter
+
+	mu       sync.Mutex
+	cached   *es.Preferences
+	loadedAt time.Time
+}
+
+// load returns the cached preferences or reads them, if reload is set or the cache is expired
+func (p *prefs) load(ctx context.Context, reload bool) (*es.Preferences, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !reload && p.cached != nil && time.Since(p.loadedAt) < prefsCache {
+		return p.cached, nil
+	}
+
+	f := filter.NewDefaultPreferenceFilter()
+	f.Name = p.name
+	loaded, err := p.service.List(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+
+	p.cached = loaded
+	p.loadedAt = time.Now()
+	return loaded, nil
+}
+
+// get returns the value of <name>_<key>
+func (p *prefs) get(loaded *es.Preferences, key string) string {
+	return loaded.Get(p.name + "_" + key)
+}
+
+func (p *prefs) enabled(loaded *es.Preferences) bool {
+	return p.get(loaded, "enabled") == "true"
+}
+
+// decrypt returns the decrypted value of <name>_<key>_crypt, empty if not set
+func (p *prefs) decrypt(loaded *es.Preferences, key string) (string, error) {
+	key = p.name + "_" + key + "_crypt"
+	if !loaded.Has(key) {
+		return "", nil
+	}
+
+	value, err := p.crypter.Decrypt(loaded.Get(key))
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt %s: %w", key, err)
+	}
+	return value, nil
+}
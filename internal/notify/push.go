@@ -0,0 +1,44 @@
+package notify
+
+import (
+	"context"
+	"strconv"
+
+	es "github.com/alexjoedt/echosight/internal"
+	"github.com/alexjoedt/echosight/internal/filter"
+	"github.com/google/uuid"
+)
+
+// pushRecipients returns the active recipients of the result, all active recipients if the result has none
+func pushRecipients(ctx context.Context, rs es.RecipientService, ids []uuid.UUID) ([]*es.Recipient, error) {
+	f := filter.NewDefaultRecipientFilter()
+	active := true
+	f.Active = &active
+	f.IDs = ids
+	return rs.List(ctx, f)
+}
+
+// pushPriority returns the priority of the state, it can be overridden
+// with the preferences <name>_priority_ok, <name>_priority_warn and <name>_priority_critical
+func pushPriority(p *prefs, loaded *es.Preferences, state es.State, defaults map[es.State]int) int {
+	var key string
+	switch state {
+	case es.StateOK:
+		key = "priority_ok"
+	case es.StateWarn:
+		key = "priority_warn"
+	case es.StateCritical:
+		key = "priority_critical"
+	}
+
+	if key != "" {
+		if priority, err := strconv.Atoi(p.get(loaded, key)); err == nil {
+			return priority
+		}
+	}
+
+	if priority, ok := defaults[state]; ok {
+		return priority
+	}
+	return defaults[es.StateOK]
+}
@@ -0,0 +1,217 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	es "github.com/alexjoedt/echosight/internal"
+)
+
+const slackTimeout time.Duration = time.Second * 10
+
+var ErrNoSlackWebhookURL error = errors.New("no slack webhook url")
+
+var _ Sender = (*Slack)(nil)
+
+// SlackAttachment is a legacy message attachment, it's supported by Slack and Mattermost
+type SlackAttachment struct {
+	Fallback  string       `json:"fallback"`
+	Color     string       `json:"color"`
+	Title     string       `json:"title"`
+	TitleLink string       `json:"title_link,omitempty"`
+	Text      string       `json:"text,omitempty"`
+	Fields    []SlackField `json:"fields,omitempty"`
+	Footer    string       `json:"footer,omitempty"`
+	Timestamp int64        `json:"ts,omitempty"`
+}
+
+type SlackField struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+	Short bool   `json:"short"`
+}
+
+// SlackPayload is the body of an incoming webhook
+type SlackPayload struct {
+	Text        string             `json:"text,omitempty"`
+	Channel     string             `json:"channel,omitempty"`
+	Username    string             `json:"username,omitempty"`
+	Attachments []*SlackAttachment `json:"attachments"`
+}
+
+// Slack sends the notifications to a Slack compatible incoming webhook, e.g. Slack or Mattermost.
+// The configuration is read from the preferences with the name of the sender as prefix:
+//
+//	<name>_enabled             "true" to enable the sender
+//	<name>_webhook_url_crypt   the encrypted webhook url
+//	<name>_channel             overrides the channel of the webhook, optional
+//	<name>_username            overrides the username of the webhook, optional
+type Slack struct {
+	name   string
+	prefs  *prefs
+	client *http.Client
+
+	// Renderer renders the title and the text of grouped notifications, optional
+	Renderer *Renderer
+}
+
+// NewSlack returns a sender for the incoming webhook configured with the preferences of name,
+// e.g. slack or mattermost. The name is also the channel of the templates.
+func NewSlack(name string, prefService es.PreferenceService, crypter es.Crypter) (*Slack, error) {
+	if prefService == nil {
+		return nil, fmt.Errorf("preference service is nil")
+	}
+
+	return &Slack{
+		name:   name,
+		prefs:  &prefs{name: name, service: prefService, crypter: crypter},
+		client: &http.Client{Timeout: slackTimeout},
+	}, nil
+}
+
+func (s *Slack) Send(ctx context.Context, result *es.Result) error {
+	loaded, err := s.prefs.load(ctx, true)
+	if err != nil {
+		return err
+	}
+
+	webhookURL, err := s.prefs.decrypt(loaded, "webhook_url")
+	if err != nil {
+		return err
+	}
+	if webhookURL == "" {
+		return ErrNoSlackWebhookURL
+	}
+
+	attachment, err := s.Attachment(ctx, result)
+	if err != nil {
+		return err
+	}
+
+	return postJSON(ctx, s.client, s.name, webhookURL, nil, &SlackPayload{
+		Channel:     s.prefs.get(loaded, "channel"),
+		Username:    s.prefs.get(loaded, "username"),
+		Attachments: []*SlackAttachment{attachment},
+	})
+}
+
+// Enabled reports if the sender is enabled and has a webhook url
+func (s *Slack) Enabled() bool {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	loaded, err := s.prefs.load(ctx, false)
+	return err == nil && s.prefs.enabled(loaded) && loaded.Has(s.name+"_webhook_url_crypt")
+}
+
+// Attachment returns the color coded attachment of the result
+func (s *Slack) Attachment(ctx context.Context, result *es.Result) (*SlackAttachment, error) {
+	msg, err := s.Renderer.Render(ctx, s.name, result)
+	if err != nil {
+		return nil, err
+	}
+	data := s.Renderer.Data(ctx, result)
+
+	attachment := &SlackAttachment{
+		Fallback:  msg.Subject,
+		Color:     slackColor(result.State),
+		Title:     msg.Subject,
+		TitleLink: data.Links.Detector,
+		Footer:    "EchoSight",
+		Timestamp: time.Now().Unix(),
+	}
+
+	// grouped notifications list the detectors in the rendered body
+	if len(result.Group) > 0 {
+		attachment.Text = msg.Body
+		return attachment, nil
+	}
+
+	attachment.Fields = []SlackField{
+		{Title: "Host", Value: result.Host, Short: true},
+		{Title: "Detector", Value: result.Detector, Short: true},
+	}
+
+	if result.Notification == es.NotificationRecovery && result.PreviousState != "" {
+		attachment.Text = "Recovered from " + string(result.PreviousState)
+		if elapsed := result.ElapsedText(); elapsed != "" {
+			attachment.Text += " after " + elapsed
+		}
+		if data.Incident != nil && data.Incident.Message != "" {
+			attachment.Text += ": " + data.Incident.Message
+		}
+	}
+
+	if result.Message != "" {
+		attachment.Fields = append(attachment.Fields, SlackField{Title: "Message", Value: result.Message})
+	}
+
+	if elapsed := result.ElapsedText(); elapsed != "" {
+		attachment.Fields = append(attachment.Fields, SlackField{Title: "Duration", Value: elapsed, Short: true})
+	}
+
+	if data.Links.Incident != "" {
+		attachment.Fields = append(attachment.Fields, SlackField{
+			Title: "Incident",
+			Value: fmt.Sprintf("<%s|Open incident>", data.Links.Incident),
+			Short: true,
+		})
+	}
+
+	return attachment, nil
+}
+
+// postJSON posts the payload with the additional headers, which can be nil,
+// and returns an error with the response body on a failure status
+func postJSON(ctx context.Context, client *http.Client, name string, url string, header http.Header, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, values := range header {
+		req.Header[key] = values
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, 1<<16))
+	if err != nil {
+		return err
+	}
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("%s responded with status %d: %s", name, res.StatusCode, body)
+	}
+
+	return nil
+}
+
+// slackColor returns the attachment color of the state
+func slackColor(state es.State) string {
+	switch state {
+	case es.StateOK:
+		return "#2eb886"
+	case es.StateWarn:
+		return "#daa038"
+	case es.StateCritical:
+		return "#a30200"
+	default:
+		return "#808080"
+	}
+}
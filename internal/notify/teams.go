@@ -0,0 +1,215 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	es "github.com/alexjoedt/echosight/internal"
+)
+
+const teamsTimeout time.Duration = time.Second * 10
+
+var ErrNoTeamsWebhookURL error = errors.New("no teams webhook url")
+
+var _ Sender = (*Teams)(nil)
+
+// TeamsMessage is the body of a Teams workflow or incoming webhook with an Adaptive Card
+type TeamsMessage struct {
+	Type        string             `json:"type"`
+	Attachments []*TeamsAttachment `json:"attachments"`
+}
+
+type TeamsAttachment struct {
+	ContentType string        `json:"contentType"`
+	ContentURL  *string       `json:"contentUrl"`
+	Content     *AdaptiveCard `json:"content"`
+}
+
+type AdaptiveCard struct {
+	Schema  string            `json:"$schema"`
+	Type    string            `json:"type"`
+	Version string            `json:"version"`
+	Body    []any             `json:"body"`
+	Actions []*CardAction     `json:"actions,omitempty"`
+	MSTeams map[string]string `json:"msteams,omitempty"`
+}
+
+type CardContainer struct {
+	Type  string `json:"type"`
+	Style string `json:"style,omitempty"`
+	Bleed bool   `json:"bleed,omitempty"`
+	Items []any  `json:"items"`
+}
+
+type CardTextBlock struct {
+	Type   string `json:"type"`
+	Text   string `json:"text"`
+	Size   string `json:"size,omitempty"`
+	Weight string `json:"weight,omitempty"`
+	Color  string `json:"color,omitempty"`
+	Wrap   bool   `json:"wrap"`
+}
+
+type CardFactSet struct {
+	Type  string      `json:"type"`
+	Facts []*CardFact `json:"facts"`
+}
+
+type CardFact struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+}
+
+type CardAction struct {
+	Type  string `json:"type"`
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+// Teams sends the notifications as Adaptive Cards to a Microsoft Teams workflow or incoming webhook.
+// The configuration is read from the preferences:
+//
+//	teams_enabled             "true" to enable the sender
+//	teams_webhook_url_crypt   the encrypted webhook url
+type Teams struct {
+	prefs  *prefs
+	client *http.Client
+
+	// Renderer renders the title and the text of grouped notifications, optional
+	Renderer *Renderer
+}
+
+func NewTeams(prefService es.PreferenceService, crypter es.Crypter) (*Teams, error) {
+	if prefService == nil {
+		return nil, fmt.Errorf("preference service is nil")
+	}
+
+	return &Teams{
+		prefs:  &prefs{name: "teams", service: prefService, crypter: crypter},
+		client: &http.Client{Timeout: teamsTimeout},
+	}, nil
+}
+
+func (t *Teams) Send(ctx context.Context, result *es.Result) error {
+	loaded, err := t.prefs.load(ctx, true)
+	if err != nil {
+		return err
+	}
+
+	webhookURL, err := t.prefs.decrypt(loaded, "webhook_url")
+	if err != nil {
+		return err
+	}
+	if webhookURL == "" {
+		return ErrNoTeamsWebhookURL
+	}
+
+	card, err := t.Card(ctx, result)
+	if err != nil {
+		return err
+	}
+
+	return postJSON(ctx, t.client, "teams", webhookURL, nil, &TeamsMessage{
+		Type: "message",
+		Attachments: []*TeamsAttachment{{
+			ContentType: "application/vnd.microsoft.card.adaptive",
+			Content:     card,
+		}},
+	})
+}
+
+// Enabled reports if the sender is enabled and has a webhook url
+func (t *Teams) Enabled() bool {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	loaded, err := t.prefs.load(ctx, false)
+	return err == nil && t.prefs.enabled(loaded) && loaded.Has("teams_webhook_url_crypt")
+}
+
+// Card returns the Adaptive Card of the result with a colored header, the facts
+// and the button to the detector
+func (t *Teams) Card(ctx context.Context, result *es.Result) (*AdaptiveCard, error) {
+	msg, err := t.Renderer.Render(ctx, "teams", result)
+	if err != nil {
+		return nil, err
+	}
+	data := t.Renderer.Data(ctx, result)
+
+	style, color := teamsStyle(result.State)
+	card := &AdaptiveCard{
+		Schema:  "http://adaptivecards.io/schemas/adaptive-card.json",
+		Type:    "AdaptiveCard",
+		Version: "1.4",
+		MSTeams: map[string]string{"width": "Full"},
+		Body: []any{
+			&CardContainer{
+				Type:  "Container",
+				Style: style,
+				Bleed: true,
+				Items: []any{
+					&CardTextBlock{Type: "TextBlock", Text: msg.Subject, Size: "Medium", Weight: "Bolder", Color: color, Wrap: true},
+				},
+			},
+		},
+	}
+
+	// grouped notifications list the detectors in the rendered body
+	if len(result.Group) > 0 {
+		card.Body = append(card.Body, &CardTextBlock{Type: "TextBlock", Text: msg.Body, Wrap: true})
+	} else {
+		card.Body = append(card.Body, &CardFactSet{Type: "FactSet", Facts: teamsFacts(data)})
+	}
+
+	if data.Links.Detector != "" {
+		card.Actions = append(card.Actions, &CardAction{Type: "Action.OpenUrl", Title: "Open detector", URL: data.Links.Detector})
+	}
+
+	return card, nil
+}
+
+func teamsFacts(data *es.TemplateData) []*CardFact {
+	facts := []*CardFact{
+		{Title: "Host", Value: data.Host},
+		{Title: "Detector", Value: data.Detector},
+		{Title: "State", Value: string(data.State)},
+	}
+
+	if data.Notification == es.NotificationRecovery && data.PreviousState != "" {
+		facts[2].Value += " (was " + string(data.PreviousState) + ")"
+	}
+
+	if data.Message != "" {
+		facts = append(facts, &CardFact{Title: "Message", Value: data.Message})
+	}
+
+	// since is the start of the incident or of the problem, the time of the notification otherwise
+	since := time.Now().Add(-time.Duration(data.Elapsed))
+	if data.Incident != nil && data.Notification != es.NotificationRecovery {
+		since = data.Incident.CreatedAt
+	}
+	value := since.Format("2006-01-02 15:04:05 MST")
+	if elapsed := data.ElapsedText(); elapsed != "" {
+		value += " (" + elapsed + ")"
+	}
+	facts = append(facts, &CardFact{Title: "Since", Value: value})
+
+	return facts
+}
+
+// teamsStyle returns the container style and the text color of the state
+func teamsStyle(state es.State) (string, string) {
+	switch state {
+	case es.StateOK:
+		return "good", "Good"
+	case es.StateWarn:
+		return "warning", "Warning"
+	case es.StateCritical:
+		return "attention", "Attention"
+	default:
+		return "emphasis", "Default"
+	}
+}
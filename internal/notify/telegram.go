@@ -1,16 +1,32 @@
 package notify
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"strings"
+	"sync"
+	"time"
 
 	echosight "github.com/alexjoedt/echosight/internal"
-	"github.com/alexjoedt/echosight/internal/filter"
+	"github.com/alexjoedt/echosight/internal/logger"
+)
+
+const (
+	// TelegramBaseURL is the default url of the Bot API
+	TelegramBaseURL = "https://api.telegram.org"
+
+	telegramTimeout time.Duration = time.Second * 10
+	// telegramPollTimeout is the long polling timeout of getUpdates, the client waits a bit longer
+	telegramPollTimeout int           = 30
+	telegramPollClient  time.Duration = time.Second * 40
+	// telegramIdle is the interval to check the config while the commands are disabled
+	telegramIdle time.Duration = time.Second * 30
 )
 
 var (
@@ -20,110 +36,324 @@ var (
 
 var _ Sender = (*telegram)(nil)
 
+// telegram sends the notifications to the configured chats and answers the bot commands
+// of these chats with long polling. The configuration is read from the preferences:
+//
+//	telegram_enabled          "true" to enable the sender
+//	telegram_bot_token_crypt  the encrypted bot token
+//	telegram_chat_ids         comma separated chat ids
+//	telegram_commands         "true" to answer the bot commands, optional
+//	telegram_api_url          overrides the url of the Bot API, e.g. for a fake server, optional
 type telegram struct {
-	service  echosight.PreferenceService
-	botToken string
-	chatIDs  []string
+	prefs      *prefs
+	client     *http.Client
+	pollClient *http.Client
+	log        *logger.Logger
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	// Renderer renders the messages, the plain text default is used without a renderer
+	Renderer *Renderer
+	// Commands answers the bot commands, no commands are answered without it
+	Commands *BotCommands
+}
+
+type telegramConfig struct {
 	enabled  bool
+	commands bool
+	token    string
+	chatIDs  []string
+	apiURL   string
 }
 
-func NewTelegramBot(prefService echosight.PreferenceService) (*telegram, error) {
+func NewTelegramBot(prefService echosight.PreferenceService, crypter echosight.Crypter) (*telegram, error) {
 	if prefService == nil {
 		return nil, fmt.Errorf("preference service is nil")
 	}
 
-	t := &telegram{
-		service: prefService,
+	return &telegram{
+		prefs:      &prefs{name: "telegram", service: prefService, crypter: crypter},
+		client:     &http.Client{Timeout: telegramTimeout},
+		pollClient: &http.Client{Timeout: telegramPollClient},
+		log:        logger.New("Telegram"),
+	}, nil
+}
+
+// TelegramTemplate returns the built-in template of telegram, the html is sent with the HTML parse mode
+func TelegramTemplate() *echosight.NotificationTemplate {
+	tmpl := DefaultTemplate()
+	tmpl.Channel = "telegram"
+	tmpl.HTML = MustReadTemplate(templateFS, "templates/telegram_html.tmpl")
+	return tmpl
+}
+
+func (t *telegram) Send(ctx context.Context, result *echosight.Result) error {
+	config, err := t.config(ctx, true)
+	if err != nil {
+		return err
 	}
 
-	err := t.loadConfig()
+	msg, err := t.Renderer.Render(ctx, "telegram", result)
 	if err != nil {
-		if !errors.Is(err, ErrNoTelegramBotToken) &&
-			!errors.Is(err, ErrNoTelegramChatID) {
-			return nil, err
+		return err
+	}
+
+	// a stored template without html is sent as plain text
+	text, parseMode := strings.TrimSpace(msg.Body), ""
+	if msg.HTML != "" {
+		text, parseMode = strings.TrimSpace(msg.HTML), "HTML"
+	}
+
+	var sendErr []error
+	for _, id := range config.chatIDs {
+		if err := t.sendMessage(ctx, config, id, text, parseMode); err != nil {
+			sendErr = append(sendErr, err)
 		}
 	}
 
-	return t, nil
+	return errors.Join(sendErr...)
 }
 
-func (t *telegram) send(chatID string, text string) error {
+// Enabled reports if telegram is enabled, the preferences are cached
+func (t *telegram) Enabled() bool {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	config, err := t.config(ctx, false)
+	return err == nil && config.enabled
+}
+
+// config reads the configuration from the preferences.
+// The bot token is stored encrypted, a plain telegram_bot_token of older versions is still read.
+func (t *telegram) config(ctx context.Context, reload bool) (telegramConfig, error) {
+	loaded, err := t.prefs.load(ctx, reload)
+	if err != nil {
+		return telegramConfig{}, err
+	}
+
+	config := telegramConfig{
+		enabled:  t.prefs.enabled(loaded),
+		commands: t.prefs.get(loaded, "commands") == "true",
+		apiURL:   strings.TrimSuffix(t.prefs.get(loaded, "api_url"), "/"),
+	}
+	if config.apiURL == "" {
+		config.apiURL = TelegramBaseURL
+	}
+
+	config.token, err = t.prefs.decrypt(loaded, "bot_token")
+	if err != nil {
+		return config, err
+	}
+	if config.token == "" {
+		config.token = t.prefs.get(loaded, "bot_token")
+	}
+	if config.token == "" {
+		return config, ErrNoTelegramBotToken
+	}
+
+	for _, id := range strings.Split(t.prefs.get(loaded, "chat_ids"), ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			config.chatIDs = append(config.chatIDs, id)
+		}
+	}
+	if len(config.chatIDs) == 0 {
+		return config, ErrNoTelegramChatID
+	}
+
+	return config, nil
+}
 
-	var payload struct {
-		Text   string `json:"text"`
-		ChatID string `json:"chat_id"`
+func (t *telegram) sendMessage(ctx context.Context, config telegramConfig, chatID string, text string, parseMode string) error {
+	payload := struct {
+		ChatID                string `json:"chat_id"`
+		Text                  string `json:"text"`
+		ParseMode             string `json:"parse_mode,omitempty"`
+		DisableWebPagePreview bool   `json:"disable_web_page_preview"`
+	}{
+		ChatID:                chatID,
+		Text:                  text,
+		ParseMode:             parseMode,
+		DisableWebPagePreview: true,
 	}
 
-	payload.ChatID = chatID
-	payload.Text = text
+	return t.call(ctx, t.client, config, "sendMessage", payload, nil)
+}
 
+// call calls the method of the Bot API and decodes the result into v, if v is not nil
+func (t *telegram) call(ctx context.Context, client *http.Client, config telegramConfig, method string, payload any, v any) error {
 	data, err := json.Marshal(payload)
 	if err != nil {
 		return err
 	}
 
-	reader := strings.NewReader(string(data))
-	client := &http.Client{} // TODO: timeout
-	url := fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage", t.botToken)
-	req, err := http.NewRequest("POST", url, reader)
+	endpoint := fmt.Sprintf("%s/bot%s/%s", config.apiURL, config.token, method)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(data))
 	if err != nil {
 		return err
 	}
-	req.Header.Add("Content-Type", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+
 	res, err := client.Do(req)
 	if err != nil {
+		// the url contains the token, it must not be logged
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return fmt.Errorf("telegram %s: %w", method, urlErr.Err)
+		}
 		return err
 	}
 	defer res.Body.Close()
 
-	_, err = io.ReadAll(res.Body)
+	body, err := io.ReadAll(io.LimitReader(res.Body, 1<<20))
 	if err != nil {
 		return err
 	}
 
+	var response struct {
+		OK          bool            `json:"ok"`
+		Description string          `json:"description"`
+		Result      json.RawMessage `json:"result"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil || res.StatusCode < 200 || res.StatusCode > 299 || !response.OK {
+		return fmt.Errorf("telegram responded with status %d: %s", res.StatusCode, body)
+	}
+
+	if v != nil {
+		return json.Unmarshal(response.Result, v)
+	}
 	return nil
 }
 
-func (t *telegram) Send(ctx context.Context, result *echosight.Result) error {
-	err := t.loadConfig()
-	if err != nil {
-		return err
-	}
+type telegramUpdate struct {
+	UpdateID int64            `json:"update_id"`
+	Message  *telegramMessage `json:"message"`
+}
 
-	var sendErr []error
-	for _, id := range t.chatIDs {
-		if err := t.send(id, "TODO: result"); err != nil {
-			sendErr = append(sendErr, err)
-		}
+type telegramMessage struct {
+	Text string `json:"text"`
+	Chat struct {
+		ID int64 `json:"id"`
+	} `json:"chat"`
+	From *struct {
+		Username  string `json:"username"`
+		FirstName string `json:"first_name"`
+		LastName  string `json:"last_name"`
+	} `json:"from"`
+}
+
+// Start starts the long polling for the bot commands
+func (t *telegram) Start() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.cancel != nil {
+		return
 	}
 
-	return errors.Join(sendErr...)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.cancel = cancel
+	t.done = make(chan struct{})
+	go t.poll(ctx, t.done)
 }
 
-func (t *telegram) Enabled() bool {
-	t.loadConfig()
-	return t.enabled
+// Stop stops the long polling and waits for the running commands
+func (t *telegram) Stop() {
+	t.mu.Lock()
+	cancel, done := t.cancel, t.done
+	t.cancel = nil
+	t.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+		<-done
+	}
 }
 
-func (t *telegram) loadConfig() error {
-	f := filter.NewDefaultPreferenceFilter()
-	f.Name = "telegram"
-	rawConfig, err := t.service.List(context.Background(), f)
-	if err != nil {
-		return err
+func (t *telegram) poll(ctx context.Context, done chan struct{}) {
+	defer close(done)
+
+	// every command runs on its own, a slow /check doesn't block the other commands
+	var running sync.WaitGroup
+	defer running.Wait()
+
+	var offset int64
+	for {
+		config, err := t.config(ctx, false)
+		if err != nil || !config.enabled || !config.commands || t.Commands == nil {
+			if !sleep(ctx, telegramIdle) {
+				return
+			}
+			continue
+		}
+
+		var updates []telegramUpdate
+		err = t.call(ctx, t.pollClient, config, "getUpdates", map[string]any{
+			"offset":          offset,
+			"timeout":         telegramPollTimeout,
+			"allowed_updates": []string{"message"},
+		}, &updates)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			t.log.Warnf("failed to get updates: %v", err)
+			if !sleep(ctx, time.Second*5) {
+				return
+			}
+			continue
+		}
+
+		for _, update := range updates {
+			offset = update.UpdateID + 1
+			running.Add(1)
+			go func() {
+				defer running.Done()
+				t.handle(ctx, config, update.Message)
+			}()
+		}
 	}
+}
 
-	if !rawConfig.Has("telegram_bot_token") {
-		return ErrNoTelegramBotToken
+// handle answers a command, the messages of other chats are ignored
+func (t *telegram) handle(ctx context.Context, config telegramConfig, msg *telegramMessage) {
+	if msg == nil || !strings.HasPrefix(msg.Text, "/") {
+		return
 	}
 
-	if !rawConfig.Has("telegram_chat_ids") {
-		return ErrNoTelegramChatID
+	chatID := fmt.Sprint(msg.Chat.ID)
+	allowed := false
+	for _, id := range config.chatIDs {
+		allowed = allowed || id == chatID
+	}
+	if !allowed {
+		t.log.Warnf("ignored command of unknown chat %s", chatID)
+		return
 	}
 
-	t.botToken = rawConfig.Get("telegram_bot_token")
-	t.chatIDs = strings.Split(rawConfig.Get("telegram_chat_ids"), ",")
-	t.enabled = rawConfig.Get("telegram_enabled") == "true"
+	from := "unknown"
+	if msg.From != nil {
+		from = strings.TrimSpace(msg.From.FirstName + " " + msg.From.LastName)
+		if msg.From.Username != "" {
+			from = "@" + msg.From.Username
+		}
+	}
 
-	return nil
+	cmdCtx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	answer := t.Commands.Run(cmdCtx, msg.Text, "Telegram "+from)
+	if err := t.sendMessage(cmdCtx, config, chatID, answer, "HTML"); err != nil {
+		t.log.Errorf("failed to answer command: %v", err)
+	}
+}
+
+// sleep waits for the duration, it returns false if the context is done
+func sleep(ctx context.Context, d time.Duration) bool {
+	select {
+	case <-time.After(d):
+		return true
+	case <-ctx.Done():
+		return false
+	}
 }
@@ -0,0 +1,81 @@
+package notify
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	es "github.com/alexjoedt/echosight/internal"
+)
+
+// fakeBotAPI returns the updates with the first getUpdates and records the sent messages
+type fakeBotAPI struct {
+	mu       sync.Mutex
+	updates  []telegramUpdate
+	messages []map[string]any
+}
+
+func (b *fakeBotAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var result any = true
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/getUpdates"):
+		result = b.updates
+		b.updates = nil
+	case strings.HasSuffix(r.URL.Path, "/sendMessage"):
+		var message map[string]any
+		json.NewDecoder(r.Body).Decode(&message)
+		b.messages = append(b.messages, message)
+	}
+	json.NewEncoder(w).Encode(map[string]any{"ok": true, "result": result})
+}
+
+func (b *fakeBotAPI) sent() []map[string]any {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.messages
+}
+
+func telegramCommand(updateID int64, chatID int64, text string) telegramUpdate {
+	msg := &telegramMessage{Text: text}
+	msg.Chat.ID = chatID
+	return telegramUpdate{UpdateID: updateID, Message: msg}
+}
+
+func TestTelegramAnswersOnlyConfiguredChats(t *testing.T) {
+	api := &fakeBotAPI{updates: []telegramUpdate{
+		telegramCommand(1, 666, "/status"),
+		telegramCommand(2, 42, "/status"),
+	}}
+	srv := httptest.NewServer(api)
+	defer srv.Close()
+
+	bot, err := NewTelegramBot(newChannelPreferences(&es.NotificationChannel{
+		Settings: map[string]string{"chat_ids": "42", "commands": "true", "api_url": srv.URL},
+		Secrets:  map[string]string{"bot_token": "crypt:token"},
+	}, "telegram"), fakeCrypter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bot.Commands = &BotCommands{}
+	// long polling answers immediately with the fake server
+	bot.pollClient = &http.Client{Timeout: time.Second}
+
+	bot.Start()
+	deadline := time.Now().Add(time.Second * 5)
+	for len(api.sent()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond * 10)
+	}
+	bot.Stop()
+
+	sent := api.sent()
+	if len(sent) != 1 || sent[0]["chat_id"] != "42" {
+		t.Fatalf("expected one answer to chat 42, got %v", sent)
+	}
+}
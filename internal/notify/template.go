@@ -0,0 +1,194 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	"sync"
+	"text/template"
+
+	es "github.com/alexjoedt/echosight/internal"
+	"github.com/alexjoedt/echosight/internal/logger"
+	"github.com/google/uuid"
+)
+
+//go:embed "templates"
+var templateFS embed.FS
+
+// Message is a rendered notification
+type Message struct {
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+	HTML    string `json:"html,omitempty"`
+}
+
+// Renderer renders the notifications with the stored template of the channel and type.
+// Without a stored template, the built-in default of the channel is used.
+type Renderer struct {
+	templates es.TemplateService
+	incidents es.IncidentService
+	log       *logger.Logger
+
+	mu       sync.RWMutex
+	defaults map[string]*es.NotificationTemplate
+
+	// PublicURL is the address of the web client for the links, no links if empty
+	PublicURL string
+}
+
+func NewRenderer(ts es.TemplateService, is es.IncidentService) *Renderer {
+	return &Renderer{
+		templates: ts,
+		incidents: is,
+		log:       logger.New("Renderer"),
+		defaults:  make(map[string]*es.NotificationTemplate),
+	}
+}
+
+// SetDefault sets the built-in template of the channel
+func (r *Renderer) SetDefault(channel string, tmpl *es.NotificationTemplate) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.defaults[channel] = tmpl
+}
+
+// Default returns the built-in template of the channel or the plain text default
+func (r *Renderer) Default(channel string) *es.NotificationTemplate {
+	if r != nil {
+		r.mu.RLock()
+		tmpl, ok := r.defaults[channel]
+		r.mu.RUnlock()
+		if ok {
+			return tmpl
+		}
+	}
+
+	tmpl := DefaultTemplate()
+	tmpl.Channel = channel
+	return tmpl
+}
+
+// DefaultTemplate returns the plain text template, used for channels without a built-in template
+func DefaultTemplate() *es.NotificationTemplate {
+	return &es.NotificationTemplate{
+		Name:    "default",
+		Active:  true,
+		Subject: MustReadTemplate(templateFS, "templates/subject.tmpl"),
+		Body:    MustReadTemplate(templateFS, "templates/body.tmpl"),
+	}
+}
+
+// MustReadTemplate reads an embedded template, it panics if the file doesn't exist.
+// The built-in templates of the senders are read with it.
+func MustReadTemplate(fs embed.FS, name string) string {
+	data, err := fs.ReadFile(name)
+	if err != nil {
+		panic(fmt.Sprintf("missing embedded template %s: %v", name, err))
+	}
+	return string(data)
+}
+
+// Render renders the result with the template of the channel.
+// The renderer can be nil, then the plain text default is used.
+func (r *Renderer) Render(ctx context.Context, channel string, result *es.Result) (*Message, error) {
+	if r == nil {
+		return RenderTemplate(DefaultTemplate(), &es.TemplateData{Result: result})
+	}
+
+	tmpl, err := r.templates.Find(ctx, channel, result.Notification)
+	if err != nil {
+		if es.ErrorCode(err) != es.ENOTFOUND {
+			r.log.Errorf("failed to find template of %s, using the default: %v", channel, err)
+		}
+		tmpl = r.Default(channel)
+	}
+
+	msg, err := RenderTemplate(tmpl, r.Data(ctx, result))
+	if err != nil && tmpl.ID != uuid.Nil {
+		// a broken template must not lose the notification
+		r.log.Errorf("failed to render template %s, using the default: %v", tmpl.Name, err)
+		return RenderTemplate(r.Default(channel), r.Data(ctx, result))
+	}
+
+	return msg, err
+}
+
+// Data returns the template context of the result with the incident and the links
+func (r *Renderer) Data(ctx context.Context, result *es.Result) *es.TemplateData {
+	data := &es.TemplateData{Result: result}
+	if r == nil {
+		return data
+	}
+
+	if result.IncidentID != "" && r.incidents != nil {
+		if id, err := uuid.Parse(result.IncidentID); err == nil {
+			incident, err := r.incidents.GetByID(ctx, id)
+			if err != nil {
+				r.log.Errorf("failed to get incident for template: %v", err)
+			} else {
+				data.Incident = incident
+			}
+		}
+	}
+
+	if r.PublicURL != "" {
+		base := strings.TrimSuffix(r.PublicURL, "/")
+		if result.DetectorID != "" {
+			data.Links.Detector = base + "/detectors/" + result.DetectorID
+		}
+		if result.IncidentID != "" {
+			data.Links.Incident = base + "/incidents/" + result.IncidentID
+		}
+	}
+
+	return data
+}
+
+// RenderTemplate renders the subject and body with text/template and the html with html/template
+func RenderTemplate(tmpl *es.NotificationTemplate, data *es.TemplateData) (*Message, error) {
+	subject, err := renderText("subject", tmpl.Subject, data)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := renderText("body", tmpl.Body, data)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := &Message{
+		Subject: strings.TrimSpace(subject),
+		Body:    body,
+	}
+
+	if tmpl.HTML != "" {
+		t, err := htmltemplate.New("html").Parse(tmpl.HTML)
+		if err != nil {
+			return nil, err
+		}
+
+		buf := new(bytes.Buffer)
+		if err := t.Execute(buf, data); err != nil {
+			return nil, err
+		}
+		msg.HTML = buf.String()
+	}
+
+	return msg, nil
+}
+
+func renderText(name string, text string, data *es.TemplateData) (string, error) {
+	t, err := template.New(name).Parse(text)
+	if err != nil {
+		return "", err
+	}
+
+	buf := new(bytes.Buffer)
+	if err := t.Execute(buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,250 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"text/template"
+	"time"
+
+	es "github.com/alexjoedt/echosight/internal"
+	"github.com/alexjoedt/echosight/internal/filter"
+	"github.com/alexjoedt/echosight/internal/logger"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultWebhookTimeout time.Duration = time.Second * 10
+	webhookAttempts       int           = 3
+	webhookRetryDelay     time.Duration = time.Second
+
+	// HeaderSignature is the hex encoded HMAC-SHA256 of "<timestamp>.<body>" with the secret
+	HeaderSignature = "X-Echosight-Signature"
+	HeaderTimestamp = "X-Echosight-Timestamp"
+	HeaderEvent     = "X-Echosight-Event"
+	HeaderDelivery  = "X-Echosight-Delivery"
+)
+
+var (
+	_ Sender   = (*WebhookSender)(nil)
+	_ Splitter = (*WebhookSender)(nil)
+)
+
+// WebhookPayload is the JSON body of the webhooks without a body template
+type WebhookPayload struct {
+	Event      es.NotificationType `json:"event"`
+	Timestamp  time.Time           `json:"timestamp"`
+	Host       string              `json:"host"`
+	Detector   string              `json:"detector"`
+	DetectorID string              `json:"detectorId,omitempty"`
+	State      es.State            `json:"state"`
+	Message    string              `json:"message"`
+	IncidentID string              `json:"incidentId,omitempty"`
+	Links      es.TemplateLinks    `json:"links"`
+	Result     *es.Result          `json:"result"`
+}
+
+// WebhookSender posts the notifications to the webhooks of the result, to all active webhooks
+// if the result has no webhooks. Failed requests are retried, except client errors.
+type WebhookSender struct {
+	webhooks es.WebhookService
+	crypter  es.Crypter
+	client   *http.Client
+	log      *logger.Logger
+
+	// Renderer provides the links and the incident for the templates, optional
+	Renderer *Renderer
+}
+
+func NewWebhookSender(ws es.WebhookService, crypter es.Crypter) *WebhookSender {
+	return &WebhookSender{
+		webhooks: ws,
+		crypter:  crypter,
+		client:   &http.Client{},
+		log:      logger.New("Webhook"),
+	}
+}
+
+func (ws *WebhookSender) Send(ctx context.Context, result *es.Result) error {
+	webhooks, err := ws.list(ctx, result.Webhooks)
+	if err != nil {
+		return err
+	}
+
+	var sendErr []error
+	for _, webhook := range webhooks {
+		if err := ws.Post(ctx, webhook, result); err != nil {
+			sendErr = append(sendErr, fmt.Errorf("webhook %s: %w", webhook.Name, err))
+		}
+	}
+
+	return errors.Join(sendErr...)
+}
+
+// Split returns a result for every webhook of the result, so every webhook has its own outbox entry
+func (ws *WebhookSender) Split(ctx context.Context, result *es.Result) ([]*es.Result, error) {
+	webhooks, err := ws.list(ctx, result.Webhooks)
+	if err != nil {
+		return nil, err
+	}
+
+	parts := make([]*es.Result, 0, len(webhooks))
+	for _, webhook := range webhooks {
+		part := *result
+		part.Webhooks = []uuid.UUID{webhook.ID}
+		parts = append(parts, &part)
+	}
+	return parts, nil
+}
+
+// Enabled reports if an active webhook exists
+func (ws *WebhookSender) Enabled() bool {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	webhooks, err := ws.list(ctx, nil)
+	return err == nil && len(webhooks) > 0
+}
+
+func (ws *WebhookSender) list(ctx context.Context, ids []uuid.UUID) ([]*es.Webhook, error) {
+	f := filter.NewDefaultWebhookFilter()
+	active := true
+	f.Active = &active
+	f.IDs = ids
+	return ws.webhooks.List(ctx, f)
+}
+
+// Post sends the result to the webhook with retries
+func (ws *WebhookSender) Post(ctx context.Context, webhook *es.Webhook, result *es.Result) error {
+	body, contentType, err := ws.body(ctx, webhook, result)
+	if err != nil {
+		return err
+	}
+
+	secret := ""
+	if webhook.SecretCrypt != "" {
+		secret, err = ws.crypter.Decrypt(webhook.SecretCrypt)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt secret: %w", err)
+		}
+	}
+
+	// the delivery id is the same for all attempts, so the receiver can detect duplicates
+	delivery := result.DeliveryID
+	if delivery == "" {
+		delivery = uuid.New().String()
+	}
+	for attempt := 1; ; attempt++ {
+		retry, err := ws.post(ctx, webhook, result, body, contentType, secret, delivery)
+		if err == nil {
+			return nil
+		}
+
+		if !retry || attempt >= webhookAttempts {
+			return err
+		}
+
+		ws.log.Debugw("webhook failed, retrying", logger.Str("webhook", webhook.Name), logger.Str("error", err.Error()))
+		select {
+		case <-time.After(webhookRetryDelay * time.Duration(attempt)):
+		case <-ctx.Done():
+			return err
+		}
+	}
+}
+
+// post sends one request and reports if a failure can be retried
+func (ws *WebhookSender) post(ctx context.Context, webhook *es.Webhook, result *es.Result, body []byte, contentType string, secret string, delivery string) (bool, error) {
+	timeout := time.Duration(webhook.Timeout)
+	if timeout <= 0 {
+		timeout = defaultWebhookTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("User-Agent", "EchoSight/"+es.Version)
+	req.Header.Set(HeaderEvent, string(result.Notification))
+	req.Header.Set(HeaderDelivery, delivery)
+	for key, value := range webhook.Headers {
+		req.Header.Set(key, value)
+	}
+
+	if secret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(HeaderTimestamp, timestamp)
+		req.Header.Set(HeaderSignature, "sha256="+Sign(secret, timestamp, body))
+	}
+
+	res, err := ws.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer res.Body.Close()
+
+	// the response is read, so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(res.Body, 1<<16))
+
+	if res.StatusCode >= 200 && res.StatusCode <= 299 {
+		return false, nil
+	}
+
+	err = fmt.Errorf("unexpected status %d", res.StatusCode)
+	return res.StatusCode >= 500 || res.StatusCode == http.StatusTooManyRequests, err
+}
+
+// body returns the rendered body template or the JSON payload
+func (ws *WebhookSender) body(ctx context.Context, webhook *es.Webhook, result *es.Result) ([]byte, string, error) {
+	data := ws.Renderer.Data(ctx, result)
+
+	if webhook.Body != "" {
+		tmpl, err := template.New("body").Funcs(es.WebhookFuncs).Parse(webhook.Body)
+		if err != nil {
+			return nil, "", err
+		}
+
+		buf := new(bytes.Buffer)
+		if err := tmpl.Execute(buf, data); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), "application/json", nil
+	}
+
+	body, err := json.Marshal(WebhookPayload{
+		Event:      result.Notification,
+		Timestamp:  time.Now(),
+		Host:       result.Host,
+		Detector:   result.Detector,
+		DetectorID: result.DetectorID,
+		State:      result.State,
+		Message:    result.Message,
+		IncidentID: result.IncidentID,
+		Links:      data.Links,
+		Result:     result,
+	})
+	return body, "application/json", err
+}
+
+// Sign returns the hex encoded HMAC-SHA256 of "<timestamp>.<body>".
+// Receivers verify the signature with the shared secret and reject old timestamps.
+func Sign(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,175 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	es "github.com/alexjoedt/echosight/internal"
+	"github.com/alexjoedt/echosight/internal/filter"
+	"github.com/google/uuid"
+)
+
+// fakeCrypter "encrypts" with a prefix
+type fakeCrypter struct{}
+
+func (fakeCrypter) Encrypt(text string) (string, error) {
+	return "crypt:" + text, nil
+}
+
+func (fakeCrypter) Decrypt(ciphertext string) (string, error) {
+	return strings.TrimPrefix(ciphertext, "crypt:"), nil
+}
+
+type fakeWebhooks struct {
+	es.WebhookService
+	webhooks []*es.Webhook
+}
+
+func (f *fakeWebhooks) List(ctx context.Context, webhookFilter *filter.WebhookFilter) ([]*es.Webhook, error) {
+	var webhooks []*es.Webhook
+	for _, webhook := range f.webhooks {
+		if len(webhookFilter.IDs) == 0 || slices.Contains(webhookFilter.IDs, webhook.ID) {
+			webhooks = append(webhooks, webhook)
+		}
+	}
+	return webhooks, nil
+}
+
+type fakeOutbox struct {
+	mu      sync.Mutex
+	entries []*es.Result
+}
+
+func (f *fakeOutbox) Enqueue(ctx context.Context, senderID string, result *es.Result) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.entries = append(f.entries, result)
+	return nil
+}
+
+// webhookServer counts the requests and responds with the status
+func webhookServer(t *testing.T, status int) (*httptest.Server, *int) {
+	t.Helper()
+
+	var mu sync.Mutex
+	count := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		count++
+		mu.Unlock()
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &count
+}
+
+func TestWebhookOutboxEntryPerWebhook(t *testing.T) {
+	ok, okCount := webhookServer(t, http.StatusOK)
+	failing, failingCount := webhookServer(t, http.StatusBadRequest)
+
+	webhooks := &fakeWebhooks{webhooks: []*es.Webhook{
+		{ID: uuid.New(), Name: "ok", URL: ok.URL, Active: true},
+		{ID: uuid.New(), Name: "failing", URL: failing.URL, Active: true},
+	}}
+	sender := NewWebhookSender(webhooks, fakeCrypter{})
+
+	outbox := &fakeOutbox{}
+	n := NewNotifier()
+	n.SetOutbox(outbox)
+	n.AddSender("webhook", sender)
+
+	if err := n.Send(context.Background(), &es.Result{Host: "web", Detector: "http", State: es.StateCritical}); err != nil {
+		t.Fatal(err)
+	}
+	if len(outbox.entries) != 2 {
+		t.Fatalf("expected an outbox entry per webhook, got %d", len(outbox.entries))
+	}
+
+	// the failed delivery of one entry doesn't resend to the other webhook
+	var failed int
+	for _, entry := range outbox.entries {
+		if err := n.Deliver(context.Background(), "webhook", entry); err != nil {
+			failed++
+		}
+	}
+	if failed != 1 || *okCount != 1 || *failingCount != 1 {
+		t.Fatalf("failed %d, ok webhook %d requests, failing webhook %d requests", failed, *okCount, *failingCount)
+	}
+}
+
+func TestWebhookSignature(t *testing.T) {
+	var signature, timestamp string
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		signature = r.Header.Get(HeaderSignature)
+		timestamp = r.Header.Get(HeaderTimestamp)
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer srv.Close()
+
+	sender := NewWebhookSender(&fakeWebhooks{}, fakeCrypter{})
+	webhook := &es.Webhook{Name: "signed", URL: srv.URL, Active: true, SecretCrypt: "crypt:secret"}
+	if err := sender.Post(context.Background(), webhook, &es.Result{Host: "web", Detector: "http", State: es.StateCritical}); err != nil {
+		t.Fatal(err)
+	}
+
+	if timestamp == "" || signature != "sha256="+Sign("secret", timestamp, body) {
+		t.Fatalf("invalid signature %q of timestamp %q", signature, timestamp)
+	}
+}
+
+func TestWebhookRetries(t *testing.T) {
+	tests := []struct {
+		status   int
+		attempts int
+	}{
+		{status: http.StatusBadGateway, attempts: webhookAttempts},
+		{status: http.StatusTooManyRequests, attempts: webhookAttempts},
+		{status: http.StatusBadRequest, attempts: 1},
+	}
+
+	for _, tt := range tests {
+		srv, count := webhookServer(t, tt.status)
+		sender := NewWebhookSender(&fakeWebhooks{}, fakeCrypter{})
+		webhook := &es.Webhook{Name: "retry", URL: srv.URL, Active: true}
+
+		if err := sender.Post(context.Background(), webhook, &es.Result{State: es.StateCritical}); err == nil {
+			t.Fatalf("status %d: expected an error", tt.status)
+		}
+		if *count != tt.attempts {
+			t.Fatalf("status %d: expected %d attempts, got %d", tt.status, tt.attempts, *count)
+		}
+	}
+}
+
+func TestWebhookBodyTemplate(t *testing.T) {
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer srv.Close()
+
+	sender := NewWebhookSender(&fakeWebhooks{}, fakeCrypter{})
+	webhook := &es.Webhook{Name: "chat", URL: srv.URL, Active: true, Body: `{"text": {{ json .Result.Message }}}`}
+	result := &es.Result{State: es.StateCritical, Message: `Get "http://web/health": connection refused`}
+	if err := sender.Post(context.Background(), webhook, result); err != nil {
+		t.Fatal(err)
+	}
+
+	var payload struct {
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		t.Fatalf("invalid json body %s: %v", body, err)
+	}
+	if payload.Text != result.Message {
+		t.Fatalf("expected text %q, got %q", result.Message, payload.Text)
+	}
+}
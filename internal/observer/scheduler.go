@@ -7,14 +7,19 @@ TODO: spawn new workers when tasks increase
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"sync"
 	"time"
 
 	"github.com/alexjoedt/echosight/dateutils"
 	es "github.com/alexjoedt/echosight/internal"
+	"github.com/alexjoedt/echosight/internal/escalation"
 	flow "github.com/alexjoedt/echosight/internal/eventflow"
+	"github.com/alexjoedt/echosight/internal/incident"
 	"github.com/alexjoedt/echosight/internal/logger"
+	"github.com/alexjoedt/echosight/internal/maintenance"
 	"github.com/alexjoedt/echosight/internal/notify"
+	"github.com/alexjoedt/echosight/internal/routing"
 	"github.com/google/uuid"
 )
 
@@ -28,12 +33,25 @@ type executor struct {
 	mu       sync.Mutex
 	lastRun  time.Time
 	lastMail time.Time
-	firstRun bool
+	// reminders is the number of reminders sent for the current problem
+	reminders int
+	firstRun  bool
+	retrying  bool
+
+	// running serializes the checks, processResult must never run concurrently
+	// for the same detector, e.g. for a slow check or a manual check
+	running sync.Mutex
 
 	done     chan struct{}
 	checkNow chan struct{}
 
-	history *es.CheckHistory
+	// ctx is canceled when the detector is removed, to cancel in-flight checks
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	history    *es.CheckHistory
+	thresholds *es.ThresholdEvaluator
+	anomalies  *es.AnomalyDetector
 }
 
 type Scheduler struct {
@@ -45,6 +63,7 @@ type Scheduler struct {
 
 	detectorService  es.DetectorService
 	metricService    es.MetricWriter
+	metricReader     es.MetricReader
 	eventHandler     *flow.Engine
 	notifier         *notify.Notifier
 	log              *logger.Logger
@@ -53,8 +72,41 @@ type Scheduler struct {
 	workerCount int
 	workerWg    sync.WaitGroup
 	schedulerWg sync.WaitGroup
+
+	// ctx is canceled when the scheduler stops, to cancel in-flight checks
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// DefaultTimeout is used for detectors without a timeout
+	DefaultTimeout time.Duration
+	// MaxTimeout limits the timeout of all detectors
+	MaxTimeout time.Duration
+	// ProcessTimeout limits the processing of a result (db update, metrics, notifications)
+	ProcessTimeout time.Duration
+
+	// HistoryService persists state changes and results, optional
+	HistoryService es.HistoryService
+
+	// Incidents opens and resolves the incidents on hard state changes, optional
+	Incidents *incident.Manager
+
+	// Maintenance suppresses the notifications during maintenance windows, optional
+	Maintenance *maintenance.Calendar
+
+	// Escalations notifies the problems of detectors with an escalation policy, optional
+	Escalations *escalation.Escalator
+
+	// Routing sends the notifications through the routing rules, optional.
+	// Without routing, the notifications are sent to all senders and recipients.
+	Routing *routing.Router
 }
 
+const (
+	defaultCheckTimeout   time.Duration = time.Second * 10
+	defaultMaxTimeout     time.Duration = time.Minute * 5
+	defaultProcessTimeout time.Duration = time.Second * 5
+)
+
 func NewScheduler(ds es.DetectorService, ms es.MetricService, eh *flow.Engine, n *notify.Notifier) *Scheduler {
 	workerCount := 3
 	s := &Scheduler{
@@ -64,11 +116,16 @@ func NewScheduler(ds es.DetectorService, ms es.MetricService, eh *flow.Engine, n
 		workerCount:     workerCount,
 		detectorService: ds,
 		metricService:   ms,
+		metricReader:    ms,
 		eventHandler:    eh,
 		notifier:        n,
 		stop:            make(chan struct{}, 1),
 		log:             logger.New("Observer-Scheduler"),
+		DefaultTimeout:  defaultCheckTimeout,
+		MaxTimeout:      defaultMaxTimeout,
+		ProcessTimeout:  defaultProcessTimeout,
 	}
+	s.ctx, s.cancel = context.WithCancel(context.Background())
 
 	return s
 }
@@ -82,24 +139,11 @@ func (s *Scheduler) AddDetector(detectorID uuid.UUID) (*executor, error) {
 		return nil, err
 	}
 
-	checker, err := d.GetChecker()
+	task, err := s.newExecutor(d)
 	if err != nil {
 		return nil, err
 	}
 
-	task := &executor{
-		id:       d.ID.String(),
-		name:     d.Name,
-		checker:  checker,
-		sched:    s,
-		done:     make(chan struct{}, 1),
-		checkNow: make(chan struct{}, 1),
-		lastRun:  dateutils.YearOne,
-		lastMail: dateutils.YearOne,
-		firstRun: true,
-		history:  &es.CheckHistory{Results: make([]*es.Result, 3)},
-	}
-
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	if _, ok := s.tasks[d.ID.String()]; !ok {
@@ -122,6 +166,115 @@ func (s *Scheduler) AddDetector(detectorID uuid.UUID) (*executor, error) {
 	return task, nil
 }
 
+// newExecutor creates the executor of the detector, the check history is restored from the detector
+func (s *Scheduler) newExecutor(d *es.Detector) (*executor, error) {
+	checker, err := d.GetChecker()
+	if err != nil {
+		return nil, err
+	}
+
+	task := &executor{
+		id:         d.ID.String(),
+		name:       d.Name,
+		checker:    checker,
+		sched:      s,
+		done:       make(chan struct{}, 1),
+		checkNow:   make(chan struct{}, 1),
+		lastRun:    dateutils.YearOne,
+		lastMail:   dateutils.YearOne,
+		firstRun:   true,
+		history:    es.NewCheckHistory(d.MaxAttempts, d.RecoveryAttempts),
+		thresholds: es.NewThresholdEvaluator(d.Thresholds),
+	}
+	task.ctx, task.cancel = context.WithCancel(context.Background())
+
+	if d.FlapDetection {
+		task.history.EnableFlapDetection(d.FlapLowThreshold, d.FlapHighThreshold)
+	}
+	task.history.Restore(d)
+
+	if len(d.Anomalies) > 0 {
+		task.anomalies = es.NewAnomalyDetector(d.Anomalies)
+		s.learnBaseline(task, d)
+	}
+	task.retrying = task.history.IsSoft()
+
+	return task, nil
+}
+
+// ReloadDetector replaces the executor of an active detector after its configuration
+// has changed. Unlike RemoveDetector, the hard state is kept and no state change is recorded.
+func (s *Scheduler) ReloadDetector(detectorID uuid.UUID) error {
+	s.mu.RLock()
+	old, ok := s.tasks[detectorID.String()]
+	s.mu.RUnlock()
+
+	// cancel the in-flight check and wait until its result is processed,
+	// the new executor restores the history from the stored detector
+	replaced := false
+	if ok {
+		old.cancel()
+		old.running.Lock()
+		defer old.running.Unlock()
+
+		// on a failure, the previous executor keeps running
+		defer func() {
+			if !replaced {
+				old.ctx, old.cancel = context.WithCancel(context.Background())
+			}
+		}()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	d, err := s.detectorService.GetByID(ctx, detectorID)
+	if err != nil {
+		return err
+	}
+
+	// an inactive detector is activated through its own route
+	if !d.Active {
+		return nil
+	}
+
+	task, err := s.newExecutor(d)
+	if err != nil {
+		return err
+	}
+
+	// keep the renotify interval of an ongoing problem
+	if ok {
+		task.lastMail = old.lastMail
+		task.reminders = old.reminders
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tasks[detectorID.String()] = task
+	replaced = true
+
+	return nil
+}
+
+// learnBaseline warms up the anomaly baselines with the already written metrics
+func (s *Scheduler) learnBaseline(task *executor, d *es.Detector) {
+	if s.metricReader == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*15)
+	defer cancel()
+
+	points, err := s.metricReader.Read(ctx, d.MetricFiler(es.AnomalyWarmUpRange))
+	if err != nil {
+		s.log.Errorf("failed to read metrics for the anomaly baseline: %v", err)
+		return
+	}
+
+	task.anomalies.Learn(points)
+}
+
 func (s *Scheduler) RemoveDetector(detectorID uuid.UUID) error {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
 	defer cancel()
@@ -134,16 +287,28 @@ func (s *Scheduler) RemoveDetector(detectorID uuid.UUID) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	_, ok := s.tasks[detectorID.String()]
+	task, ok := s.tasks[detectorID.String()]
 	if !ok {
 		s.log.Errorf("no detector job is running with provided id")
 	} else {
+		// cancel in-flight check
+		task.cancel()
 		delete(s.tasks, detectorID.String())
 	}
 
+	if d.HardState != es.StateInactive {
+		s.addStateChange(ctx, d, es.StateInactive, "detector deactivated", false)
+		d.StateChangedAt = time.Now()
+	}
+
 	// even if no detector is registered, update the state
 	d.Active = false
 	d.State = es.StateInactive
+	d.HardState = es.StateInactive
+	d.StateType = es.StateTypeHard
+	d.Attempt = 0
+	d.Flapping = false
+	d.FlapPercent = 0
 	err = s.detectorService.Update(ctx, d)
 	if err != nil {
 		return err
@@ -157,6 +322,37 @@ func (s *Scheduler) RemoveDetector(detectorID uuid.UUID) error {
 	return nil
 }
 
+// addStateChange persists the state change from the current hard state of the detector
+func (s *Scheduler) addStateChange(ctx context.Context, d *es.Detector, to es.State, message string, flapping bool) {
+	if s.HistoryService == nil {
+		return
+	}
+
+	from := d.HardState
+	if from == "" {
+		from = es.StateInactive
+	}
+
+	var duration time.Duration
+	if d.StateChangedAt.After(dateutils.YearOne) {
+		duration = time.Since(d.StateChangedAt)
+	}
+
+	err := s.HistoryService.AddStateChange(ctx, &es.StateChange{
+		DetectorID: d.ID,
+		HostID:     d.HostID,
+		FromState:  from,
+		ToState:    to,
+		Message:    message,
+		Flapping:   flapping,
+		Duration:   es.Duration(duration),
+		CreatedAt:  time.Now(),
+	})
+	if err != nil {
+		s.log.Errorf("failed to add state change: %v", err)
+	}
+}
+
 func (s *Scheduler) AddDetectors(ds ...*es.Detector) error {
 	for _, d := range ds {
 		_, err := s.AddDetector(d.ID)
@@ -176,6 +372,7 @@ func (s *Scheduler) Start() {
 
 	s.schedulerWg.Add(1)
 	s.schedulerRunning = true
+	s.ctx, s.cancel = context.WithCancel(context.Background())
 
 	s.taskPool = make(chan *executor, s.workerCount)
 	for i := 0; i < s.workerCount; i++ {
@@ -201,7 +398,7 @@ func (s *Scheduler) Start() {
 				s.mu.RLock()
 				for _, checkerTasks := range s.tasks {
 					checkerTasks.mu.Lock()
-					if now.Sub(checkerTasks.lastRun) >= checkerTasks.checker.Interval() {
+					if now.Sub(checkerTasks.lastRun) >= checkerTasks.interval() {
 						// lastRun must set immediatley here,
 						// because the execution could be take more then the scheduler Tick (1s)
 						// and then the scheduler would start every second a new execution as long the first run is not finished
@@ -222,18 +419,64 @@ func (s *Scheduler) Start() {
 
 }
 
-// Stop, blocks until scheduler and worker done
+// CheckNow schedules the check of the detector for the next tick,
+// the check runs on a worker like the regular checks
+func (s *Scheduler) CheckNow(detectorID uuid.UUID) error {
+	s.mu.RLock()
+	task, ok := s.tasks[detectorID.String()]
+	s.mu.RUnlock()
+	if !ok {
+		return es.ErrNotfoundf("detector is not scheduled")
+	}
+
+	return task.CheckNow()
+}
+
+// AnomalyBands adds the expected bands of the anomaly baselines of the detector to the points
+func (s *Scheduler) AnomalyBands(detectorID uuid.UUID, points []es.MetricPoint) {
+	s.mu.RLock()
+	task, ok := s.tasks[detectorID.String()]
+	s.mu.RUnlock()
+	if !ok {
+		return
+	}
+
+	task.anomalies.AddBands(points)
+}
+
+// Stop, cancels all in-flight checks and blocks until scheduler and worker done
 func (s *Scheduler) Stop() {
 	if !s.IsRunning() {
 		return
 	}
-	s.mu.Lock()
-	defer s.mu.Unlock()
 
+	s.mu.RLock()
+	s.cancel()
+	s.mu.RUnlock()
+
+	// the lock must not be held here, the scheduler loop needs it to receive the stop signal
 	s.stop <- struct{}{}
 	s.schedulerWg.Wait()
 	s.workerWg.Wait()
+
+	s.mu.Lock()
 	s.schedulerRunning = false
+	s.mu.Unlock()
+}
+
+// checkTimeout returns the timeout of the detector,
+// limited by the max timeout of the scheduler
+func (s *Scheduler) checkTimeout(d *es.Detector) time.Duration {
+	timeout := time.Duration(d.Timeout)
+	if timeout <= 0 {
+		timeout = s.DefaultTimeout
+	}
+
+	if s.MaxTimeout > 0 && timeout > s.MaxTimeout {
+		timeout = s.MaxTimeout
+	}
+
+	return timeout
 }
 
 func (s *Scheduler) IsRunning() bool {
@@ -247,42 +490,113 @@ func (s *Scheduler) worker(i int) {
 	s.log.Infof("Start worker '%d'", i)
 	for t := range s.taskPool {
 		t.runCheck()
-		t.firstRun = false
 	}
 	s.log.Infof("worker '%d' done", i)
 }
 
+// CheckNow schedules the check for the next tick, it runs on a worker
 func (t *executor) CheckNow() error {
 	t.mu.Lock()
-	defer t.mu.Unlock()
-	t.runCheck()
+	t.lastRun = time.Time{}
+	t.mu.Unlock()
 	return nil
 }
 
+// runCheck runs the check and processes the result. It's skipped, if a check
+// of the executor is already running, the running check provides the result.
 func (t *executor) runCheck() {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	if !t.running.TryLock() {
+		t.sched.log.Debugw("check is already running", logger.Str("detector_id", t.id))
+		return
+	}
+	defer func() {
+		t.firstRun = false
+		t.running.Unlock()
+	}()
+
+	detector := t.checker.Detector()
+	timeout := t.sched.checkTimeout(detector)
+
+	ctx, cancel := context.WithTimeout(t.ctx, timeout)
 	defer cancel()
 
-	result := t.checker.Check(ctx)
+	// cancel the check also if the scheduler stops
+	t.sched.mu.RLock()
+	stop := context.AfterFunc(t.sched.ctx, cancel)
+	t.sched.mu.RUnlock()
+	defer stop()
+
+	// the check runs in its own goroutine, so a checker which
+	// does not respect the context can't block the worker
+	resultCh := make(chan *es.Result, 1)
+	go func() {
+		resultCh <- t.checker.Check(ctx)
+	}()
+
+	var result *es.Result
+	select {
+	case result = <-resultCh:
+	case <-ctx.Done():
+	}
+
+	switch {
+	case errors.Is(ctx.Err(), context.Canceled):
+		t.sched.log.Debugw("check canceled", logger.Str("detector_id", t.id))
+		return
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		result = es.NewTimeoutResult(timeout)
+	}
+
 	if result.Error() != nil {
 		t.sched.log.Errorf("check failed: %v", result.Error())
 	}
 
-	t.processResult(result, t.checker.Detector())
+	t.processResult(result, detector)
 }
 
-// processResul
+// processResult updates the detector state, writes the metrics,
+// sends notifications and publishes the result
 func (t *executor) processResult(result *es.Result, detector *es.Detector) error {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	ctx, cancel := context.WithTimeout(context.Background(), t.sched.ProcessTimeout)
 	defer cancel()
 
 	result.Host = detector.HostName
 	result.Detector = detector.Name
 
+	t.thresholds.Evaluate(result)
+	t.anomalies.Evaluate(result)
 	t.history.AddResult(result)
+	t.mu.Lock()
+	t.retrying = t.history.IsSoft()
+	t.mu.Unlock()
+
+	if t.history.HardStateChanged() || t.history.FlappingChanged() {
+		t.sched.addStateChange(ctx, detector, t.history.HardState, result.Message, result.Flapping)
+	}
+
+	// the start of the problem, for the duration of a recovery
+	problemSince := detector.StateChangedAt
+	if t.history.HardStateChanged() {
+		detector.StateChangedAt = time.Now()
+	}
+
+	if t.sched.HistoryService != nil {
+		err := t.sched.HistoryService.AddResult(ctx, es.NewResultLog(detector, result))
+		if err != nil {
+			t.sched.log.Errorf("failed to add result to history: %v", err)
+		}
+	}
 
 	detector.LastCheckedAt = time.Now()
 	detector.State = result.State
+	detector.StateType = t.history.StateType
+	detector.HardState = t.history.HardState
+	detector.Attempt = t.history.Attempt
+	detector.Flapping = result.Flapping
+	detector.FlapPercent = result.FlapPercent
+	inMaintenance := detector.InMaintenance
+	detector.InMaintenance = t.sched.Maintenance.Active(detector, time.Now()) != nil
+	maintenanceEnded := inMaintenance && !detector.InMaintenance
 	err := t.sched.detectorService.Update(ctx, detector)
 	if err != nil {
 		t.sched.log.Errorf("failed to update detector after check: %v", err)
@@ -295,20 +609,101 @@ func (t *executor) processResult(result *es.Result, detector *es.Detector) error
 		}
 	}
 
-	if t.shouldNotify(result) {
-		t.lastMail = time.Now()
-		err = t.sched.notifier.Send(ctx, result)
+	acknowledged := false
+	if t.sched.Incidents != nil && t.history.HardStateChanged() {
+		unresolved, err := t.sched.Incidents.OnStateChange(ctx, detector, result)
+		if err != nil {
+			t.sched.log.Errorf("failed to update incident: %v", err)
+		} else if unresolved != nil {
+			result.IncidentID = unresolved.ID.String()
+			acknowledged = unresolved.IsAcknowledged()
+		}
+	}
+
+	now := time.Now()
+	notification, ok := t.notification(result)
+	persisting := false
+	if ok {
+		t.reminders = 0
+	} else if maintenanceEnded && t.problemPersists() {
+		// the problem was suppressed during the maintenance window, it's notified after the window
+		notification, ok, persisting = es.NotificationProblem, true, true
+		t.reminders = 0
+	} else if t.reminderDue(detector, now) {
+		notification, ok, persisting = es.NotificationReminder, true, true
+	}
+
+	// the unresolved incident of a persisting problem wasn't changed by this result
+	if persisting && t.sched.Incidents != nil {
+		unresolved, err := t.sched.Incidents.Unresolved(ctx, detector.ID)
+		if err != nil {
+			t.sched.log.Errorf("failed to get incident of the problem: %v", err)
+		} else if unresolved != nil {
+			result.IncidentID = unresolved.ID.String()
+			acknowledged = unresolved.IsAcknowledged()
+		}
+	}
+
+	problem := notification == es.NotificationProblem || notification == es.NotificationReminder
+
+	// an acknowledged incident is not notified again until it's resolved
+	if acknowledged && problem {
+		ok = false
+	}
+
+	// the problem is notified by the levels of the escalation policy
+	if result.IncidentID != "" && problem && t.sched.Escalations.Covers(detector) {
+		ok = false
+	}
+
+	// the checks are running during a maintenance window, but nothing is notified
+	if detector.InMaintenance {
+		ok = false
+	}
+
+	// a suppressed reminder restarts the renotify interval
+	if !ok && notification == es.NotificationReminder {
+		t.lastMail = now
+	}
+
+	if ok {
+		if notification == es.NotificationReminder {
+			t.reminders++
+			result.Reminder = t.reminders
+			result.Elapsed = es.Duration(now.Sub(detector.StateChangedAt))
+		}
+
+		if notification == es.NotificationRecovery {
+			result.PreviousState = t.history.PreviousHardState()
+			if problemSince.After(dateutils.YearOne) {
+				result.Elapsed = es.Duration(now.Sub(problemSince))
+			}
+		}
+
+		result.Notification = notification
+		result.DetectorID = detector.ID.String()
+		result.Tags = detector.Tags
+		t.lastMail = now
+		if t.sched.Routing != nil {
+			err = t.sched.Routing.Notify(ctx, detector, result)
+		} else {
+			err = t.sched.notifier.Send(ctx, result)
+		}
 		if err != nil {
 			t.sched.log.Errorf("failed to send notifications: %v", err)
 		}
 	}
 
 	eventPayload := es.ResultEvent{
-		HostID:       detector.HostID.String(),
-		HostName:     detector.Name,
-		DetectorID:   detector.ID.String(),
-		DetectorName: detector.Name,
-		CheckResult:  result,
+		HostID:        detector.HostID.String(),
+		HostName:      detector.Name,
+		DetectorID:    detector.ID.String(),
+		DetectorName:  detector.Name,
+		StateType:     result.StateType,
+		Attempt:       result.Attempt,
+		MaxAttempts:   t.history.MaxAttempts,
+		InMaintenance: detector.InMaintenance,
+		CheckResult:   result,
 	}
 
 	payload, err := json.Marshal(eventPayload)
@@ -325,14 +720,69 @@ func (t *executor) processResult(result *es.Result, detector *es.Detector) error
 	return nil
 }
 
-func (e *executor) shouldNotify(r *es.Result) bool {
-	if e.firstRun && r.State != es.StateOK {
-		return true
+// interval returns the retry interval while the state is SOFT,
+// otherwise the regular check interval.
+// The caller must hold the lock.
+func (e *executor) interval() time.Duration {
+	retryInterval := time.Duration(e.checker.Detector().RetryInterval)
+	if e.retrying && retryInterval > 0 {
+		return retryInterval
+	}
+	return e.checker.Interval()
+}
+
+// problemPersists reports if the hard state is a problem, which isn't flapping
+func (e *executor) problemPersists() bool {
+	if e.history.IsFlapping() {
+		return false
+	}
+	return e.history.HardState == es.StateWarn || e.history.HardState == es.StateCritical
+}
+
+// reminderDue reports if a reminder is due, because the problem persists longer
+// than the renotify interval since the last notification
+func (e *executor) reminderDue(d *es.Detector, now time.Time) bool {
+	interval := time.Duration(d.RenotifyInterval)
+	if interval <= 0 || !e.problemPersists() {
+		return false
+	}
+
+	if d.RenotifyMax > 0 && e.reminders >= d.RenotifyMax {
+		return false
+	}
+
+	// after a restart, the interval starts with the state change
+	last := e.lastMail
+	if d.StateChangedAt.After(last) {
+		last = d.StateChangedAt
+	}
+
+	return now.Sub(last) >= interval
+}
+
+// notification returns the type of notification to send for the result.
+// A notification is sent if the hard state has changed or the flapping started or stopped.
+// While a detector is flapping, state changes are not notified.
+// The activation of a detector with an OK state is not notified.
+func (e *executor) notification(r *es.Result) (es.NotificationType, bool) {
+	if e.history.FlappingChanged() {
+		if e.history.IsFlapping() {
+			return es.NotificationFlappingStart, true
+		}
+		return es.NotificationFlappingStop, true
+	}
+
+	if e.history.IsFlapping() || !e.history.HardStateChanged() {
+		return "", false
+	}
+
+	if e.history.PreviousHardState() == es.StateInactive && r.State == es.StateOK {
+		return "", false
 	}
 
-	if !e.firstRun && e.history.StateChanged() {
-		return true
+	if r.State == es.StateOK {
+		return es.NotificationRecovery, true
 	}
 
-	return false
+	return es.NotificationProblem, true
 }
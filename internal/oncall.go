@@ -0,0 +1,347 @@
+package echosight
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/alexjoedt/echosight/internal/validator"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// maxShiftRange limits the time range for the calculation of shifts
+const maxShiftRange time.Duration = time.Hour * 24 * 366
+
+type RotationType string
+
+const (
+	RotationDaily  RotationType = "daily"
+	RotationWeekly RotationType = "weekly"
+)
+
+// days returns the length of a shift in days
+func (rt RotationType) days() int {
+	if rt == RotationWeekly {
+		return 7
+	}
+	return 1
+}
+
+// ClockRange is a daily time range in the timezone of the schedule, e.g. 18:00 - 08:00.
+// A range with End before Start lasts over midnight.
+type ClockRange struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// parse returns the start and end as duration since midnight
+func (cr ClockRange) parse() (time.Duration, time.Duration, error) {
+	start, err := parseClock(cr.Start)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	end, err := parseClock(cr.End)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return start, end, nil
+}
+
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// contains reports if the local time is within the range
+func (cr ClockRange) contains(t time.Time) bool {
+	start, end, err := cr.parse()
+	if err != nil {
+		return false
+	}
+
+	clock := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+	if start <= end {
+		return clock >= start && clock < end
+	}
+	return clock >= start || clock < end
+}
+
+// OnCallLayer is a rotation of the participants.
+// The shifts are handed off daily or weekly at the local time of Start,
+// e.g. every monday at 09:00.
+type OnCallLayer struct {
+	Name     string       `json:"name"`
+	Rotation RotationType `json:"rotation"`
+	// Start is the first handoff
+	Start time.Time `json:"start"`
+	// Participants are the ids of the recipients in the order of the rotation
+	Participants []uuid.UUID `json:"participants"`
+	// Restriction limits the layer to a daily time range, e.g. out of office hours
+	Restriction *ClockRange `json:"restriction,omitempty"`
+}
+
+// shift returns the number of the shift at t and its time range
+func (l *OnCallLayer) shift(t time.Time, loc *time.Location) (int, TimeRange) {
+	start := l.Start.In(loc)
+	days := l.Rotation.days()
+
+	n := int(t.Sub(start) / (time.Duration(days) * 24 * time.Hour))
+	// the estimation differs on DST changes
+	for n > 0 && start.AddDate(0, 0, n*days).After(t) {
+		n--
+	}
+	for !start.AddDate(0, 0, (n+1)*days).After(t) {
+		n++
+	}
+
+	return n, TimeRange{
+		From: start.AddDate(0, 0, n*days),
+		To:   start.AddDate(0, 0, (n+1)*days),
+	}
+}
+
+// OnCallAt returns the participant of the layer at t
+func (l *OnCallLayer) OnCallAt(t time.Time, loc *time.Location) (uuid.UUID, bool) {
+	if len(l.Participants) == 0 || t.Before(l.Start) {
+		return uuid.Nil, false
+	}
+
+	if l.Restriction != nil && !l.Restriction.contains(t.In(loc)) {
+		return uuid.Nil, false
+	}
+
+	n, _ := l.shift(t, loc)
+	return l.Participants[n%len(l.Participants)], true
+}
+
+// boundaries returns the handoffs and restriction changes within [from, to)
+func (l *OnCallLayer) boundaries(from time.Time, to time.Time, loc *time.Location) []time.Time {
+	var times []time.Time
+	if !l.Start.Before(from) && l.Start.Before(to) {
+		times = append(times, l.Start)
+	}
+
+	if from.Before(l.Start) {
+		from = l.Start
+	}
+
+	if !from.Before(to) {
+		return times
+	}
+
+	_, tr := l.shift(from, loc)
+	for handoff := tr.To; handoff.Before(to); handoff = handoff.AddDate(0, 0, l.Rotation.days()) {
+		times = append(times, handoff)
+	}
+
+	if l.Restriction != nil {
+		start, end, err := l.Restriction.parse()
+		if err != nil {
+			return times
+		}
+
+		local := from.In(loc)
+		day := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+		for ; day.Before(to); day = day.AddDate(0, 0, 1) {
+			for _, clock := range []time.Duration{start, end} {
+				// wall clock time, the duration since midnight differs on DST changes
+				t := time.Date(day.Year(), day.Month(), day.Day(), int(clock/time.Hour), int(clock%time.Hour/time.Minute), 0, 0, loc)
+				if !t.Before(from) && t.Before(to) {
+					times = append(times, t)
+				}
+			}
+		}
+	}
+
+	return times
+}
+
+// OnCallSchedule is a schedule of layered rotations.
+// A later layer takes precedence over the previous layers, if it has a participant at the time.
+// Overrides take precedence over all layers.
+type OnCallSchedule struct {
+	bun.BaseModel `bun:"table:oncall_schedules"`
+	ID            uuid.UUID     `json:"id" bun:"type:uuid,pk,default:uuid_generate_v4()"`
+	LookupVersion int           `json:"lookupVersion" bun:",default:1"`
+	Name          string        `json:"name"`
+	Description   string        `json:"description"`
+	Timezone      string        `json:"timezone"`
+	Layers        []OnCallLayer `json:"layers" bun:"type:jsonb"`
+	// FallbackRecipientID is notified if nobody is on call, optional
+	FallbackRecipientID *uuid.UUID `json:"fallbackRecipientId" bun:"type:uuid"`
+
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+func (s *OnCallSchedule) location() *time.Location {
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// OnCallOverride replaces the on-call of a schedule within the time range,
+// e.g. during the vacation of a participant
+type OnCallOverride struct {
+	bun.BaseModel `bun:"table:oncall_overrides"`
+	ID            uuid.UUID  `json:"id" bun:"type:uuid,pk,default:uuid_generate_v4()"`
+	ScheduleID    uuid.UUID  `json:"scheduleId" bun:"type:uuid"`
+	RecipientID   uuid.UUID  `json:"recipientId" bun:"type:uuid"`
+	StartsAt      time.Time  `json:"startsAt"`
+	EndsAt        time.Time  `json:"endsAt"`
+	CreatedBy     *uuid.UUID `json:"createdBy,omitempty" bun:"type:uuid"`
+	CreatedAt     time.Time  `json:"createdAt"`
+}
+
+func (o *OnCallOverride) activeAt(t time.Time) bool {
+	return !t.Before(o.StartsAt) && t.Before(o.EndsAt)
+}
+
+// OnCallShift is a time range with the on-call recipient of a schedule
+type OnCallShift struct {
+	ScheduleID   uuid.UUID `json:"scheduleId"`
+	ScheduleName string    `json:"scheduleName"`
+	RecipientID  uuid.UUID `json:"recipientId"`
+	// Layer is the name of the layer or empty for an override
+	Layer      string     `json:"layer,omitempty"`
+	OverrideID *uuid.UUID `json:"overrideId,omitempty"`
+	From       time.Time  `json:"from"`
+	To         time.Time  `json:"to"`
+}
+
+// OnCallAt returns the shift at t or nil if nobody is on call.
+// The overrides must belong to the schedule, the shift is limited to t.
+func (s *OnCallSchedule) OnCallAt(t time.Time, overrides []*OnCallOverride) *OnCallShift {
+	// the latest override wins
+	for i := len(overrides) - 1; i >= 0; i-- {
+		o := overrides[i]
+		if o.activeAt(t) {
+			id := o.ID
+			return &OnCallShift{
+				ScheduleID:   s.ID,
+				ScheduleName: s.Name,
+				RecipientID:  o.RecipientID,
+				OverrideID:   &id,
+				From:         t,
+				To:           t,
+			}
+		}
+	}
+
+	loc := s.location()
+	for i := len(s.Layers) - 1; i >= 0; i-- {
+		layer := &s.Layers[i]
+		if recipientID, ok := layer.OnCallAt(t, loc); ok {
+			return &OnCallShift{
+				ScheduleID:   s.ID,
+				ScheduleName: s.Name,
+				RecipientID:  recipientID,
+				Layer:        layer.Name,
+				From:         t,
+				To:           t,
+			}
+		}
+	}
+
+	return nil
+}
+
+// Shifts returns the final on-call shifts within [from, to) including the overrides.
+// The overrides must belong to the schedule and be sorted by creation.
+func (s *OnCallSchedule) Shifts(from time.Time, to time.Time, overrides []*OnCallOverride) []*OnCallShift {
+	if !from.Before(to) {
+		return nil
+	}
+
+	if to.Sub(from) > maxShiftRange {
+		to = from.Add(maxShiftRange)
+	}
+
+	loc := s.location()
+	times := []time.Time{from}
+	for i := range s.Layers {
+		times = append(times, s.Layers[i].boundaries(from, to, loc)...)
+	}
+
+	for _, o := range overrides {
+		for _, t := range []time.Time{o.StartsAt, o.EndsAt} {
+			if t.After(from) && t.Before(to) {
+				times = append(times, t)
+			}
+		}
+	}
+
+	sort.Slice(times, func(i, j int) bool {
+		return times[i].Before(times[j])
+	})
+	times = slices.CompactFunc(times, func(a, b time.Time) bool {
+		return a.Equal(b)
+	})
+	times = append(times, to)
+
+	var shifts []*OnCallShift
+	for i := 0; i < len(times)-1; i++ {
+		shift := s.OnCallAt(times[i], overrides)
+		if shift == nil {
+			continue
+		}
+		shift.From = times[i]
+		shift.To = times[i+1]
+
+		// merge with the previous shift of the same participant and source
+		if n := len(shifts); n > 0 {
+			last := shifts[n-1]
+			if last.To.Equal(shift.From) && last.RecipientID == shift.RecipientID &&
+				last.Layer == shift.Layer && sameOverride(last.OverrideID, shift.OverrideID) {
+				last.To = shift.To
+				continue
+			}
+		}
+
+		shifts = append(shifts, shift)
+	}
+
+	return shifts
+}
+
+func sameOverride(a *uuid.UUID, b *uuid.UUID) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func ValidateOnCallSchedule(v *validator.Validator, s *OnCallSchedule) {
+	v.Check(len(s.Name) > 3, "name", "name too short")
+	_, err := time.LoadLocation(s.Timezone)
+	v.Check(err == nil, "timezone", "invalid timezone")
+	v.Check(len(s.Layers) > 0, "layers", "at least one layer must be provided")
+
+	for i, layer := range s.Layers {
+		key := fmt.Sprintf("layers[%d]", i)
+		v.Check(validator.PermittedValue(layer.Rotation, RotationDaily, RotationWeekly), key+".rotation", "must be daily or weekly")
+		v.Check(!layer.Start.IsZero(), key+".start", "must be provided")
+		v.Check(len(layer.Participants) > 0, key+".participants", "at least one participant must be provided")
+
+		if layer.Restriction != nil {
+			_, _, err := layer.Restriction.parse()
+			v.Check(err == nil, key+".restriction", "start and end must be in the format HH:MM")
+			v.Check(layer.Restriction.Start != layer.Restriction.End, key+".restriction", "start and end must differ")
+		}
+	}
+}
+
+func ValidateOnCallOverride(v *validator.Validator, o *OnCallOverride) {
+	v.Check(o.RecipientID != uuid.Nil, "recipientId", "must be provided")
+	v.Check(!o.StartsAt.IsZero(), "startsAt", "must be provided")
+	v.Check(o.EndsAt.After(o.StartsAt), "endsAt", "must be after startsAt")
+}
@@ -0,0 +1,51 @@
+package oncall
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	es "github.com/alexjoedt/echosight/internal"
+)
+
+const icalTimeFormat = "20060102T150405Z"
+
+var icalEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`)
+
+// WriteICal writes the shifts as iCalendar (RFC 5545) with one event per shift
+func WriteICal(w io.Writer, name string, shifts []*es.OnCallShift) error {
+	bw := bufio.NewWriter(w)
+	line := func(format string, args ...any) {
+		fmt.Fprintf(bw, format+"\r\n", args...)
+	}
+
+	now := time.Now().UTC().Format(icalTimeFormat)
+
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//EchoSight//On-Call//EN")
+	line("CALSCALE:GREGORIAN")
+	line("X-WR-CALNAME:%s", icalEscaper.Replace(name))
+
+	for _, shift := range shifts {
+		summary := "On call: " + shift.ScheduleName
+		if shift.OverrideID != nil {
+			summary += " (override)"
+		} else if shift.Layer != "" {
+			summary += " (" + shift.Layer + ")"
+		}
+
+		line("BEGIN:VEVENT")
+		line("UID:%s-%s-%d@echosight", shift.ScheduleID, shift.RecipientID, shift.From.Unix())
+		line("DTSTAMP:%s", now)
+		line("DTSTART:%s", shift.From.UTC().Format(icalTimeFormat))
+		line("DTEND:%s", shift.To.UTC().Format(icalTimeFormat))
+		line("SUMMARY:%s", icalEscaper.Replace(summary))
+		line("END:VEVENT")
+	}
+
+	line("END:VCALENDAR")
+	return bw.Flush()
+}
@@ -0,0 +1,120 @@
+package oncall
+
+import (
+	"context"
+	"time"
+
+	es "github.com/alexjoedt/echosight/internal"
+	"github.com/alexjoedt/echosight/internal/filter"
+	"github.com/alexjoedt/echosight/internal/logger"
+	"github.com/google/uuid"
+)
+
+// currentRange limits the search for the end of the current shift
+const currentRange time.Duration = time.Hour * 24 * 31
+
+// Resolver calculates the on-call shifts of the schedules
+type Resolver struct {
+	service es.OnCallService
+	log     *logger.Logger
+}
+
+func NewResolver(service es.OnCallService) *Resolver {
+	return &Resolver{
+		service: service,
+		log:     logger.New("OnCall-Resolver"),
+	}
+}
+
+// Current returns the on-call shift of the schedule at t or nil if nobody is on call.
+// The shift ends at the next handoff, but starts at t.
+func (r *Resolver) Current(ctx context.Context, schedule *es.OnCallSchedule, t time.Time) (*es.OnCallShift, error) {
+	shifts, err := r.Shifts(ctx, schedule, t, t.Add(currentRange))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(shifts) == 0 || !shifts[0].From.Equal(t) {
+		return nil, nil
+	}
+	return shifts[0], nil
+}
+
+// Shifts returns the final shifts of the schedule within [from, to) including the overrides
+func (r *Resolver) Shifts(ctx context.Context, schedule *es.OnCallSchedule, from time.Time, to time.Time) ([]*es.OnCallShift, error) {
+	f := filter.NewDefaultOnCallFilter()
+	f.ScheduleID = &schedule.ID
+	f.From = &from
+	f.To = &to
+
+	overrides, err := r.service.ListOverrides(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+
+	return schedule.Shifts(from, to, overrides), nil
+}
+
+// RecipientShifts returns the shifts of the recipient in all schedules within [from, to)
+func (r *Resolver) RecipientShifts(ctx context.Context, recipientID uuid.UUID, from time.Time, to time.Time) ([]*es.OnCallShift, error) {
+	schedules, err := r.service.List(ctx, filter.NewDefaultOnCallFilter())
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*es.OnCallShift
+	for _, schedule := range schedules {
+		shifts, err := r.Shifts(ctx, schedule, from, to)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, shift := range shifts {
+			if shift.RecipientID == recipientID {
+				result = append(result, shift)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// Recipients returns the ids of the recipients which are on call now for the schedules.
+// The fallback recipient of a schedule is returned, if nobody is on call.
+// Unknown schedules and schedules without on-call and fallback are skipped.
+func (r *Resolver) Recipients(ctx context.Context, scheduleIDs []uuid.UUID) ([]uuid.UUID, error) {
+	if r == nil {
+		return nil, nil
+	}
+
+	now := time.Now()
+	var recipients []uuid.UUID
+	for _, id := range scheduleIDs {
+		schedule, err := r.service.GetByID(ctx, id)
+		if err != nil {
+			if es.ErrorCode(err) == es.ENOTFOUND {
+				r.log.Warnf("on-call schedule %s not found", id)
+				continue
+			}
+			return nil, err
+		}
+
+		shift, err := r.Current(ctx, schedule, now)
+		if err != nil {
+			return nil, err
+		}
+
+		if shift == nil {
+			if schedule.FallbackRecipientID != nil {
+				recipients = append(recipients, *schedule.FallbackRecipientID)
+				continue
+			}
+			r.log.Warnf("nobody is on call for schedule %s", schedule.Name)
+			continue
+		}
+
+		recipients = append(recipients, shift.RecipientID)
+	}
+
+	return recipients, nil
+}
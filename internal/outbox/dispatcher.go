@@ -0,0 +1,225 @@
+package outbox
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	es "github.com/alexjoedt/echosight/internal"
+	"github.com/alexjoedt/echosight/internal/logger"
+	"github.com/alexjoedt/echosight/internal/notify"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultWorkers      int           = 3
+	defaultPollInterval time.Duration = time.Second * 5
+	defaultMaxAttempts  int           = 8
+	defaultBaseBackoff  time.Duration = time.Second * 30
+	defaultMaxBackoff   time.Duration = time.Hour
+	defaultLease        time.Duration = time.Minute * 2
+	deliverTimeout      time.Duration = time.Minute
+)
+
+var _ notify.Outbox = (*Dispatcher)(nil)
+
+// Dispatcher persists the notifications and delivers them with a pool of workers.
+//
+// Failed deliveries are retried with an exponential backoff until MaxAttempts
+// is reached, then the notification is dead and can be resent manually.
+// A claimed notification is leased, if the server stops during the delivery,
+// it's delivered again after the lease expired.
+type Dispatcher struct {
+	notifications es.NotificationService
+	notifier      *notify.Notifier
+	log           *logger.Logger
+
+	queue  chan *es.Notification
+	wake   chan struct{}
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	// Workers is the number of concurrent deliveries
+	Workers int
+	// PollInterval between two polls for due notifications
+	PollInterval time.Duration
+	// MaxAttempts until a notification is dead
+	MaxAttempts int
+	// BaseBackoff is the delay after the first failed attempt, it's doubled with every attempt
+	BaseBackoff time.Duration
+	// MaxBackoff limits the delay between two attempts
+	MaxBackoff time.Duration
+	// Lease is the time a claimed notification is reserved for the delivery
+	Lease time.Duration
+}
+
+func NewDispatcher(ns es.NotificationService, n *notify.Notifier) *Dispatcher {
+	return &Dispatcher{
+		notifications: ns,
+		notifier:      n,
+		log:           logger.New("Outbox"),
+		wake:          make(chan struct{}, 1),
+		Workers:       defaultWorkers,
+		PollInterval:  defaultPollInterval,
+		MaxAttempts:   defaultMaxAttempts,
+		BaseBackoff:   defaultBaseBackoff,
+		MaxBackoff:    defaultMaxBackoff,
+		Lease:         defaultLease,
+	}
+}
+
+// Enqueue stores the notification of the result for the sender
+func (d *Dispatcher) Enqueue(ctx context.Context, senderID string, result *es.Result) error {
+	notification := es.NewNotification(senderID, result, d.MaxAttempts)
+	if err := d.notifications.Create(ctx, notification); err != nil {
+		return err
+	}
+
+	d.notify()
+	return nil
+}
+
+// Resend schedules a delivered or dead notification for a new delivery
+// with MaxAttempts further attempts
+func (d *Dispatcher) Resend(ctx context.Context, id uuid.UUID) (*es.Notification, error) {
+	notification, err := d.notifications.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if notification.Status == es.DeliveryPending {
+		return nil, es.ErrConflictf("notification is already pending")
+	}
+
+	notification.Status = es.DeliveryPending
+	notification.NextAttemptAt = time.Now()
+	notification.MaxAttempts = notification.Attempts + d.MaxAttempts
+	notification.DeliveredAt = nil
+
+	if err := d.notifications.Update(ctx, notification); err != nil {
+		return nil, err
+	}
+
+	d.notify()
+	return notification, nil
+}
+
+// notify wakes up the poll loop
+func (d *Dispatcher) notify() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Start delivers the due notifications in the background until Stop is called
+func (d *Dispatcher) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	d.cancel = cancel
+	d.queue = make(chan *es.Notification)
+
+	for i := 0; i < d.Workers; i++ {
+		d.wg.Add(1)
+		go d.worker()
+	}
+
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		defer close(d.queue)
+
+		ticker := time.NewTicker(d.PollInterval)
+		defer ticker.Stop()
+
+		for {
+			d.dispatch(ctx)
+
+			select {
+			case <-ticker.C:
+			case <-d.wake:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Stop blocks until the running deliveries are done
+func (d *Dispatcher) Stop() {
+	if d.cancel == nil {
+		return
+	}
+	d.cancel()
+	d.wg.Wait()
+}
+
+// dispatch claims the due notifications and passes them to the workers
+func (d *Dispatcher) dispatch(ctx context.Context) {
+	for {
+		notifications, err := d.notifications.Claim(ctx, time.Now(), d.Workers, d.Lease)
+		if err != nil {
+			if ctx.Err() == nil {
+				d.log.Errorf("failed to claim notifications: %v", err)
+			}
+			return
+		}
+
+		for _, notification := range notifications {
+			select {
+			case d.queue <- notification:
+			case <-ctx.Done():
+				// the lease expires and the notification is claimed after a restart
+				return
+			}
+		}
+
+		if len(notifications) < d.Workers {
+			return
+		}
+	}
+}
+
+func (d *Dispatcher) worker() {
+	defer d.wg.Done()
+	for notification := range d.queue {
+		d.deliver(notification)
+	}
+}
+
+// deliver sends the notification and records the attempt
+func (d *Dispatcher) deliver(notification *es.Notification) {
+	ctx, cancel := context.WithTimeout(context.Background(), deliverTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := d.notifier.Deliver(ctx, notification.Channel, notification.Result())
+	now := time.Now()
+
+	attempt := &es.DeliveryAttempt{
+		NotificationID: notification.ID,
+		Attempt:        notification.Attempts + 1,
+		Success:        err == nil,
+		Duration:       es.Duration(now.Sub(start)),
+	}
+
+	if err != nil {
+		attempt.Error = err.Error()
+		notification.Failed(err, now, d.BaseBackoff, d.MaxBackoff)
+		if notification.Status == es.DeliveryDead {
+			d.log.Errorc("notification is dead", err, logger.UUID("notification_id", notification.ID), logger.Str("channel", notification.Channel))
+		} else {
+			d.log.Warnf("failed to deliver notification %s via %s: %v", notification.ID, notification.Channel, err)
+		}
+	} else {
+		notification.Delivered(now)
+		d.log.Debugw("notification delivered", logger.UUID("notification_id", notification.ID), logger.Str("channel", notification.Channel))
+	}
+
+	if err := d.notifications.AddAttempt(ctx, attempt); err != nil {
+		d.log.Errorf("failed to record delivery attempt: %v", err)
+	}
+
+	if err := d.notifications.Update(ctx, notification); err != nil {
+		d.log.Errorf("failed to update notification %s: %v", notification.ID, err)
+	}
+}
@@ -113,6 +113,10 @@ func (m *DetectorModel) List(ctx context.Context, detectorFilter *filter.Detecto
 		query.Where("active = ?", *detectorFilter.Active)
 	}
 
+	if detectorFilter.Tag != nil {
+		query.Where("? = ANY(tags)", *detectorFilter.Tag)
+	}
+
 	count, err := query.
 		Limit(detectorFilter.Limit()).
 		Offset(detectorFilter.Offset()).
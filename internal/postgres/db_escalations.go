@@ -0,0 +1,191 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	es "github.com/alexjoedt/echosight/internal"
+	"github.com/alexjoedt/echosight/internal/filter"
+	"github.com/alexjoedt/echosight/internal/logger"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+var _ es.EscalationService = (*EscalationModel)(nil)
+
+type EscalationModel struct {
+	db  *bun.DB
+	log *logger.Logger
+}
+
+func (m *EscalationModel) CreatePolicy(ctx context.Context, policy *es.EscalationPolicy) error {
+	policy.CreatedAt = time.Now()
+	_, err := m.db.NewInsert().
+		Model(policy).
+		Exec(ctx)
+	if err != nil {
+		m.log.Errorc("failed to insert escalation policy", err)
+		return es.ErrInternalf("failed to insert escalation policy").WithError(err)
+	}
+
+	return nil
+}
+
+func (m *EscalationModel) GetPolicyByID(ctx context.Context, id uuid.UUID) (*es.EscalationPolicy, error) {
+	policy := new(es.EscalationPolicy)
+	err := m.db.NewSelect().Model(policy).
+		Where("id = ?", id).Scan(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, es.ErrNotfoundf("no escalation policy found")
+		}
+		m.log.Errorc("failed to get escalation policy by id", err, logger.UUID("policy_id", id))
+		return nil, es.ErrInternalf("failed to get escalation policy by id").WithError(err)
+	}
+
+	return policy, nil
+}
+
+func (m *EscalationModel) UpdatePolicy(ctx context.Context, policy *es.EscalationPolicy) error {
+	policy.UpdatedAt = time.Now()
+	lv := policy.LookupVersion
+	policy.LookupVersion++
+
+	res, err := m.db.NewUpdate().Model(policy).
+		Where("id = ? AND lookup_version = ?", policy.ID, lv).
+		Exec(ctx)
+	if err != nil {
+		m.log.Errorc("failed to update escalation policy", err, logger.UUID("policy_id", policy.ID))
+		return es.ErrInternalf("failed to update escalation policy").WithError(err)
+	}
+
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		policy.LookupVersion = lv
+		return es.ErrConflictf("escalation policy was changed in the meantime")
+	}
+
+	return nil
+}
+
+func (m *EscalationModel) DeletePolicyByID(ctx context.Context, id uuid.UUID) (*es.EscalationPolicy, error) {
+	policy := new(es.EscalationPolicy)
+	err := m.db.NewDelete().Model(policy).
+		Where("id = ?", id).
+		Returning("*").
+		Scan(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, es.ErrNotfoundf("no escalation policy found")
+		}
+		m.log.Errorc("failed to delete escalation policy", err, logger.UUID("policy_id", id))
+		return nil, es.ErrInternalf("failed to delete escalation policy").WithError(err)
+	}
+
+	return policy, nil
+}
+
+func (m *EscalationModel) ListPolicies(ctx context.Context, escalationFilter *filter.EscalationFilter) ([]*es.EscalationPolicy, error) {
+	policies := make([]*es.EscalationPolicy, 0)
+	query := m.db.NewSelect().Model(&policies)
+
+	if escalationFilter.Name != nil {
+		query.Where("name = ?", *escalationFilter.Name)
+	}
+
+	if escalationFilter.Active != nil {
+		query.Where("active = ?", *escalationFilter.Active)
+	}
+
+	count, err := query.
+		Limit(escalationFilter.Limit()).
+		Offset(escalationFilter.Offset()).
+		Order(escalationFilter.Order()).
+		ScanAndCount(ctx)
+	if err != nil {
+		m.log.Errorc("failed to list escalation policies", err)
+		return nil, es.ErrInternalf("failed to list escalation policies").WithError(err)
+	}
+
+	escalationFilter.Pagination = filter.ComputePagination(count, escalationFilter.Page, escalationFilter.PageSize)
+	return policies, nil
+}
+
+func (m *EscalationModel) CreateEscalation(ctx context.Context, escalation *es.Escalation) error {
+	escalation.UpdatedAt = time.Now()
+	_, err := m.db.NewInsert().
+		Model(escalation).
+		On("CONFLICT (policy_id, incident_id) DO NOTHING").
+		Exec(ctx)
+	if err != nil {
+		m.log.Errorc("failed to insert escalation", err, logger.UUID("incident_id", escalation.IncidentID))
+		return es.ErrInternalf("failed to insert escalation").WithError(err)
+	}
+
+	return nil
+}
+
+func (m *EscalationModel) UpdateEscalation(ctx context.Context, escalation *es.Escalation) error {
+	escalation.UpdatedAt = time.Now()
+	_, err := m.db.NewUpdate().Model(escalation).
+		WherePK().
+		Exec(ctx)
+	if err != nil {
+		m.log.Errorc("failed to update escalation", err, logger.UUID("escalation_id", escalation.ID))
+		return es.ErrInternalf("failed to update escalation").WithError(err)
+	}
+
+	return nil
+}
+
+func (m *EscalationModel) ListDue(ctx context.Context, now time.Time) ([]*es.Escalation, error) {
+	escalations := make([]*es.Escalation, 0)
+	err := m.db.NewSelect().Model(&escalations).
+		Where("status = ?", es.EscalationPending).
+		Where("next_at <= ?", now).
+		Order("next_at ASC").
+		Scan(ctx)
+	if err != nil {
+		m.log.Errorc("failed to list due escalations", err)
+		return nil, es.ErrInternalf("failed to list due escalations").WithError(err)
+	}
+
+	return escalations, nil
+}
+
+func (m *EscalationModel) ListPending(ctx context.Context, incidentID uuid.UUID) ([]*es.Escalation, error) {
+	escalations := make([]*es.Escalation, 0)
+	err := m.db.NewSelect().Model(&escalations).
+		Where("status = ?", es.EscalationPending).
+		Where("incident_id = ?", incidentID).
+		Scan(ctx)
+	if err != nil {
+		m.log.Errorc("failed to list pending escalations", err, logger.UUID("incident_id", incidentID))
+		return nil, es.ErrInternalf("failed to list pending escalations").WithError(err)
+	}
+
+	return escalations, nil
+}
+
+func (m *EscalationModel) ListEscalations(ctx context.Context, escalationFilter *filter.EscalationFilter) ([]*es.Escalation, error) {
+	escalations := make([]*es.Escalation, 0)
+	query := m.db.NewSelect().Model(&escalations)
+
+	if escalationFilter.IncidentID != nil {
+		query.Where("incident_id = ?", *escalationFilter.IncidentID)
+	}
+
+	count, err := query.
+		Limit(escalationFilter.Limit()).
+		Offset(escalationFilter.Offset()).
+		Order("started_at DESC").
+		ScanAndCount(ctx)
+	if err != nil {
+		m.log.Errorc("failed to list escalations", err)
+		return nil, es.ErrInternalf("failed to list escalations").WithError(err)
+	}
+
+	escalationFilter.Pagination = filter.ComputePagination(count, escalationFilter.Page, escalationFilter.PageSize)
+	return escalations, nil
+}
@@ -0,0 +1,123 @@
+package postgres
+
+import (
+	"context"
+
+	es "github.com/alexjoedt/echosight/internal"
+	"github.com/alexjoedt/echosight/internal/filter"
+	"github.com/alexjoedt/echosight/internal/logger"
+	"github.com/uptrace/bun"
+)
+
+var _ es.HistoryService = (*HistoryModel)(nil)
+
+const (
+	// defaultResultLogLimit is the max number of results kept per detector
+	defaultResultLogLimit int = 1000
+)
+
+type HistoryModel struct {
+	db  *bun.DB
+	log *logger.Logger
+
+	// ResultLimit is the max number of results kept per detector
+	ResultLimit int
+}
+
+func (m *HistoryModel) AddStateChange(ctx context.Context, change *es.StateChange) error {
+	_, err := m.db.NewInsert().Model(change).Exec(ctx)
+	if err != nil {
+		m.log.Errorc("failed to insert state change", err, logger.UUID("detector_id", change.DetectorID))
+		return es.ErrInternalf("failed to insert state change").WithError(err)
+	}
+
+	return nil
+}
+
+func (m *HistoryModel) ListStateChanges(ctx context.Context, historyFilter *filter.HistoryFilter) ([]*es.StateChange, error) {
+	changes := make([]*es.StateChange, 0)
+	query := m.db.NewSelect().Model(&changes)
+	applyHistoryFilter(query, historyFilter)
+
+	count, err := query.
+		Limit(historyFilter.Limit()).
+		Offset(historyFilter.Offset()).
+		Order(historyFilter.Order()).
+		ScanAndCount(ctx)
+	if err != nil {
+		m.log.Errorc("failed to list state changes", err)
+		return nil, es.ErrInternalf("failed to list state changes").WithError(err)
+	}
+
+	historyFilter.Pagination = filter.ComputePagination(count, historyFilter.Page, historyFilter.PageSize)
+	return changes, nil
+}
+
+// AddResult inserts the result and deletes the oldest results
+// which exceeds the result limit of the detector.
+func (m *HistoryModel) AddResult(ctx context.Context, result *es.ResultLog) error {
+	_, err := m.db.NewInsert().Model(result).Exec(ctx)
+	if err != nil {
+		m.log.Errorc("failed to insert result", err, logger.UUID("detector_id", result.DetectorID))
+		return es.ErrInternalf("failed to insert result").WithError(err)
+	}
+
+	limit := m.ResultLimit
+	if limit <= 0 {
+		limit = defaultResultLogLimit
+	}
+
+	keep := m.db.NewSelect().Model((*es.ResultLog)(nil)).
+		Column("id").
+		Where("detector_id = ?", result.DetectorID).
+		Order("created_at DESC").
+		Limit(limit)
+
+	_, err = m.db.NewDelete().Model((*es.ResultLog)(nil)).
+		Where("detector_id = ?", result.DetectorID).
+		Where("id NOT IN (?)", keep).
+		Exec(ctx)
+	if err != nil {
+		m.log.Errorc("failed to delete old results", err, logger.UUID("detector_id", result.DetectorID))
+		return es.ErrInternalf("failed to delete old results").WithError(err)
+	}
+
+	return nil
+}
+
+func (m *HistoryModel) ListResults(ctx context.Context, historyFilter *filter.HistoryFilter) ([]*es.ResultLog, error) {
+	results := make([]*es.ResultLog, 0)
+	query := m.db.NewSelect().Model(&results)
+	applyHistoryFilter(query, historyFilter)
+
+	count, err := query.
+		Limit(historyFilter.Limit()).
+		Offset(historyFilter.Offset()).
+		Order(historyFilter.Order()).
+		ScanAndCount(ctx)
+	if err != nil {
+		m.log.Errorc("failed to list results", err)
+		return nil, es.ErrInternalf("failed to list results").WithError(err)
+	}
+
+	historyFilter.Pagination = filter.ComputePagination(count, historyFilter.Page, historyFilter.PageSize)
+	return results, nil
+}
+
+func applyHistoryFilter(query *bun.SelectQuery, historyFilter *filter.HistoryFilter) {
+	if historyFilter.DetectorID != nil {
+		query.Where("detector_id = ?", *historyFilter.DetectorID)
+	}
+
+	if historyFilter.HostID != nil {
+		query.Where("host_id = ?", *historyFilter.HostID)
+	}
+
+	if historyFilter.From != nil {
+		query.Where("created_at >= ?", *historyFilter.From)
+	}
+
+	if historyFilter.To != nil {
+		query.Where("created_at < ?", *historyFilter.To)
+	}
+}
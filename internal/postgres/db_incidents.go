@@ -0,0 +1,143 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	es "github.com/alexjoedt/echosight/internal"
+	"github.com/alexjoedt/echosight/internal/filter"
+	"github.com/alexjoedt/echosight/internal/logger"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+var _ es.IncidentService = (*IncidentModel)(nil)
+
+type IncidentModel struct {
+	db  *bun.DB
+	log *logger.Logger
+}
+
+func (m *IncidentModel) Create(ctx context.Context, incident *es.Incident) error {
+	incident.CreatedAt = time.Now()
+	_, err := m.db.NewInsert().
+		Model(incident).
+		Exec(ctx)
+	if err != nil {
+		m.log.Errorc("failed to insert incident", err, logger.UUID("detector_id", incident.DetectorID))
+		return es.ErrInternalf("failed to insert incident").WithError(err)
+	}
+
+	return nil
+}
+
+// GetByID returns the incident including the timeline
+func (m *IncidentModel) GetByID(ctx context.Context, id uuid.UUID) (*es.Incident, error) {
+	incident := new(es.Incident)
+	err := m.db.NewSelect().Model(incident).
+		Relation("Timeline", func(q *bun.SelectQuery) *bun.SelectQuery {
+			return q.Order("created_at ASC")
+		}).
+		Where("incident.id = ?", id).
+		Scan(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, es.ErrNotfoundf("no incident found")
+		}
+		m.log.Errorc("failed to get incident by id", err, logger.UUID("incident_id", id))
+		return nil, es.ErrInternalf("failed to get incident by id").WithError(err)
+	}
+
+	return incident, nil
+}
+
+func (m *IncidentModel) GetUnresolved(ctx context.Context, detectorID uuid.UUID) (*es.Incident, error) {
+	incident := new(es.Incident)
+	err := m.db.NewSelect().Model(incident).
+		Where("detector_id = ?", detectorID).
+		Where("status <> ?", es.IncidentResolved).
+		Scan(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, es.ErrNotfoundf("no unresolved incident found")
+		}
+		m.log.Errorc("failed to get unresolved incident", err, logger.UUID("detector_id", detectorID))
+		return nil, es.ErrInternalf("failed to get unresolved incident").WithError(err)
+	}
+
+	return incident, nil
+}
+
+// Update updates the incident without the timeline.
+// It fails with a conflict, if the incident was changed in the meantime.
+func (m *IncidentModel) Update(ctx context.Context, incident *es.Incident) error {
+	incident.UpdatedAt = time.Now()
+	lv := incident.LookupVersion
+	incident.LookupVersion++
+
+	res, err := m.db.NewUpdate().Model(incident).
+		Where("id = ? AND lookup_version = ?", incident.ID, lv).
+		Exec(ctx)
+	if err != nil {
+		m.log.Errorc("failed to update incident", err, logger.UUID("incident_id", incident.ID))
+		return es.ErrInternalf("failed to update incident").WithError(err)
+	}
+
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		incident.LookupVersion = lv
+		return es.ErrConflictf("incident was changed in the meantime")
+	}
+
+	return nil
+}
+
+func (m *IncidentModel) List(ctx context.Context, incidentFilter *filter.IncidentFilter) ([]*es.Incident, error) {
+	incidents := make([]*es.Incident, 0)
+	query := m.db.NewSelect().Model(&incidents)
+
+	if incidentFilter.DetectorID != nil {
+		query.Where("detector_id = ?", *incidentFilter.DetectorID)
+	}
+
+	if incidentFilter.HostID != nil {
+		query.Where("host_id = ?", *incidentFilter.HostID)
+	}
+
+	if incidentFilter.AssigneeID != nil {
+		query.Where("assignee_id = ?", *incidentFilter.AssigneeID)
+	}
+
+	if incidentFilter.Status != nil {
+		query.Where("status = ?", *incidentFilter.Status)
+	}
+
+	if incidentFilter.Unresolved {
+		query.Where("status <> ?", es.IncidentResolved)
+	}
+
+	count, err := query.
+		Limit(incidentFilter.Limit()).
+		Offset(incidentFilter.Offset()).
+		Order(incidentFilter.Order()).
+		ScanAndCount(ctx)
+	if err != nil {
+		m.log.Errorc("failed to list incidents", err)
+		return nil, es.ErrInternalf("failed to list incidents").WithError(err)
+	}
+
+	incidentFilter.Pagination = filter.ComputePagination(count, incidentFilter.Page, incidentFilter.PageSize)
+	return incidents, nil
+}
+
+func (m *IncidentModel) AddEntry(ctx context.Context, entry *es.IncidentEntry) error {
+	entry.CreatedAt = time.Now()
+	_, err := m.db.NewInsert().Model(entry).Exec(ctx)
+	if err != nil {
+		m.log.Errorc("failed to insert incident entry", err, logger.UUID("incident_id", entry.IncidentID))
+		return es.ErrInternalf("failed to insert incident entry").WithError(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,112 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	es "github.com/alexjoedt/echosight/internal"
+	"github.com/alexjoedt/echosight/internal/filter"
+	"github.com/alexjoedt/echosight/internal/logger"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+var _ es.MaintenanceService = (*MaintenanceModel)(nil)
+
+type MaintenanceModel struct {
+	db  *bun.DB
+	log *logger.Logger
+}
+
+func (m *MaintenanceModel) Create(ctx context.Context, window *es.MaintenanceWindow) error {
+	window.CreatedAt = time.Now()
+	_, err := m.db.NewInsert().
+		Model(window).
+		Exec(ctx)
+	if err != nil {
+		m.log.Errorc("failed to insert maintenance window", err)
+		return es.ErrInternalf("failed to insert maintenance window").WithError(err)
+	}
+
+	return nil
+}
+
+func (m *MaintenanceModel) GetByID(ctx context.Context, id uuid.UUID) (*es.MaintenanceWindow, error) {
+	window := new(es.MaintenanceWindow)
+	err := m.db.NewSelect().Model(window).
+		Where("id = ?", id).Scan(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, es.ErrNotfoundf("no maintenance window found")
+		}
+		m.log.Errorc("failed to get maintenance window by id", err, logger.UUID("maintenance_id", id))
+		return nil, es.ErrInternalf("failed to get maintenance window by id").WithError(err)
+	}
+
+	return window, nil
+}
+
+func (m *MaintenanceModel) Update(ctx context.Context, window *es.MaintenanceWindow) error {
+	window.UpdatedAt = time.Now()
+	lv := window.LookupVersion
+	window.LookupVersion++
+
+	_, err := m.db.NewUpdate().Model(window).
+		Where("id = ? AND lookup_version = ?", window.ID, lv).
+		Exec(ctx)
+	if err != nil {
+		m.log.Errorc("failed to update maintenance window", err, logger.UUID("maintenance_id", window.ID))
+		return es.ErrInternalf("failed to update maintenance window").WithError(err)
+	}
+
+	return nil
+}
+
+func (m *MaintenanceModel) DeleteByID(ctx context.Context, id uuid.UUID) (*es.MaintenanceWindow, error) {
+	window := new(es.MaintenanceWindow)
+	err := m.db.NewDelete().Model(window).
+		Where("id = ?", id).
+		Returning("*").
+		Scan(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, es.ErrNotfoundf("no maintenance window found")
+		}
+		m.log.Errorc("failed to delete maintenance window", err, logger.UUID("maintenance_id", id))
+		return nil, es.ErrInternalf("failed to delete maintenance window").WithError(err)
+	}
+
+	return window, nil
+}
+
+func (m *MaintenanceModel) List(ctx context.Context, maintenanceFilter *filter.MaintenanceFilter) ([]*es.MaintenanceWindow, error) {
+	windows := make([]*es.MaintenanceWindow, 0)
+	query := m.db.NewSelect().Model(&windows)
+
+	if maintenanceFilter.HostID != nil {
+		query.Where("host_id = ?", *maintenanceFilter.HostID)
+	}
+
+	if maintenanceFilter.DetectorID != nil {
+		query.Where("detector_id = ?", *maintenanceFilter.DetectorID)
+	}
+
+	if maintenanceFilter.Tag != nil {
+		query.Where("tag = ?", *maintenanceFilter.Tag)
+	}
+
+	count, err := query.
+		Limit(maintenanceFilter.Limit()).
+		Offset(maintenanceFilter.Offset()).
+		Order(maintenanceFilter.Order()).
+		ScanAndCount(ctx)
+	if err != nil {
+		m.log.Errorc("failed to list maintenance windows", err)
+		return nil, es.ErrInternalf("failed to list maintenance windows").WithError(err)
+	}
+
+	maintenanceFilter.Pagination = filter.ComputePagination(count, maintenanceFilter.Page, maintenanceFilter.PageSize)
+	return windows, nil
+}
@@ -0,0 +1,123 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	es "github.com/alexjoedt/echosight/internal"
+	"github.com/alexjoedt/echosight/internal/filter"
+	"github.com/alexjoedt/echosight/internal/logger"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+var _ es.NotificationChannelService = (*NotificationChannelModel)(nil)
+
+type NotificationChannelModel struct {
+	db  *bun.DB
+	log *logger.Logger
+}
+
+func (m *NotificationChannelModel) Create(ctx context.Context, channel *es.NotificationChannel) error {
+	channel.CreatedAt = time.Now()
+	_, err := m.db.NewInsert().
+		Model(channel).
+		Exec(ctx)
+	if err != nil {
+		m.log.Errorc("failed to insert notification channel", err)
+		return es.ErrInternalf("failed to insert notification channel").WithError(err)
+	}
+
+	return nil
+}
+
+func (m *NotificationChannelModel) GetByID(ctx context.Context, id uuid.UUID) (*es.NotificationChannel, error) {
+	channel := new(es.NotificationChannel)
+	err := m.db.NewSelect().Model(channel).
+		Where("id = ?", id).Scan(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, es.ErrNotfoundf("no notification channel found")
+		}
+		m.log.Errorc("failed to get notification channel by id", err, logger.UUID("channel_id", id))
+		return nil, es.ErrInternalf("failed to get notification channel by id").WithError(err)
+	}
+
+	channel.SetSecretKeys()
+	return channel, nil
+}
+
+func (m *NotificationChannelModel) Update(ctx context.Context, channel *es.NotificationChannel) error {
+	channel.UpdatedAt = time.Now()
+	lv := channel.LookupVersion
+	channel.LookupVersion++
+
+	res, err := m.db.NewUpdate().Model(channel).
+		Where("id = ? AND lookup_version = ?", channel.ID, lv).
+		Exec(ctx)
+	if err != nil {
+		m.log.Errorc("failed to update notification channel", err, logger.UUID("channel_id", channel.ID))
+		return es.ErrInternalf("failed to update notification channel").WithError(err)
+	}
+
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		channel.LookupVersion = lv
+		return es.ErrConflictf("notification channel was changed in the meantime")
+	}
+
+	return nil
+}
+
+func (m *NotificationChannelModel) DeleteByID(ctx context.Context, id uuid.UUID) (*es.NotificationChannel, error) {
+	channel := new(es.NotificationChannel)
+	err := m.db.NewDelete().Model(channel).
+		Where("id = ?", id).
+		Returning("*").
+		Scan(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, es.ErrNotfoundf("no notification channel found")
+		}
+		m.log.Errorc("failed to delete notification channel", err, logger.UUID("channel_id", id))
+		return nil, es.ErrInternalf("failed to delete notification channel").WithError(err)
+	}
+
+	channel.SetSecretKeys()
+	return channel, nil
+}
+
+func (m *NotificationChannelModel) List(ctx context.Context, channelFilter *filter.NotificationChannelFilter) ([]*es.NotificationChannel, error) {
+	channels := make([]*es.NotificationChannel, 0)
+	query := m.db.NewSelect().Model(&channels)
+
+	if channelFilter.Name != nil {
+		query.Where("name = ?", *channelFilter.Name)
+	}
+
+	if channelFilter.Type != nil {
+		query.Where("type = ?", *channelFilter.Type)
+	}
+
+	if channelFilter.Enabled != nil {
+		query.Where("enabled = ?", *channelFilter.Enabled)
+	}
+
+	count, err := query.
+		Limit(channelFilter.Limit()).
+		Offset(channelFilter.Offset()).
+		Order(channelFilter.Order(), "created_at ASC").
+		ScanAndCount(ctx)
+	if err != nil {
+		m.log.Errorc("failed to list notification channels", err)
+		return nil, es.ErrInternalf("failed to list notification channels").WithError(err)
+	}
+
+	for _, channel := range channels {
+		channel.SetSecretKeys()
+	}
+
+	channelFilter.Pagination = filter.ComputePagination(count, channelFilter.Page, channelFilter.PageSize)
+	return channels, nil
+}
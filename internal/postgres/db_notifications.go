@@ -0,0 +1,132 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	es "github.com/alexjoedt/echosight/internal"
+	"github.com/alexjoedt/echosight/internal/filter"
+	"github.com/alexjoedt/echosight/internal/logger"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+var _ es.NotificationService = (*NotificationModel)(nil)
+
+type NotificationModel struct {
+	db  *bun.DB
+	log *logger.Logger
+}
+
+func (m *NotificationModel) Create(ctx context.Context, notification *es.Notification) error {
+	notification.CreatedAt = time.Now()
+	_, err := m.db.NewInsert().
+		Model(notification).
+		Exec(ctx)
+	if err != nil {
+		m.log.Errorc("failed to insert notification", err, logger.Str("channel", notification.Channel))
+		return es.ErrInternalf("failed to insert notification").WithError(err)
+	}
+
+	return nil
+}
+
+func (m *NotificationModel) GetByID(ctx context.Context, id uuid.UUID) (*es.Notification, error) {
+	notification := new(es.Notification)
+	err := m.db.NewSelect().Model(notification).
+		Relation("Log", func(q *bun.SelectQuery) *bun.SelectQuery {
+			return q.Order("attempt ASC")
+		}).
+		Where("notification.id = ?", id).Scan(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, es.ErrNotfoundf("no notification found")
+		}
+		m.log.Errorc("failed to get notification by id", err, logger.UUID("notification_id", id))
+		return nil, es.ErrInternalf("failed to get notification by id").WithError(err)
+	}
+
+	return notification, nil
+}
+
+func (m *NotificationModel) Update(ctx context.Context, notification *es.Notification) error {
+	notification.UpdatedAt = time.Now()
+	_, err := m.db.NewUpdate().Model(notification).
+		WherePK().
+		Exec(ctx)
+	if err != nil {
+		m.log.Errorc("failed to update notification", err, logger.UUID("notification_id", notification.ID))
+		return es.ErrInternalf("failed to update notification").WithError(err)
+	}
+
+	return nil
+}
+
+func (m *NotificationModel) List(ctx context.Context, notificationFilter *filter.NotificationFilter) ([]*es.Notification, error) {
+	notifications := make([]*es.Notification, 0)
+	query := m.db.NewSelect().Model(&notifications)
+
+	if notificationFilter.Status != nil {
+		query.Where("status = ?", *notificationFilter.Status)
+	}
+
+	if notificationFilter.Channel != nil {
+		query.Where("channel = ?", *notificationFilter.Channel)
+	}
+
+	if notificationFilter.Type != nil {
+		query.Where("type = ?", *notificationFilter.Type)
+	}
+
+	count, err := query.
+		Limit(notificationFilter.Limit()).
+		Offset(notificationFilter.Offset()).
+		Order(notificationFilter.Order()).
+		ScanAndCount(ctx)
+	if err != nil {
+		m.log.Errorc("failed to list notifications", err)
+		return nil, es.ErrInternalf("failed to list notifications").WithError(err)
+	}
+
+	notificationFilter.Pagination = filter.ComputePagination(count, notificationFilter.Page, notificationFilter.PageSize)
+	return notifications, nil
+}
+
+func (m *NotificationModel) Claim(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]*es.Notification, error) {
+	due := m.db.NewSelect().Model((*es.Notification)(nil)).
+		Column("id").
+		Where("status = ?", es.DeliveryPending).
+		Where("next_attempt_at <= ?", now).
+		Order("next_attempt_at ASC").
+		Limit(limit).
+		For("UPDATE SKIP LOCKED")
+
+	notifications := make([]*es.Notification, 0)
+	err := m.db.NewUpdate().Model((*es.Notification)(nil)).
+		Set("next_attempt_at = ?", now.Add(lease)).
+		Set("updated_at = ?", now).
+		Where("id IN (?)", due).
+		Returning("*").
+		Scan(ctx, &notifications)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		m.log.Errorc("failed to claim notifications", err)
+		return nil, es.ErrInternalf("failed to claim notifications").WithError(err)
+	}
+
+	return notifications, nil
+}
+
+func (m *NotificationModel) AddAttempt(ctx context.Context, attempt *es.DeliveryAttempt) error {
+	attempt.CreatedAt = time.Now()
+	_, err := m.db.NewInsert().
+		Model(attempt).
+		Exec(ctx)
+	if err != nil {
+		m.log.Errorc("failed to insert delivery attempt", err, logger.UUID("notification_id", attempt.NotificationID))
+		return es.ErrInternalf("failed to insert delivery attempt").WithError(err)
+	}
+
+	return nil
+}
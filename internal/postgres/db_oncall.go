@@ -0,0 +1,166 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	es "github.com/alexjoedt/echosight/internal"
+	"github.com/alexjoedt/echosight/internal/filter"
+	"github.com/alexjoedt/echosight/internal/logger"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+var _ es.OnCallService = (*OnCallModel)(nil)
+
+type OnCallModel struct {
+	db  *bun.DB
+	log *logger.Logger
+}
+
+func (m *OnCallModel) Create(ctx context.Context, schedule *es.OnCallSchedule) error {
+	schedule.CreatedAt = time.Now()
+	_, err := m.db.NewInsert().
+		Model(schedule).
+		Exec(ctx)
+	if err != nil {
+		m.log.Errorc("failed to insert on-call schedule", err)
+		return es.ErrInternalf("failed to insert on-call schedule").WithError(err)
+	}
+
+	return nil
+}
+
+func (m *OnCallModel) GetByID(ctx context.Context, id uuid.UUID) (*es.OnCallSchedule, error) {
+	schedule := new(es.OnCallSchedule)
+	err := m.db.NewSelect().Model(schedule).
+		Where("id = ?", id).Scan(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, es.ErrNotfoundf("no on-call schedule found")
+		}
+		m.log.Errorc("failed to get on-call schedule by id", err, logger.UUID("schedule_id", id))
+		return nil, es.ErrInternalf("failed to get on-call schedule by id").WithError(err)
+	}
+
+	return schedule, nil
+}
+
+func (m *OnCallModel) Update(ctx context.Context, schedule *es.OnCallSchedule) error {
+	schedule.UpdatedAt = time.Now()
+	lv := schedule.LookupVersion
+	schedule.LookupVersion++
+
+	res, err := m.db.NewUpdate().Model(schedule).
+		Where("id = ? AND lookup_version = ?", schedule.ID, lv).
+		Exec(ctx)
+	if err != nil {
+		m.log.Errorc("failed to update on-call schedule", err, logger.UUID("schedule_id", schedule.ID))
+		return es.ErrInternalf("failed to update on-call schedule").WithError(err)
+	}
+
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		schedule.LookupVersion = lv
+		return es.ErrConflictf("on-call schedule was changed in the meantime")
+	}
+
+	return nil
+}
+
+func (m *OnCallModel) DeleteByID(ctx context.Context, id uuid.UUID) (*es.OnCallSchedule, error) {
+	schedule := new(es.OnCallSchedule)
+	err := m.db.NewDelete().Model(schedule).
+		Where("id = ?", id).
+		Returning("*").
+		Scan(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, es.ErrNotfoundf("no on-call schedule found")
+		}
+		m.log.Errorc("failed to delete on-call schedule", err, logger.UUID("schedule_id", id))
+		return nil, es.ErrInternalf("failed to delete on-call schedule").WithError(err)
+	}
+
+	return schedule, nil
+}
+
+func (m *OnCallModel) List(ctx context.Context, oncallFilter *filter.OnCallFilter) ([]*es.OnCallSchedule, error) {
+	schedules := make([]*es.OnCallSchedule, 0)
+	query := m.db.NewSelect().Model(&schedules)
+
+	if oncallFilter.Name != nil {
+		query.Where("name = ?", *oncallFilter.Name)
+	}
+
+	count, err := query.
+		Limit(oncallFilter.Limit()).
+		Offset(oncallFilter.Offset()).
+		Order(oncallFilter.Order()).
+		ScanAndCount(ctx)
+	if err != nil {
+		m.log.Errorc("failed to list on-call schedules", err)
+		return nil, es.ErrInternalf("failed to list on-call schedules").WithError(err)
+	}
+
+	oncallFilter.Pagination = filter.ComputePagination(count, oncallFilter.Page, oncallFilter.PageSize)
+	return schedules, nil
+}
+
+func (m *OnCallModel) CreateOverride(ctx context.Context, override *es.OnCallOverride) error {
+	override.CreatedAt = time.Now()
+	_, err := m.db.NewInsert().
+		Model(override).
+		Exec(ctx)
+	if err != nil {
+		m.log.Errorc("failed to insert on-call override", err, logger.UUID("schedule_id", override.ScheduleID))
+		return es.ErrInternalf("failed to insert on-call override").WithError(err)
+	}
+
+	return nil
+}
+
+func (m *OnCallModel) DeleteOverride(ctx context.Context, scheduleID uuid.UUID, id uuid.UUID) (*es.OnCallOverride, error) {
+	override := new(es.OnCallOverride)
+	err := m.db.NewDelete().Model(override).
+		Where("id = ? AND schedule_id = ?", id, scheduleID).
+		Returning("*").
+		Scan(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, es.ErrNotfoundf("no on-call override found")
+		}
+		m.log.Errorc("failed to delete on-call override", err, logger.UUID("override_id", id))
+		return nil, es.ErrInternalf("failed to delete on-call override").WithError(err)
+	}
+
+	return override, nil
+}
+
+func (m *OnCallModel) ListOverrides(ctx context.Context, oncallFilter *filter.OnCallFilter) ([]*es.OnCallOverride, error) {
+	overrides := make([]*es.OnCallOverride, 0)
+	query := m.db.NewSelect().Model(&overrides)
+
+	if oncallFilter.ScheduleID != nil {
+		query.Where("schedule_id = ?", *oncallFilter.ScheduleID)
+	}
+
+	if oncallFilter.From != nil {
+		query.Where("ends_at > ?", *oncallFilter.From)
+	}
+
+	if oncallFilter.To != nil {
+		query.Where("starts_at < ?", *oncallFilter.To)
+	}
+
+	err := query.
+		Order("created_at ASC").
+		Scan(ctx)
+	if err != nil {
+		m.log.Errorc("failed to list on-call overrides", err)
+		return nil, es.ErrInternalf("failed to list on-call overrides").WithError(err)
+	}
+
+	return overrides, nil
+}
@@ -47,6 +47,7 @@ func (m *RecipientModel) GetByID(ctx context.Context, id uuid.UUID) (*es.Recipie
 		return nil, err
 	}
 
+	recipient.HasGotifyToken = recipient.GotifyTokenCrypt != ""
 	return recipient, nil
 }
 
@@ -62,6 +63,7 @@ func (m *RecipientModel) GetByEmail(ctx context.Context, email string) (*es.Reci
 		return nil, es.ErrInternalf("failed to get recipient by email").WithError(err)
 	}
 
+	recipient.HasGotifyToken = recipient.GotifyTokenCrypt != ""
 	return recipient, nil
 }
 
@@ -107,6 +109,10 @@ func (m *RecipientModel) List(ctx context.Context, rcptFilter *filter.RecipientF
 		query.Where("activated = ?", *rcptFilter.Active)
 	}
 
+	if len(rcptFilter.IDs) > 0 {
+		query.Where("id IN (?)", bun.In(rcptFilter.IDs))
+	}
+
 	if rcptFilter.Email != nil {
 		query.Where("LOWER(email) LIKE ?", "%"+strings.ToLower(*rcptFilter.Email)+"%")
 	}
@@ -121,6 +127,10 @@ func (m *RecipientModel) List(ctx context.Context, rcptFilter *filter.RecipientF
 		return nil, err
 	}
 
+	for _, recipient := range recipients {
+		recipient.HasGotifyToken = recipient.GotifyTokenCrypt != ""
+	}
+
 	rcptFilter.Pagination = filter.ComputePagination(count, rcptFilter.Page, rcptFilter.PageSize)
 	return recipients, nil
 }
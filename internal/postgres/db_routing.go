@@ -0,0 +1,113 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	es "github.com/alexjoedt/echosight/internal"
+	"github.com/alexjoedt/echosight/internal/filter"
+	"github.com/alexjoedt/echosight/internal/logger"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+var _ es.RoutingService = (*RoutingModel)(nil)
+
+type RoutingModel struct {
+	db  *bun.DB
+	log *logger.Logger
+}
+
+func (m *RoutingModel) Create(ctx context.Context, rule *es.RoutingRule) error {
+	rule.CreatedAt = time.Now()
+	_, err := m.db.NewInsert().
+		Model(rule).
+		Exec(ctx)
+	if err != nil {
+		m.log.Errorc("failed to insert routing rule", err)
+		return es.ErrInternalf("failed to insert routing rule").WithError(err)
+	}
+
+	return nil
+}
+
+func (m *RoutingModel) GetByID(ctx context.Context, id uuid.UUID) (*es.RoutingRule, error) {
+	rule := new(es.RoutingRule)
+	err := m.db.NewSelect().Model(rule).
+		Where("id = ?", id).Scan(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, es.ErrNotfoundf("no routing rule found")
+		}
+		m.log.Errorc("failed to get routing rule by id", err, logger.UUID("rule_id", id))
+		return nil, es.ErrInternalf("failed to get routing rule by id").WithError(err)
+	}
+
+	return rule, nil
+}
+
+func (m *RoutingModel) Update(ctx context.Context, rule *es.RoutingRule) error {
+	rule.UpdatedAt = time.Now()
+	lv := rule.LookupVersion
+	rule.LookupVersion++
+
+	res, err := m.db.NewUpdate().Model(rule).
+		Where("id = ? AND lookup_version = ?", rule.ID, lv).
+		Exec(ctx)
+	if err != nil {
+		m.log.Errorc("failed to update routing rule", err, logger.UUID("rule_id", rule.ID))
+		return es.ErrInternalf("failed to update routing rule").WithError(err)
+	}
+
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		rule.LookupVersion = lv
+		return es.ErrConflictf("routing rule was changed in the meantime")
+	}
+
+	return nil
+}
+
+func (m *RoutingModel) DeleteByID(ctx context.Context, id uuid.UUID) (*es.RoutingRule, error) {
+	rule := new(es.RoutingRule)
+	err := m.db.NewDelete().Model(rule).
+		Where("id = ?", id).
+		Returning("*").
+		Scan(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, es.ErrNotfoundf("no routing rule found")
+		}
+		m.log.Errorc("failed to delete routing rule", err, logger.UUID("rule_id", id))
+		return nil, es.ErrInternalf("failed to delete routing rule").WithError(err)
+	}
+
+	return rule, nil
+}
+
+func (m *RoutingModel) List(ctx context.Context, routingFilter *filter.RoutingFilter) ([]*es.RoutingRule, error) {
+	rules := make([]*es.RoutingRule, 0)
+	query := m.db.NewSelect().Model(&rules)
+
+	if routingFilter.Name != nil {
+		query.Where("name = ?", *routingFilter.Name)
+	}
+
+	if routingFilter.Active != nil {
+		query.Where("active = ?", *routingFilter.Active)
+	}
+
+	count, err := query.
+		Limit(routingFilter.Limit()).
+		Offset(routingFilter.Offset()).
+		Order(routingFilter.Order(), "created_at ASC").
+		ScanAndCount(ctx)
+	if err != nil {
+		m.log.Errorc("failed to list routing rules", err)
+		return nil, es.ErrInternalf("failed to list routing rules").WithError(err)
+	}
+
+	routingFilter.Pagination = filter.ComputePagination(count, routingFilter.Page, routingFilter.PageSize)
+	return rules, nil
+}
@@ -0,0 +1,129 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	es "github.com/alexjoedt/echosight/internal"
+	"github.com/alexjoedt/echosight/internal/filter"
+	"github.com/alexjoedt/echosight/internal/logger"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+var _ es.SLOService = (*SLOModel)(nil)
+
+type SLOModel struct {
+	db  *bun.DB
+	log *logger.Logger
+}
+
+func (m *SLOModel) Create(ctx context.Context, slo *es.SLO) error {
+	slo.CreatedAt = time.Now()
+	_, err := m.db.NewInsert().
+		Model(slo).
+		Exec(ctx)
+	if err != nil {
+		m.log.Errorc("failed to insert slo", err)
+		return es.ErrInternalf("failed to insert slo").WithError(err)
+	}
+
+	return nil
+}
+
+func (m *SLOModel) GetByID(ctx context.Context, id uuid.UUID) (*es.SLO, error) {
+	slo := new(es.SLO)
+	err := m.db.NewSelect().Model(slo).
+		Where("id = ?", id).Scan(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, es.ErrNotfoundf("no slo found")
+		}
+		m.log.Errorc("failed to get slo by id", err, logger.UUID("slo_id", id))
+		return nil, es.ErrInternalf("failed to get slo by id").WithError(err)
+	}
+
+	return slo, nil
+}
+
+func (m *SLOModel) Update(ctx context.Context, slo *es.SLO) error {
+	slo.UpdatedAt = time.Now()
+	lv := slo.LookupVersion
+	slo.LookupVersion++
+
+	_, err := m.db.NewUpdate().Model(slo).
+		ExcludeColumn("status").
+		Where("id = ? AND lookup_version = ?", slo.ID, lv).
+		Exec(ctx)
+	if err != nil {
+		m.log.Errorc("failed to update slo", err, logger.UUID("slo_id", slo.ID))
+		return es.ErrInternalf("failed to update slo").WithError(err)
+	}
+
+	return nil
+}
+
+// UpdateStatus updates only the status of the slo,
+// the status is written by the slo tracker and must not conflict with the updates of the definition.
+func (m *SLOModel) UpdateStatus(ctx context.Context, slo *es.SLO) error {
+	_, err := m.db.NewUpdate().Model(slo).
+		Column("status").
+		Where("id = ?", slo.ID).
+		Exec(ctx)
+	if err != nil {
+		m.log.Errorc("failed to update slo status", err, logger.UUID("slo_id", slo.ID))
+		return es.ErrInternalf("failed to update slo status").WithError(err)
+	}
+
+	return nil
+}
+
+func (m *SLOModel) DeleteByID(ctx context.Context, id uuid.UUID) (*es.SLO, error) {
+	slo := new(es.SLO)
+	err := m.db.NewDelete().Model(slo).
+		Where("id = ?", id).
+		Returning("*").
+		Scan(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, es.ErrNotfoundf("no slo found")
+		}
+		m.log.Errorc("failed to delete slo", err, logger.UUID("slo_id", id))
+		return nil, es.ErrInternalf("failed to delete slo").WithError(err)
+	}
+
+	return slo, nil
+}
+
+func (m *SLOModel) List(ctx context.Context, sloFilter *filter.SLOFilter) ([]*es.SLO, error) {
+	slos := make([]*es.SLO, 0)
+	query := m.db.NewSelect().Model(&slos)
+
+	if sloFilter.Name != nil {
+		query.Where("LOWER(name) LIKE ?", "%"+strings.ToLower(*sloFilter.Name)+"%")
+	}
+
+	if sloFilter.DetectorID != nil {
+		query.Where("detector_id = ?", *sloFilter.DetectorID)
+	}
+
+	if sloFilter.Tag != nil {
+		query.Where("tag = ?", *sloFilter.Tag)
+	}
+
+	count, err := query.
+		Limit(sloFilter.Limit()).
+		Offset(sloFilter.Offset()).
+		Order(sloFilter.Order()).
+		ScanAndCount(ctx)
+	if err != nil {
+		m.log.Errorc("failed to list slos", err)
+		return nil, es.ErrInternalf("failed to list slos").WithError(err)
+	}
+
+	sloFilter.Pagination = filter.ComputePagination(count, sloFilter.Page, sloFilter.PageSize)
+	return slos, nil
+}
@@ -0,0 +1,138 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	es "github.com/alexjoedt/echosight/internal"
+	"github.com/alexjoedt/echosight/internal/filter"
+	"github.com/alexjoedt/echosight/internal/logger"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+var _ es.TemplateService = (*TemplateModel)(nil)
+
+type TemplateModel struct {
+	db  *bun.DB
+	log *logger.Logger
+}
+
+func (m *TemplateModel) Create(ctx context.Context, tmpl *es.NotificationTemplate) error {
+	tmpl.CreatedAt = time.Now()
+	_, err := m.db.NewInsert().
+		Model(tmpl).
+		Exec(ctx)
+	if err != nil {
+		m.log.Errorc("failed to insert notification template", err, logger.Str("channel", tmpl.Channel))
+		return es.ErrInternalf("failed to insert notification template").WithError(err)
+	}
+
+	return nil
+}
+
+func (m *TemplateModel) GetByID(ctx context.Context, id uuid.UUID) (*es.NotificationTemplate, error) {
+	tmpl := new(es.NotificationTemplate)
+	err := m.db.NewSelect().Model(tmpl).
+		Where("id = ?", id).Scan(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, es.ErrNotfoundf("no notification template found")
+		}
+		m.log.Errorc("failed to get notification template by id", err, logger.UUID("template_id", id))
+		return nil, es.ErrInternalf("failed to get notification template by id").WithError(err)
+	}
+
+	return tmpl, nil
+}
+
+func (m *TemplateModel) Update(ctx context.Context, tmpl *es.NotificationTemplate) error {
+	tmpl.UpdatedAt = time.Now()
+	lv := tmpl.LookupVersion
+	tmpl.LookupVersion++
+
+	res, err := m.db.NewUpdate().Model(tmpl).
+		Where("id = ? AND lookup_version = ?", tmpl.ID, lv).
+		Exec(ctx)
+	if err != nil {
+		m.log.Errorc("failed to update notification template", err, logger.UUID("template_id", tmpl.ID))
+		return es.ErrInternalf("failed to update notification template").WithError(err)
+	}
+
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		tmpl.LookupVersion = lv
+		return es.ErrConflictf("notification template was changed in the meantime")
+	}
+
+	return nil
+}
+
+func (m *TemplateModel) DeleteByID(ctx context.Context, id uuid.UUID) (*es.NotificationTemplate, error) {
+	tmpl := new(es.NotificationTemplate)
+	err := m.db.NewDelete().Model(tmpl).
+		Where("id = ?", id).
+		Returning("*").
+		Scan(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, es.ErrNotfoundf("no notification template found")
+		}
+		m.log.Errorc("failed to delete notification template", err, logger.UUID("template_id", id))
+		return nil, es.ErrInternalf("failed to delete notification template").WithError(err)
+	}
+
+	return tmpl, nil
+}
+
+func (m *TemplateModel) List(ctx context.Context, templateFilter *filter.TemplateFilter) ([]*es.NotificationTemplate, error) {
+	templates := make([]*es.NotificationTemplate, 0)
+	query := m.db.NewSelect().Model(&templates)
+
+	if templateFilter.Channel != nil {
+		query.Where("channel = ?", *templateFilter.Channel)
+	}
+
+	if templateFilter.Type != nil {
+		query.Where("type = ?", *templateFilter.Type)
+	}
+
+	if templateFilter.Active != nil {
+		query.Where("active = ?", *templateFilter.Active)
+	}
+
+	count, err := query.
+		Limit(templateFilter.Limit()).
+		Offset(templateFilter.Offset()).
+		Order(templateFilter.Order(), "type ASC").
+		ScanAndCount(ctx)
+	if err != nil {
+		m.log.Errorc("failed to list notification templates", err)
+		return nil, es.ErrInternalf("failed to list notification templates").WithError(err)
+	}
+
+	templateFilter.Pagination = filter.ComputePagination(count, templateFilter.Page, templateFilter.PageSize)
+	return templates, nil
+}
+
+func (m *TemplateModel) Find(ctx context.Context, channel string, notificationType es.NotificationType) (*es.NotificationTemplate, error) {
+	tmpl := new(es.NotificationTemplate)
+	// the template of the type is sorted before the template for all types
+	err := m.db.NewSelect().Model(tmpl).
+		Where("channel = ?", channel).
+		Where("type IN (?)", bun.In([]string{string(notificationType), ""})).
+		Where("active = ?", true).
+		Order("type DESC").
+		Limit(1).
+		Scan(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, es.ErrNotfoundf("no notification template found")
+		}
+		m.log.Errorc("failed to find notification template", err, logger.Str("channel", channel))
+		return nil, es.ErrInternalf("failed to find notification template").WithError(err)
+	}
+
+	return tmpl, nil
+}
@@ -0,0 +1,122 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	es "github.com/alexjoedt/echosight/internal"
+	"github.com/alexjoedt/echosight/internal/filter"
+	"github.com/alexjoedt/echosight/internal/logger"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+var _ es.WebhookService = (*WebhookModel)(nil)
+
+type WebhookModel struct {
+	db  *bun.DB
+	log *logger.Logger
+}
+
+func (m *WebhookModel) Create(ctx context.Context, webhook *es.Webhook) error {
+	webhook.CreatedAt = time.Now()
+	_, err := m.db.NewInsert().
+		Model(webhook).
+		Exec(ctx)
+	if err != nil {
+		m.log.Errorc("failed to insert webhook", err)
+		return es.ErrInternalf("failed to insert webhook").WithError(err)
+	}
+
+	return nil
+}
+
+func (m *WebhookModel) GetByID(ctx context.Context, id uuid.UUID) (*es.Webhook, error) {
+	webhook := new(es.Webhook)
+	err := m.db.NewSelect().Model(webhook).
+		Where("id = ?", id).Scan(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, es.ErrNotfoundf("no webhook found")
+		}
+		m.log.Errorc("failed to get webhook by id", err, logger.UUID("webhook_id", id))
+		return nil, es.ErrInternalf("failed to get webhook by id").WithError(err)
+	}
+
+	webhook.HasSecret = webhook.SecretCrypt != ""
+	return webhook, nil
+}
+
+func (m *WebhookModel) Update(ctx context.Context, webhook *es.Webhook) error {
+	webhook.UpdatedAt = time.Now()
+	lv := webhook.LookupVersion
+	webhook.LookupVersion++
+
+	res, err := m.db.NewUpdate().Model(webhook).
+		Where("id = ? AND lookup_version = ?", webhook.ID, lv).
+		Exec(ctx)
+	if err != nil {
+		m.log.Errorc("failed to update webhook", err, logger.UUID("webhook_id", webhook.ID))
+		return es.ErrInternalf("failed to update webhook").WithError(err)
+	}
+
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		webhook.LookupVersion = lv
+		return es.ErrConflictf("webhook was changed in the meantime")
+	}
+
+	return nil
+}
+
+func (m *WebhookModel) DeleteByID(ctx context.Context, id uuid.UUID) (*es.Webhook, error) {
+	webhook := new(es.Webhook)
+	err := m.db.NewDelete().Model(webhook).
+		Where("id = ?", id).
+		Returning("*").
+		Scan(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, es.ErrNotfoundf("no webhook found")
+		}
+		m.log.Errorc("failed to delete webhook", err, logger.UUID("webhook_id", id))
+		return nil, es.ErrInternalf("failed to delete webhook").WithError(err)
+	}
+
+	return webhook, nil
+}
+
+func (m *WebhookModel) List(ctx context.Context, webhookFilter *filter.WebhookFilter) ([]*es.Webhook, error) {
+	webhooks := make([]*es.Webhook, 0)
+	query := m.db.NewSelect().Model(&webhooks)
+
+	if webhookFilter.Name != nil {
+		query.Where("name = ?", *webhookFilter.Name)
+	}
+
+	if webhookFilter.Active != nil {
+		query.Where("active = ?", *webhookFilter.Active)
+	}
+
+	if len(webhookFilter.IDs) > 0 {
+		query.Where("id IN (?)", bun.In(webhookFilter.IDs))
+	}
+
+	count, err := query.
+		Limit(webhookFilter.Limit()).
+		Offset(webhookFilter.Offset()).
+		Order(webhookFilter.Order(), "created_at ASC").
+		ScanAndCount(ctx)
+	if err != nil {
+		m.log.Errorc("failed to list webhooks", err)
+		return nil, es.ErrInternalf("failed to list webhooks").WithError(err)
+	}
+
+	for _, webhook := range webhooks {
+		webhook.HasSecret = webhook.SecretCrypt != ""
+	}
+
+	webhookFilter.Pagination = filter.ComputePagination(count, webhookFilter.Page, webhookFilter.PageSize)
+	return webhooks, nil
+}
@@ -0,0 +1,6 @@
+ALTER TABLE detectors DROP COLUMN IF EXISTS max_attempts;
+ALTER TABLE detectors DROP COLUMN IF EXISTS recovery_attempts;
+ALTER TABLE detectors DROP COLUMN IF EXISTS retry_interval;
+ALTER TABLE detectors DROP COLUMN IF EXISTS state_type;
+ALTER TABLE detectors DROP COLUMN IF EXISTS hard_state;
+ALTER TABLE detectors DROP COLUMN IF EXISTS attempt;
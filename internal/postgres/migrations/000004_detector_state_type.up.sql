@@ -0,0 +1,6 @@
+ALTER TABLE detectors ADD COLUMN IF NOT EXISTS max_attempts integer NOT NULL DEFAULT 1;
+ALTER TABLE detectors ADD COLUMN IF NOT EXISTS recovery_attempts integer NOT NULL DEFAULT 1;
+ALTER TABLE detectors ADD COLUMN IF NOT EXISTS retry_interval varchar;
+ALTER TABLE detectors ADD COLUMN IF NOT EXISTS state_type varchar NOT NULL DEFAULT 'HARD';
+ALTER TABLE detectors ADD COLUMN IF NOT EXISTS hard_state varchar NOT NULL DEFAULT 'INACTIVE';
+ALTER TABLE detectors ADD COLUMN IF NOT EXISTS attempt integer NOT NULL DEFAULT 0;
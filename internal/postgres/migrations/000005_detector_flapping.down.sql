@@ -0,0 +1,5 @@
+ALTER TABLE detectors DROP COLUMN IF EXISTS flap_detection;
+ALTER TABLE detectors DROP COLUMN IF EXISTS flap_low_threshold;
+ALTER TABLE detectors DROP COLUMN IF EXISTS flap_high_threshold;
+ALTER TABLE detectors DROP COLUMN IF EXISTS flapping;
+ALTER TABLE detectors DROP COLUMN IF EXISTS flap_percent;
@@ -0,0 +1,5 @@
+ALTER TABLE detectors ADD COLUMN IF NOT EXISTS flap_detection BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE detectors ADD COLUMN IF NOT EXISTS flap_low_threshold double precision NOT NULL DEFAULT 5.0;
+ALTER TABLE detectors ADD COLUMN IF NOT EXISTS flap_high_threshold double precision NOT NULL DEFAULT 20.0;
+ALTER TABLE detectors ADD COLUMN IF NOT EXISTS flapping BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE detectors ADD COLUMN IF NOT EXISTS flap_percent double precision NOT NULL DEFAULT 0;
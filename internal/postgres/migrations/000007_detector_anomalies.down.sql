@@ -0,0 +1 @@
+ALTER TABLE detectors DROP COLUMN IF EXISTS anomalies;
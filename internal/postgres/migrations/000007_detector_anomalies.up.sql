@@ -0,0 +1 @@
+ALTER TABLE detectors ADD COLUMN IF NOT EXISTS anomalies JSONB;
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS detector_results_detector_idx;
+DROP TABLE IF EXISTS detector_results;
+DROP INDEX IF EXISTS detector_state_changes_detector_idx;
+DROP TABLE IF EXISTS detector_state_changes;
+ALTER TABLE detectors DROP COLUMN IF EXISTS state_changed_at;
@@ -0,0 +1,30 @@
+ALTER TABLE detectors ADD COLUMN IF NOT EXISTS state_changed_at timestamp(0) with time zone NOT NULL DEFAULT '1900-01-01 00:00:00+00';
+
+CREATE TABLE IF NOT EXISTS detector_state_changes (
+  id uuid PRIMARY KEY NOT NULL DEFAULT uuid_generate_v4(),
+  detector_id uuid NOT NULL REFERENCES detectors ON DELETE CASCADE,
+  host_id uuid NOT NULL REFERENCES hosts ON DELETE CASCADE,
+  from_state varchar NOT NULL,
+  to_state varchar NOT NULL,
+  message TEXT,
+  flapping BOOLEAN NOT NULL DEFAULT false,
+  duration bigint NOT NULL DEFAULT 0, -- nanoseconds in the previous state
+  created_at timestamp with time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS detector_state_changes_detector_idx ON detector_state_changes (detector_id, created_at);
+
+CREATE TABLE IF NOT EXISTS detector_results (
+  id bigserial PRIMARY KEY,
+  detector_id uuid NOT NULL REFERENCES detectors ON DELETE CASCADE,
+  host_id uuid NOT NULL REFERENCES hosts ON DELETE CASCADE,
+  state varchar NOT NULL,
+  state_type varchar NOT NULL,
+  attempt integer NOT NULL DEFAULT 0,
+  message TEXT,
+  timed_out BOOLEAN NOT NULL DEFAULT false,
+  flapping BOOLEAN NOT NULL DEFAULT false,
+  created_at timestamp with time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS detector_results_detector_idx ON detector_results (detector_id, created_at);
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS slos (
+  id uuid PRIMARY KEY NOT NULL DEFAULT uuid_generate_v4(),
+  lookup_version bigint NOT NULL DEFAULT 1,
+  name varchar UNIQUE NOT NULL,
+  detector_id uuid REFERENCES detectors ON DELETE CASCADE,
+  tag varchar,
+  type varchar NOT NULL,
+  objective double precision NOT NULL,
+  "window" bigint NOT NULL, -- nanoseconds
+  threshold JSONB,
+  alerts BOOLEAN NOT NULL DEFAULT true,
+  status JSONB,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  updated_at timestamp(0) with time zone NOT NULL DEFAULT '1900-01-01 00:00:00+00'
+);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS incident_timeline_incident_idx;
+DROP TABLE IF EXISTS incident_timeline;
+DROP INDEX IF EXISTS incidents_status_idx;
+DROP INDEX IF EXISTS incidents_detector_unresolved_idx;
+DROP TABLE IF EXISTS incidents;
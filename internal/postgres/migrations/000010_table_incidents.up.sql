@@ -0,0 +1,35 @@
+CREATE TABLE IF NOT EXISTS incidents (
+  id uuid PRIMARY KEY NOT NULL DEFAULT uuid_generate_v4(),
+  lookup_version bigint NOT NULL DEFAULT 1,
+  detector_id uuid NOT NULL REFERENCES detectors ON DELETE CASCADE,
+  detector_name varchar NOT NULL,
+  host_id uuid NOT NULL REFERENCES hosts ON DELETE CASCADE,
+  host_name varchar NOT NULL,
+  status varchar NOT NULL DEFAULT 'open',
+  state varchar NOT NULL,
+  worst_state varchar NOT NULL,
+  message TEXT,
+  assignee_id uuid REFERENCES users ON DELETE SET NULL,
+  acknowledged_by uuid REFERENCES users ON DELETE SET NULL,
+  acknowledged_at timestamp with time zone,
+  resolved_by uuid REFERENCES users ON DELETE SET NULL,
+  resolved_at timestamp with time zone,
+  created_at timestamp with time zone NOT NULL DEFAULT NOW(),
+  updated_at timestamp with time zone NOT NULL DEFAULT '1900-01-01 00:00:00+00'
+);
+
+-- a detector has at most one unresolved incident
+CREATE UNIQUE INDEX IF NOT EXISTS incidents_detector_unresolved_idx ON incidents (detector_id) WHERE status <> 'resolved';
+CREATE INDEX IF NOT EXISTS incidents_status_idx ON incidents (status, created_at);
+
+CREATE TABLE IF NOT EXISTS incident_timeline (
+  id uuid PRIMARY KEY NOT NULL DEFAULT uuid_generate_v4(),
+  incident_id uuid NOT NULL REFERENCES incidents ON DELETE CASCADE,
+  type varchar NOT NULL,
+  user_id uuid REFERENCES users ON DELETE SET NULL,
+  state varchar,
+  message TEXT,
+  created_at timestamp with time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS incident_timeline_incident_idx ON incident_timeline (incident_id, created_at);
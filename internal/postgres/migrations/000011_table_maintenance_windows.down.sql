@@ -0,0 +1,2 @@
+ALTER TABLE detectors DROP COLUMN IF EXISTS in_maintenance;
+DROP TABLE IF EXISTS maintenance_windows;
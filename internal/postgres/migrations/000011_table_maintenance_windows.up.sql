@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS maintenance_windows (
+  id uuid PRIMARY KEY NOT NULL DEFAULT uuid_generate_v4(),
+  lookup_version bigint NOT NULL DEFAULT 1,
+  name varchar NOT NULL,
+  description TEXT,
+  host_id uuid REFERENCES hosts ON DELETE CASCADE,
+  detector_id uuid REFERENCES detectors ON DELETE CASCADE,
+  tag varchar,
+  starts_at timestamp with time zone NOT NULL,
+  ends_at timestamp with time zone,
+  recurrence varchar,
+  duration bigint NOT NULL DEFAULT 0, -- nanoseconds
+  timezone varchar NOT NULL DEFAULT 'UTC',
+  created_by uuid REFERENCES users ON DELETE SET NULL,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  updated_at timestamp(0) with time zone NOT NULL DEFAULT '1900-01-01 00:00:00+00'
+);
+
+ALTER TABLE detectors ADD COLUMN IF NOT EXISTS in_maintenance BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS escalations_due_idx;
+DROP TABLE IF EXISTS escalations;
+DROP TABLE IF EXISTS escalation_policies;
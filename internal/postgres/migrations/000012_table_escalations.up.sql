@@ -0,0 +1,27 @@
+CREATE TABLE IF NOT EXISTS escalation_policies (
+  id uuid PRIMARY KEY NOT NULL DEFAULT uuid_generate_v4(),
+  lookup_version bigint NOT NULL DEFAULT 1,
+  name varchar UNIQUE NOT NULL,
+  active BOOLEAN NOT NULL DEFAULT true,
+  detector_ids uuid[],
+  tags varchar[],
+  levels JSONB NOT NULL,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  updated_at timestamp(0) with time zone NOT NULL DEFAULT '1900-01-01 00:00:00+00'
+);
+
+CREATE TABLE IF NOT EXISTS escalations (
+  id uuid PRIMARY KEY NOT NULL DEFAULT uuid_generate_v4(),
+  policy_id uuid NOT NULL REFERENCES escalation_policies ON DELETE CASCADE,
+  incident_id uuid NOT NULL REFERENCES incidents ON DELETE CASCADE,
+  detector_id uuid NOT NULL REFERENCES detectors ON DELETE CASCADE,
+  level integer NOT NULL DEFAULT 0,
+  next_at timestamp with time zone NOT NULL,
+  status varchar NOT NULL DEFAULT 'pending',
+  stop_reason TEXT,
+  started_at timestamp with time zone NOT NULL,
+  updated_at timestamp with time zone NOT NULL DEFAULT '1900-01-01 00:00:00+00',
+  UNIQUE (policy_id, incident_id)
+);
+
+CREATE INDEX IF NOT EXISTS escalations_due_idx ON escalations (status, next_at);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS oncall_overrides_schedule_idx;
+DROP TABLE IF EXISTS oncall_overrides;
+DROP TABLE IF EXISTS oncall_schedules;
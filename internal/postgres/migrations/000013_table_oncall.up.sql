@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS oncall_schedules (
+  id uuid PRIMARY KEY NOT NULL DEFAULT uuid_generate_v4(),
+  lookup_version bigint NOT NULL DEFAULT 1,
+  name varchar UNIQUE NOT NULL,
+  description TEXT,
+  timezone varchar NOT NULL DEFAULT 'UTC',
+  layers JSONB NOT NULL,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  updated_at timestamp(0) with time zone NOT NULL DEFAULT '1900-01-01 00:00:00+00'
+);
+
+CREATE TABLE IF NOT EXISTS oncall_overrides (
+  id uuid PRIMARY KEY NOT NULL DEFAULT uuid_generate_v4(),
+  schedule_id uuid NOT NULL REFERENCES oncall_schedules ON DELETE CASCADE,
+  recipient_id uuid NOT NULL REFERENCES recipients ON DELETE CASCADE,
+  starts_at timestamp with time zone NOT NULL,
+  ends_at timestamp with time zone NOT NULL,
+  created_by uuid,
+  created_at timestamp with time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS oncall_overrides_schedule_idx ON oncall_overrides (schedule_id, ends_at);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS routing_rules_position_idx;
+DROP TABLE IF EXISTS routing_rules;
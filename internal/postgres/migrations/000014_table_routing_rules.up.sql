@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS routing_rules (
+  id uuid PRIMARY KEY NOT NULL DEFAULT uuid_generate_v4(),
+  lookup_version bigint NOT NULL DEFAULT 1,
+  name varchar UNIQUE NOT NULL,
+  position integer NOT NULL DEFAULT 0,
+  active BOOLEAN NOT NULL DEFAULT true,
+  continue BOOLEAN NOT NULL DEFAULT false,
+  host_ids uuid[],
+  detector_ids uuid[],
+  tags varchar[],
+  states varchar[],
+  time_of_day JSONB,
+  timezone varchar NOT NULL DEFAULT 'UTC',
+  channels varchar[],
+  recipients uuid[],
+  schedules uuid[],
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  updated_at timestamp(0) with time zone NOT NULL DEFAULT '1900-01-01 00:00:00+00'
+);
+
+CREATE INDEX IF NOT EXISTS routing_rules_position_idx ON routing_rules (position);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS notification_attempts_notification_idx;
+DROP TABLE IF EXISTS notification_attempts;
+DROP INDEX IF EXISTS notifications_due_idx;
+DROP TABLE IF EXISTS notifications;
@@ -0,0 +1,29 @@
+CREATE TABLE IF NOT EXISTS notifications (
+  id uuid PRIMARY KEY NOT NULL DEFAULT uuid_generate_v4(),
+  channel varchar NOT NULL,
+  type varchar,
+  recipients uuid[],
+  payload JSONB NOT NULL,
+  status varchar NOT NULL DEFAULT 'pending',
+  attempts integer NOT NULL DEFAULT 0,
+  max_attempts integer NOT NULL,
+  next_attempt_at timestamp with time zone NOT NULL DEFAULT NOW(),
+  last_error TEXT,
+  delivered_at timestamp with time zone,
+  created_at timestamp with time zone NOT NULL DEFAULT NOW(),
+  updated_at timestamp with time zone NOT NULL DEFAULT '1900-01-01 00:00:00+00'
+);
+
+CREATE INDEX IF NOT EXISTS notifications_due_idx ON notifications (status, next_attempt_at);
+
+CREATE TABLE IF NOT EXISTS notification_attempts (
+  id uuid PRIMARY KEY NOT NULL DEFAULT uuid_generate_v4(),
+  notification_id uuid NOT NULL REFERENCES notifications ON DELETE CASCADE,
+  attempt integer NOT NULL,
+  success BOOLEAN NOT NULL,
+  error TEXT,
+  duration bigint NOT NULL DEFAULT 0, -- nanoseconds
+  created_at timestamp with time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS notification_attempts_notification_idx ON notification_attempts (notification_id);
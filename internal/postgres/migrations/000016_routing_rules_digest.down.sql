@@ -0,0 +1 @@
+ALTER TABLE routing_rules DROP COLUMN IF EXISTS digest;
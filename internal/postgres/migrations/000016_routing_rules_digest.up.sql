@@ -0,0 +1 @@
+ALTER TABLE routing_rules ADD COLUMN IF NOT EXISTS digest bigint NOT NULL DEFAULT 0; -- nanoseconds
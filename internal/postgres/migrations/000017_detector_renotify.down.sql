@@ -0,0 +1,2 @@
+ALTER TABLE detectors DROP COLUMN IF EXISTS renotify_interval;
+ALTER TABLE detectors DROP COLUMN IF EXISTS renotify_max;
@@ -0,0 +1,2 @@
+ALTER TABLE detectors ADD COLUMN IF NOT EXISTS renotify_interval varchar;
+ALTER TABLE detectors ADD COLUMN IF NOT EXISTS renotify_max integer NOT NULL DEFAULT 0;
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS notification_templates (
+  id uuid PRIMARY KEY NOT NULL DEFAULT uuid_generate_v4(),
+  lookup_version bigint NOT NULL DEFAULT 1,
+  name varchar NOT NULL,
+  channel varchar NOT NULL,
+  type varchar NOT NULL DEFAULT '', -- empty for all types
+  active BOOLEAN NOT NULL DEFAULT true,
+  subject TEXT NOT NULL DEFAULT '',
+  body TEXT NOT NULL,
+  html TEXT NOT NULL DEFAULT '',
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  updated_at timestamp(0) with time zone NOT NULL DEFAULT '1900-01-01 00:00:00+00',
+  UNIQUE (channel, type)
+);
@@ -0,0 +1,2 @@
+ALTER TABLE routing_rules DROP COLUMN IF EXISTS webhooks;
+DROP TABLE IF EXISTS webhooks;
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS webhooks (
+  id uuid PRIMARY KEY NOT NULL DEFAULT uuid_generate_v4(),
+  lookup_version bigint NOT NULL DEFAULT 1,
+  name varchar UNIQUE NOT NULL,
+  url varchar NOT NULL,
+  active BOOLEAN NOT NULL DEFAULT true,
+  headers JSONB,
+  body TEXT NOT NULL DEFAULT '',
+  timeout bigint NOT NULL DEFAULT 0, -- nanoseconds
+  secret_crypt varchar,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  updated_at timestamp(0) with time zone NOT NULL DEFAULT '1900-01-01 00:00:00+00'
+);
+
+ALTER TABLE routing_rules ADD COLUMN IF NOT EXISTS webhooks uuid[];
@@ -0,0 +1,2 @@
+ALTER TABLE recipients DROP COLUMN IF EXISTS ntfy_topic;
+ALTER TABLE recipients DROP COLUMN IF EXISTS gotify_token_crypt;
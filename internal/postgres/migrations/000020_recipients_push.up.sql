@@ -0,0 +1,2 @@
+ALTER TABLE recipients ADD COLUMN IF NOT EXISTS ntfy_topic varchar;
+ALTER TABLE recipients ADD COLUMN IF NOT EXISTS gotify_token_crypt varchar;
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS notification_channels (
+  id uuid PRIMARY KEY NOT NULL DEFAULT uuid_generate_v4(),
+  lookup_version bigint NOT NULL DEFAULT 1,
+  type varchar NOT NULL,
+  name varchar UNIQUE NOT NULL,
+  enabled BOOLEAN NOT NULL DEFAULT true,
+  settings JSONB,
+  secrets JSONB, -- encrypted values
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  updated_at timestamp(0) with time zone NOT NULL DEFAULT '1900-01-01 00:00:00+00'
+);
@@ -0,0 +1 @@
+ALTER TABLE oncall_schedules DROP COLUMN IF EXISTS fallback_recipient_id;
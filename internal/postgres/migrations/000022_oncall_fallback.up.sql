@@ -0,0 +1 @@
+ALTER TABLE oncall_schedules ADD COLUMN IF NOT EXISTS fallback_recipient_id uuid REFERENCES recipients ON DELETE SET NULL;
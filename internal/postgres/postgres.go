@@ -12,13 +12,24 @@ import (
 )
 
 type PostgresDB struct {
-	db          *bun.DB
-	Users       UserModel
-	Hosts       HostModel
-	Detectors   DetectorModel
-	Recipients  RecipientModel
-	Preferences PreferenceModel
-	Sessions    SessionModel
+	db            *bun.DB
+	Users         UserModel
+	Hosts         HostModel
+	Detectors     DetectorModel
+	Recipients    RecipientModel
+	Preferences   PreferenceModel
+	Sessions      SessionModel
+	History       HistoryModel
+	SLOs          SLOModel
+	Incidents     IncidentModel
+	Maintenance   MaintenanceModel
+	Escalations   EscalationModel
+	OnCall        OnCallModel
+	Routing       RoutingModel
+	Notifications NotificationModel
+	Templates     TemplateModel
+	Webhooks      WebhookModel
+	Channels      NotificationChannelModel
 }
 
 func New(dsn string) (*PostgresDB, error) {
@@ -37,13 +48,24 @@ func New(dsn string) (*PostgresDB, error) {
 	}
 
 	return &PostgresDB{
-		db:          db,
-		Users:       UserModel{db: db, log: logger.New("user_repo")},
-		Hosts:       HostModel{db: db, log: logger.New("host_repo")},
-		Detectors:   DetectorModel{db: db, log: logger.New("detector_repo")},
-		Recipients:  RecipientModel{db: db, log: logger.New("recipient_repo")},
-		Preferences: PreferenceModel{db: db, log: logger.New("preferences_repo")},
-		Sessions:    SessionModel{db: db, log: logger.New("session_repo")},
+		db:            db,
+		Users:         UserModel{db: db, log: logger.New("user_repo")},
+		Hosts:         HostModel{db: db, log: logger.New("host_repo")},
+		Detectors:     DetectorModel{db: db, log: logger.New("detector_repo")},
+		Recipients:    RecipientModel{db: db, log: logger.New("recipient_repo")},
+		Preferences:   PreferenceModel{db: db, log: logger.New("preferences_repo")},
+		Sessions:      SessionModel{db: db, log: logger.New("session_repo")},
+		History:       HistoryModel{db: db, log: logger.New("history_repo"), ResultLimit: defaultResultLogLimit},
+		SLOs:          SLOModel{db: db, log: logger.New("slo_repo")},
+		Incidents:     IncidentModel{db: db, log: logger.New("incident_repo")},
+		Maintenance:   MaintenanceModel{db: db, log: logger.New("maintenance_repo")},
+		Escalations:   EscalationModel{db: db, log: logger.New("escalation_repo")},
+		OnCall:        OnCallModel{db: db, log: logger.New("oncall_repo")},
+		Routing:       RoutingModel{db: db, log: logger.New("routing_repo")},
+		Notifications: NotificationModel{db: db, log: logger.New("notification_repo")},
+		Templates:     TemplateModel{db: db, log: logger.New("template_repo")},
+		Webhooks:      WebhookModel{db: db, log: logger.New("webhook_repo")},
+		Channels:      NotificationChannelModel{db: db, log: logger.New("channel_repo")},
 	}, nil
 }
 
@@ -15,6 +15,11 @@ type Recipient struct {
 	Name          string    `json:"first_name"`
 	Activated     bool      `json:"activated"`
 	Email         string    `json:"email"`
-	CreatedAt     time.Time `json:"created_at"`
-	UpdatedAt     time.Time `json:"updated_at"`
+	// NtfyTopic is the ntfy topic name or topic url for the push notifications, optional
+	NtfyTopic string `json:"ntfy_topic" bun:",nullzero"`
+	// GotifyTokenCrypt is the encrypted Gotify app token for the push notifications, optional
+	GotifyTokenCrypt string    `json:"-" bun:",nullzero"`
+	HasGotifyToken   bool      `json:"has_gotify_token" bun:"-"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
 }
@@ -0,0 +1,127 @@
+package report
+
+import (
+	"context"
+	"time"
+
+	es "github.com/alexjoedt/echosight/internal"
+	"github.com/alexjoedt/echosight/internal/filter"
+	"github.com/google/uuid"
+)
+
+// Scope selects the detectors of a report.
+// Without any field set, all detectors are part of the report.
+type Scope struct {
+	DetectorID *uuid.UUID
+	HostID     *uuid.UUID
+	Tag        *string
+}
+
+// Exclusions returns the time ranges of a detector which are excluded from the uptime,
+// e.g. maintenance windows
+type Exclusions interface {
+	Excluded(d *es.Detector, from time.Time, to time.Time) []es.TimeRange
+}
+
+// Reporter calculates reports from the persisted state history
+type Reporter struct {
+	Detectors es.DetectorService
+	History   es.HistoryService
+	// Exclusions is optional
+	Exclusions Exclusions
+}
+
+// Uptime calculates the uptime report for each detector in the scope
+// and the merged report over all detectors
+func (r *Reporter) Uptime(ctx context.Context, scope Scope, from time.Time, to time.Time) (*es.UptimeReport, []*es.UptimeReport, error) {
+	detectors, err := r.ScopeDetectors(ctx, scope)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	reports := make([]*es.UptimeReport, 0, len(detectors))
+	for _, d := range detectors {
+		report, err := r.DetectorUptime(ctx, d, from, to)
+		if err != nil {
+			return nil, nil, err
+		}
+		reports = append(reports, report)
+	}
+
+	total := es.MergeUptimeReports(from, to, reports...)
+	total.HostID = scope.HostID
+	total.DetectorID = scope.DetectorID
+	if scope.Tag != nil {
+		total.Tag = *scope.Tag
+	}
+
+	return total, reports, nil
+}
+
+// DetectorUptime calculates the uptime report of a single detector
+func (r *Reporter) DetectorUptime(ctx context.Context, d *es.Detector, from time.Time, to time.Time) (*es.UptimeReport, error) {
+	initial, changes, err := r.StateChanges(ctx, d.ID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	var excluded []es.TimeRange
+	if r.Exclusions != nil {
+		excluded = r.Exclusions.Excluded(d, from, to)
+	}
+
+	report := es.ComputeUptime(initial, changes, from, to, excluded)
+	report.DetectorID = &d.ID
+	report.DetectorName = d.Name
+	report.HostID = &d.HostID
+	report.HostName = d.HostName
+	return report, nil
+}
+
+// StateChanges returns the state at the beginning of the time range
+// and all state changes within the time range sorted by time
+func (r *Reporter) StateChanges(ctx context.Context, detectorID uuid.UUID, from time.Time, to time.Time) (es.State, []*es.StateChange, error) {
+	lastFilter := filter.NewDefaultHistoryFilter()
+	lastFilter.DetectorID = &detectorID
+	lastFilter.To = &from
+	lastFilter.PageSize = 1
+	lastFilter.Sort = "-created_at"
+	last, err := r.History.ListStateChanges(ctx, lastFilter)
+	if err != nil {
+		return "", nil, err
+	}
+
+	initial := es.StateInactive
+	if len(last) > 0 {
+		initial = last[0].ToState
+	}
+
+	rangeFilter := filter.NewDefaultHistoryFilter()
+	rangeFilter.DetectorID = &detectorID
+	rangeFilter.From = &from
+	rangeFilter.To = &to
+	rangeFilter.PageSize = filter.NewDefaultFilter().PageSize
+	rangeFilter.Sort = "created_at"
+	changes, err := r.History.ListStateChanges(ctx, rangeFilter)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return initial, changes, nil
+}
+
+// ScopeDetectors returns all detectors of the scope
+func (r *Reporter) ScopeDetectors(ctx context.Context, scope Scope) ([]*es.Detector, error) {
+	if scope.DetectorID != nil {
+		d, err := r.Detectors.GetByID(ctx, *scope.DetectorID)
+		if err != nil {
+			return nil, err
+		}
+		return []*es.Detector{d}, nil
+	}
+
+	detectorFilter := filter.NewDefaultDetectorFilter()
+	detectorFilter.HostID = scope.HostID
+	detectorFilter.Tag = scope.Tag
+	return r.Detectors.List(ctx, detectorFilter)
+}
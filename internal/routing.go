@@ -0,0 +1,124 @@
+package echosight
+
+import (
+	"slices"
+	"time"
+
+	"github.com/alexjoedt/echosight/internal/validator"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// RoutingRule routes the notifications of matching results to channels and recipients.
+// The rules are evaluated by position, a matching rule without Continue stops the evaluation.
+// Empty match fields match everything. Without a matching rule, the notification is sent
+// to all channels and recipients.
+type RoutingRule struct {
+	bun.BaseModel `bun:"table:routing_rules"`
+	ID            uuid.UUID `json:"id" bun:"type:uuid,pk,default:uuid_generate_v4()"`
+	LookupVersion int       `json:"lookupVersion" bun:",default:1"`
+	Name          string    `json:"name"`
+	Position      int       `json:"position"`
+	Active        bool      `json:"active"`
+	// Continue evaluates the next rules after a match
+	Continue bool `json:"continue"`
+
+	HostIDs     []uuid.UUID `json:"hostIds" bun:"type:uuid[],array"`
+	DetectorIDs []uuid.UUID `json:"detectorIds" bun:"type:uuid[],array"`
+	// Tags matches detectors with at least one of the tags
+	Tags   []string `json:"tags" bun:",array"`
+	States []State  `json:"states" bun:",array"`
+	// TimeOfDay matches results within the daily time range in the timezone
+	TimeOfDay *ClockRange `json:"timeOfDay,omitempty" bun:"type:jsonb"`
+	Timezone  string      `json:"timezone"`
+
+	// Channels are the ids of the notification senders, all if empty
+	Channels []string `json:"channels" bun:",array"`
+	// Recipients are the ids of the mail recipients
+	Recipients []uuid.UUID `json:"recipients" bun:"type:uuid[],array"`
+	// Schedules are the ids of on-call schedules, whoever is on call is notified
+	Schedules []uuid.UUID `json:"schedules" bun:"type:uuid[],array"`
+	// Webhooks are the ids of the webhooks, all if empty. Limit the channels
+	// to the webhook channel to notify only the webhooks.
+	Webhooks []uuid.UUID `json:"webhooks" bun:"type:uuid[],array"`
+	// Digest sends a periodic summary of the non-OK detectors instead of every result,
+	// e.g. an hourly digest for low-priority detectors. Zero sends every result.
+	Digest Duration `json:"digest"`
+
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Matches reports if the rule applies to the result of the detector at t
+func (rr *RoutingRule) Matches(d *Detector, state State, t time.Time) bool {
+	if !rr.Active {
+		return false
+	}
+
+	if len(rr.HostIDs) > 0 && !slices.Contains(rr.HostIDs, d.HostID) {
+		return false
+	}
+
+	if len(rr.DetectorIDs) > 0 && !slices.Contains(rr.DetectorIDs, d.ID) {
+		return false
+	}
+
+	if len(rr.Tags) > 0 && !slices.ContainsFunc(rr.Tags, func(tag string) bool {
+		return slices.Contains(d.Tags, tag)
+	}) {
+		return false
+	}
+
+	if len(rr.States) > 0 && !slices.Contains(rr.States, state) {
+		return false
+	}
+
+	if rr.TimeOfDay != nil {
+		loc, err := time.LoadLocation(rr.Timezone)
+		if err != nil {
+			loc = time.UTC
+		}
+		if !rr.TimeOfDay.contains(t.In(loc)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// MatchRoutingRules returns the matching rules until the first match without Continue.
+// The rules must be sorted by position.
+func MatchRoutingRules(rules []*RoutingRule, d *Detector, state State, t time.Time) []*RoutingRule {
+	var matched []*RoutingRule
+	for _, rule := range rules {
+		if !rule.Matches(d, state, t) {
+			continue
+		}
+
+		matched = append(matched, rule)
+		if !rule.Continue {
+			break
+		}
+	}
+	return matched
+}
+
+func ValidateRoutingRule(v *validator.Validator, rr *RoutingRule) {
+	v.Check(len(rr.Name) > 3, "name", "name too short")
+	v.Check(rr.Position >= 0, "position", "must not be negative")
+
+	for _, state := range rr.States {
+		v.Check(validator.PermittedValue(state, StateOK, StateWarn, StateCritical), "states", "must be OK, WARN or CRITICAL")
+	}
+
+	_, err := time.LoadLocation(rr.Timezone)
+	v.Check(err == nil, "timezone", "invalid timezone")
+
+	v.Check(rr.Digest == 0 || time.Duration(rr.Digest) >= time.Minute, "digest", "must be at least 1m")
+
+	if rr.TimeOfDay != nil {
+		_, _, err := rr.TimeOfDay.parse()
+		v.Check(err == nil, "timeOfDay", "start and end must be in the format HH:MM")
+		v.Check(rr.TimeOfDay.Start != rr.TimeOfDay.End, "timeOfDay", "start and end must differ")
+	}
+}
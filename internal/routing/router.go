@@ -0,0 +1,256 @@
+package routing
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	es "github.com/alexjoedt/echosight/internal"
+	"github.com/alexjoedt/echosight/internal/filter"
+	"github.com/alexjoedt/echosight/internal/logger"
+	"github.com/alexjoedt/echosight/internal/notify"
+	"github.com/alexjoedt/echosight/internal/oncall"
+	"github.com/google/uuid"
+)
+
+// digestInterval is the interval to check the rules for due digests
+const digestInterval time.Duration = time.Minute
+
+// Router sends the notifications of results through the matching routing rules.
+// It holds all active rules in memory and must be reloaded after a rule has changed.
+//
+// Rules with a digest don't send the results, the non-OK detectors of the rule
+// are summarized periodically instead.
+type Router struct {
+	service  es.RoutingService
+	notifier *notify.Notifier
+	log      *logger.Logger
+
+	mu    sync.RWMutex
+	rules []*es.RoutingRule
+
+	// lastDigest is the time of the last digest by rule
+	lastDigest map[uuid.UUID]time.Time
+	cancel     context.CancelFunc
+	wg         sync.WaitGroup
+
+	// OnCall resolves the on-call schedules of the rules, optional
+	OnCall *oncall.Resolver
+	// Detectors are required for the digests
+	Detectors es.DetectorService
+}
+
+func NewRouter(rs es.RoutingService, n *notify.Notifier) *Router {
+	return &Router{
+		service:    rs,
+		notifier:   n,
+		log:        logger.New("Router"),
+		lastDigest: make(map[uuid.UUID]time.Time),
+	}
+}
+
+// Reload loads all active rules sorted by position
+func (r *Router) Reload(ctx context.Context) error {
+	f := filter.NewDefaultRoutingFilter()
+	active := true
+	f.Active = &active
+
+	rules, err := r.service.List(ctx, f)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.rules = rules
+	r.mu.Unlock()
+	return nil
+}
+
+// Match returns the rules which apply to the detector with the state at t
+func (r *Router) Match(d *es.Detector, state es.State, t time.Time) []*es.RoutingRule {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return es.MatchRoutingRules(r.rules, d, state, t)
+}
+
+// Notify sends the result to the channels and recipients of the matching rules.
+// Without a matching rule, the result is sent to all senders and recipients.
+func (r *Router) Notify(ctx context.Context, d *es.Detector, result *es.Result) error {
+	rules := r.Match(d, result.State, time.Now())
+	if len(rules) == 0 {
+		return r.notifier.Send(ctx, result)
+	}
+
+	var sendErrors []error
+	for _, rule := range rules {
+		// the result is part of the next digest
+		if rule.Digest > 0 {
+			continue
+		}
+
+		if err := r.SendTo(ctx, rule, result); err != nil {
+			sendErrors = append(sendErrors, err)
+		}
+	}
+
+	return errors.Join(sendErrors...)
+}
+
+// NotifyDetector sends the result through the matching rules of the detector, e.g. for incident or SLO notifications.
+// Without a detector, the result is sent to all senders and recipients.
+func (r *Router) NotifyDetector(ctx context.Context, detectorID uuid.UUID, result *es.Result) error {
+	if detectorID == uuid.Nil || r.Detectors == nil {
+		return r.notifier.Send(ctx, result)
+	}
+
+	d, err := r.Detectors.GetByID(ctx, detectorID)
+	if err != nil {
+		r.log.Errorf("failed to get detector %s for routing, sending to all: %v", detectorID, err)
+		return r.notifier.Send(ctx, result)
+	}
+
+	return r.Notify(ctx, d, result)
+}
+
+// SendTo sends the result to the channels, recipients, on-call schedules and webhooks of the rule.
+// The rule doesn't have to be stored, e.g. an escalation level is sent as rule.
+// If the rule has schedules, but nobody is on call, the result isn't sent.
+func (r *Router) SendTo(ctx context.Context, rule *es.RoutingRule, result *es.Result) error {
+	recipients, err := r.recipients(ctx, rule)
+	if err != nil {
+		r.log.Errorf("failed to resolve recipients of routing rule %s: %v", rule.Name, err)
+	}
+
+	// an empty list is sent to all recipients, the rule must only reach whoever is on call
+	if len(rule.Schedules) > 0 && len(recipients) == 0 {
+		r.log.Warnf("routing rule %s skipped, nobody is on call", rule.Name)
+		return nil
+	}
+
+	// every rule gets its own copy, the senders may queue the result
+	routed := *result
+	routed.Recipients = recipients
+	routed.Webhooks = rule.Webhooks
+	return r.notifier.SendTo(ctx, rule.Channels, &routed)
+}
+
+// recipients returns the recipients and whoever is on call for the schedules of the rule.
+// Without recipients and schedules, the rule notifies all recipients.
+func (r *Router) recipients(ctx context.Context, rule *es.RoutingRule) ([]uuid.UUID, error) {
+	recipients := slices.Clone(rule.Recipients)
+	if len(rule.Schedules) == 0 {
+		return recipients, nil
+	}
+
+	onCall, err := r.OnCall.Recipients(ctx, rule.Schedules)
+	return append(recipients, onCall...), err
+}
+
+// Start sends the due digests in the background until Stop is called
+func (r *Router) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+
+		ticker := time.NewTicker(digestInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case now := <-ticker.C:
+				r.SendDigests(ctx, now)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Stop blocks until the running digests are sent
+func (r *Router) Stop() {
+	if r.cancel == nil {
+		return
+	}
+	r.cancel()
+	r.wg.Wait()
+}
+
+// SendDigests sends the digests of the rules whose interval has passed.
+// The first digest of a rule is sent one interval after the start.
+func (r *Router) SendDigests(ctx context.Context, now time.Time) {
+	if r.Detectors == nil {
+		return
+	}
+
+	var due []*es.RoutingRule
+	r.mu.Lock()
+	for _, rule := range r.rules {
+		if rule.Digest == 0 {
+			continue
+		}
+
+		last, ok := r.lastDigest[rule.ID]
+		if !ok {
+			r.lastDigest[rule.ID] = now
+			continue
+		}
+
+		if now.Sub(last) >= time.Duration(rule.Digest) {
+			r.lastDigest[rule.ID] = now
+			due = append(due, rule)
+		}
+	}
+	r.mu.Unlock()
+
+	if len(due) == 0 {
+		return
+	}
+
+	f := filter.NewDefaultDetectorFilter()
+	active := true
+	f.Active = &active
+	detectors, err := r.Detectors.List(ctx, f)
+	if err != nil {
+		r.log.Errorf("failed to list detectors for digests: %v", err)
+		return
+	}
+
+	for _, rule := range due {
+		if err := r.sendDigest(ctx, rule, detectors, now); err != nil {
+			r.log.Errorf("failed to send digest of routing rule %s: %v", rule.Name, err)
+		}
+	}
+}
+
+// sendDigest sends the non-OK detectors which match the rule, nothing if all are OK
+func (r *Router) sendDigest(ctx context.Context, rule *es.RoutingRule, detectors []*es.Detector, now time.Time) error {
+	var results []*es.Result
+	for _, d := range detectors {
+		if d.InMaintenance || (d.HardState != es.StateWarn && d.HardState != es.StateCritical) {
+			continue
+		}
+
+		if !rule.Matches(d, d.HardState, now) {
+			continue
+		}
+
+		results = append(results, &es.Result{
+			Host:     d.HostName,
+			Detector: d.Name,
+			State:    d.HardState,
+			Message:  d.StatusMessage,
+		})
+	}
+
+	if len(results) == 0 {
+		return nil
+	}
+
+	// the digest is skipped like any result of the rule, if nobody is on call
+	return r.SendTo(ctx, rule, es.CombineResults(es.NotificationDigest, rule.Name, results))
+}
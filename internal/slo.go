@@ -0,0 +1,231 @@
+package echosight
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/alexjoedt/echosight/internal/validator"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+const (
+	EventSLOStatus = "slo_status"
+
+	DefaultSLOWindow Duration = Duration(28 * 24 * time.Hour)
+)
+
+type SLOType string
+
+const (
+	// SLOAvailability measures the time a detector is not CRITICAL
+	SLOAvailability SLOType = "availability"
+	// SLOThreshold measures the ratio of checks where the metric field matches the threshold
+	SLOThreshold SLOType = "threshold"
+)
+
+// BurnRateAlert is a multi-window burn-rate alert.
+// It fires if the burn rate of the long and the short window exceeds the factor.
+// Source: https://sre.google/workbook/alerting-on-slos/
+type BurnRateAlert struct {
+	Name         NotificationType
+	State        State
+	LongWindow   time.Duration
+	ShortWindow  time.Duration
+	BurnRateOver float64
+}
+
+var (
+	// FastBurnAlert consumes 2% of a 30 day budget within an hour
+	FastBurnAlert = BurnRateAlert{
+		Name:         NotificationSLOFastBurn,
+		State:        StateCritical,
+		LongWindow:   time.Hour,
+		ShortWindow:  time.Minute * 5,
+		BurnRateOver: 14.4,
+	}
+
+	// SlowBurnAlert consumes 5% of a 30 day budget within 6 hours
+	SlowBurnAlert = BurnRateAlert{
+		Name:         NotificationSLOSlowBurn,
+		State:        StateWarn,
+		LongWindow:   time.Hour * 6,
+		ShortWindow:  time.Minute * 30,
+		BurnRateOver: 6,
+	}
+
+	BurnRateAlerts = []BurnRateAlert{FastBurnAlert, SlowBurnAlert}
+)
+
+// SLO is a service level objective for a detector or all detectors with a tag,
+// e.g. 99.9% availability or 95% of the checks with response_time < 500 over 28 days.
+type SLO struct {
+	bun.BaseModel `bun:"table:slos"`
+	ID            uuid.UUID  `json:"id" bun:"type:uuid,pk,default:uuid_generate_v4()"`
+	LookupVersion int        `json:"lookupVersion" bun:",default:1"`
+	Name          string     `json:"name"`
+	DetectorID    *uuid.UUID `json:"detectorId,omitempty" bun:"type:uuid"`
+	Tag           string     `json:"tag,omitempty" bun:",nullzero"`
+	Type          SLOType    `json:"type"`
+	// Objective in percent, e.g. 99.9
+	Objective float64  `json:"objective"`
+	Window    Duration `json:"window"`
+
+	// Threshold defines the good checks for the threshold type
+	Threshold *ThresholdRule `json:"threshold,omitempty" bun:"type:jsonb"`
+
+	// Alerts enables the burn-rate notifications
+	Alerts bool `json:"alerts"`
+
+	Status SLOStatus `json:"status" bun:"type:jsonb"`
+
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// ErrorBudget returns the allowed ratio of bad events, e.g. 0.001 for 99.9%
+func (s *SLO) ErrorBudget() float64 {
+	return 1 - s.Objective/100
+}
+
+// BurnRate returns how fast the error budget is consumed with the given bad ratio.
+// A burn rate of 1 consumes the whole budget exactly within the window.
+func (s *SLO) BurnRate(badRatio float64) float64 {
+	budget := s.ErrorBudget()
+	if budget <= 0 {
+		return 0
+	}
+	return badRatio / budget
+}
+
+// BadRatioFunc returns the ratio of bad events within the window until now
+// and false if there are no events within the window
+type BadRatioFunc func(window time.Duration) (float64, bool)
+
+// ComputeStatus calculates the SLI, the remaining error budget and the burn rates
+// of the alert windows. The alert state is set if all windows of the alert exceed the burn rate.
+func (s *SLO) ComputeStatus(badRatio BadRatioFunc, now time.Time) SLOStatus {
+	status := SLOStatus{
+		SLI:             100,
+		BudgetRemaining: 100,
+		BurnRates:       make(map[string]float64),
+		UpdatedAt:       now,
+	}
+
+	if bad, ok := badRatio(time.Duration(s.Window)); ok {
+		status.SLI = (1 - bad) * 100
+		status.BudgetRemaining = (1 - s.BurnRate(bad)) * 100
+	}
+
+	burnRate := func(window time.Duration) float64 {
+		key := windowName(window)
+		if rate, ok := status.BurnRates[key]; ok {
+			return rate
+		}
+
+		bad, _ := badRatio(window)
+		rate := s.BurnRate(bad)
+		status.BurnRates[key] = rate
+		return rate
+	}
+
+	for _, alert := range BurnRateAlerts {
+		firing := burnRate(alert.LongWindow) > alert.BurnRateOver &&
+			burnRate(alert.ShortWindow) > alert.BurnRateOver
+
+		switch alert.Name {
+		case NotificationSLOFastBurn:
+			status.FastBurn = firing
+		case NotificationSLOSlowBurn:
+			status.SlowBurn = firing
+		}
+	}
+
+	return status
+}
+
+// ThresholdBadRatio returns the ratio of points since the given time,
+// which doesn't match the threshold of the SLO.
+func (s *SLO) ThresholdBadRatio(points []MetricPoint, since time.Time) (float64, bool) {
+	if s.Threshold == nil {
+		return 0, false
+	}
+
+	var total, bad int
+	for _, p := range points {
+		if p.Time.Before(since) {
+			continue
+		}
+
+		value, ok := toFloat(p.Fields[s.Threshold.Field])
+		if !ok {
+			continue
+		}
+
+		total++
+		if !s.Threshold.Operator.compare(value, float64(s.Threshold.Value)) {
+			bad++
+		}
+	}
+
+	if total == 0 {
+		return 0, false
+	}
+	return float64(bad) / float64(total), true
+}
+
+// windowName formats a window like 5m, 1h or 28d
+func windowName(d time.Duration) string {
+	switch {
+	case d%(24*time.Hour) == 0:
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	case d%time.Hour == 0:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	case d%time.Minute == 0:
+		return fmt.Sprintf("%dm", d/time.Minute)
+	}
+	return d.String()
+}
+
+// SLOStatus is the current status of an SLO
+type SLOStatus struct {
+	// SLI is the measured ratio of good events within the window in percent
+	SLI float64 `json:"sli"`
+	// BudgetRemaining is the remaining error budget in percent, negative if exhausted
+	BudgetRemaining float64 `json:"budgetRemaining"`
+	// BurnRates of the alert windows, e.g. "1h": 2.5
+	BurnRates map[string]float64 `json:"burnRates"`
+	FastBurn  bool               `json:"fastBurn"`
+	SlowBurn  bool               `json:"slowBurn"`
+	UpdatedAt time.Time          `json:"updatedAt"`
+}
+
+// Alerting reports if a burn-rate alert is firing
+func (s SLOStatus) Alerting() bool {
+	return s.FastBurn || s.SlowBurn
+}
+
+// SLOEvent is published on the topic of the SLO
+type SLOEvent struct {
+	SLOID        string
+	Name         string
+	Objective    float64
+	Status       SLOStatus
+	Notification NotificationType `json:",omitempty"`
+}
+
+func ValidateSLO(v *validator.Validator, slo *SLO) {
+	v.Check(len(slo.Name) > 3, "name", "name too short")
+	v.Check((slo.DetectorID != nil) != (slo.Tag != ""), "detectorId", "either detectorId or tag must be provided")
+	v.Check(validator.PermittedValue(slo.Type, SLOAvailability, SLOThreshold), "type", "must be availability or threshold")
+	v.Check(slo.Objective > 0 && slo.Objective < 100, "objective", "must be between 0 and 100")
+	v.Check(slo.Window >= Duration(time.Hour), "window", "must be at least 1h")
+
+	if slo.Type == SLOThreshold {
+		v.Check(slo.Threshold != nil, "threshold", "must be provided for type threshold")
+		if slo.Threshold != nil {
+			v.Check(slo.Threshold.Field != "", "threshold.field", "must be provided")
+			v.Check(validator.PermittedValue(slo.Threshold.Operator, thresholdOperators...), "threshold.operator", "invalid operator")
+		}
+	}
+}
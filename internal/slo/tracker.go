@@ -0,0 +1,367 @@
+package slo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	es "github.com/alexjoedt/echosight/internal"
+	"github.com/alexjoedt/echosight/internal/eventflow"
+	"github.com/alexjoedt/echosight/internal/filter"
+	"github.com/alexjoedt/echosight/internal/logger"
+	"github.com/alexjoedt/echosight/internal/notify"
+	"github.com/alexjoedt/echosight/internal/report"
+	"github.com/alexjoedt/echosight/internal/routing"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultInterval time.Duration = time.Minute
+	evaluateTimeout time.Duration = time.Second * 30
+	// maxCacheAge limits the age of the cached bad ratio of a window
+	maxCacheAge time.Duration = time.Minute * 15
+)
+
+// ratio is the bad ratio of a window at the time it was calculated, ok is false without data
+type ratio struct {
+	value float64
+	ok    bool
+	at    time.Time
+}
+
+// windowCache holds the bad ratios of the windows of an SLO version
+type windowCache struct {
+	version int
+	ratios  map[time.Duration]ratio
+}
+
+// cacheAge returns how long the bad ratio of the window is reused. A long window like
+// 28d barely changes within minutes, the short alert windows are calculated every time.
+func cacheAge(window time.Duration) time.Duration {
+	return min(window/100, maxCacheAge)
+}
+
+// Tracker evaluates the error budget of all SLOs periodically,
+// sends the burn-rate notifications and publishes the status on the topic of the SLO.
+// The SLOs are only evaluated by the loop of the tracker, so a burn-rate transition is notified once.
+type Tracker struct {
+	slos         es.SLOService
+	reporter     *report.Reporter
+	metricReader es.MetricReader
+	eventHandler *eventflow.Engine
+	notifier     *notify.Notifier
+	log          *logger.Logger
+
+	mu     sync.Mutex
+	topics map[string]struct{}
+
+	// cache is only used by the evaluation loop
+	cache map[uuid.UUID]*windowCache
+
+	stop    chan struct{}
+	trigger chan struct{}
+	wg      sync.WaitGroup
+
+	// Interval between two evaluations
+	Interval time.Duration
+	// Routing sends the notifications through the routing rules, optional
+	Routing *routing.Router
+}
+
+func NewTracker(slos es.SLOService, reporter *report.Reporter, mr es.MetricReader, eh *eventflow.Engine, n *notify.Notifier) *Tracker {
+	return &Tracker{
+		slos:         slos,
+		reporter:     reporter,
+		metricReader: mr,
+		eventHandler: eh,
+		notifier:     n,
+		log:          logger.New("SLO-Tracker"),
+		topics:       make(map[string]struct{}),
+		cache:        make(map[uuid.UUID]*windowCache),
+		trigger:      make(chan struct{}, 1),
+		Interval:     defaultInterval,
+	}
+}
+
+// Start evaluates all SLOs in the background until Stop is called
+func (t *Tracker) Start() {
+	t.stop = make(chan struct{})
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+
+		ticker := time.NewTicker(t.Interval)
+		defer ticker.Stop()
+
+		for {
+			t.evaluateAll()
+
+			select {
+			case <-ticker.C:
+			case <-t.trigger:
+			case <-t.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Trigger evaluates all SLOs without waiting for the next interval, e.g. after an SLO has changed.
+// It doesn't block, a pending trigger isn't repeated.
+func (t *Tracker) Trigger() {
+	select {
+	case t.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// Stop blocks until the running evaluation is done
+func (t *Tracker) Stop() {
+	if t.stop == nil {
+		return
+	}
+	close(t.stop)
+	t.wg.Wait()
+	t.stop = nil
+}
+
+// evaluateAll evaluates all SLOs and closes the topics of deleted SLOs
+func (t *Tracker) evaluateAll() {
+	ctx, cancel := context.WithTimeout(context.Background(), evaluateTimeout)
+	defer cancel()
+
+	slos, err := t.slos.List(ctx, filter.NewDefaultSLOFilter())
+	if err != nil {
+		t.log.Errorf("failed to list slos: %v", err)
+		return
+	}
+
+	active := make(map[string]struct{}, len(slos))
+	for _, slo := range slos {
+		active[slo.ID.String()] = struct{}{}
+		if err := t.evaluate(ctx, slo); err != nil {
+			t.log.Errorf("failed to evaluate slo '%s': %v", slo.Name, err)
+		}
+	}
+
+	for id := range t.cache {
+		if _, ok := active[id.String()]; !ok {
+			delete(t.cache, id)
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for id := range t.topics {
+		if _, ok := active[id]; !ok {
+			t.eventHandler.CloseTopic(id)
+			delete(t.topics, id)
+		}
+	}
+}
+
+// evaluate calculates the status of the SLO, persists it, sends a notification
+// if a burn-rate alert starts or ends and publishes the status.
+func (t *Tracker) evaluate(ctx context.Context, slo *es.SLO) error {
+	now := time.Now()
+
+	badRatio, err := t.badRatioFunc(ctx, slo, now)
+	if err != nil {
+		return err
+	}
+
+	previous := slo.Status
+	slo.Status = slo.ComputeStatus(badRatio, now)
+	if err := t.slos.UpdateStatus(ctx, slo); err != nil {
+		return err
+	}
+
+	notification, ok := t.notification(slo, previous)
+	if ok && slo.Alerts {
+		if err := t.send(ctx, slo, newResult(slo, notification)); err != nil {
+			t.log.Errorf("failed to send notifications: %v", err)
+		}
+	}
+
+	t.publish(slo, notification)
+	return nil
+}
+
+// notification returns the notification type if a burn-rate alert started or all alerts stopped
+func (t *Tracker) notification(slo *es.SLO, previous es.SLOStatus) (es.NotificationType, bool) {
+	switch {
+	case slo.Status.FastBurn && !previous.FastBurn:
+		return es.NotificationSLOFastBurn, true
+	case slo.Status.SlowBurn && !previous.SlowBurn && !slo.Status.FastBurn:
+		return es.NotificationSLOSlowBurn, true
+	case !slo.Status.Alerting() && previous.Alerting():
+		return es.NotificationSLORecovery, true
+	}
+	return "", false
+}
+
+// badRatioFunc calculates the bad ratios of the SLO window and all alert windows
+// and returns a function to look them up. The ratios of the long windows are cached.
+func (t *Tracker) badRatioFunc(ctx context.Context, slo *es.SLO, now time.Time) (es.BadRatioFunc, error) {
+	scope := report.Scope{DetectorID: slo.DetectorID}
+	if slo.Tag != "" {
+		scope.Tag = &slo.Tag
+	}
+
+	windows := []time.Duration{time.Duration(slo.Window)}
+	for _, alert := range es.BurnRateAlerts {
+		windows = append(windows, alert.LongWindow, alert.ShortWindow)
+	}
+
+	cache, ok := t.cache[slo.ID]
+	if !ok || cache.version != slo.LookupVersion {
+		cache = &windowCache{version: slo.LookupVersion, ratios: make(map[time.Duration]ratio, len(windows))}
+		t.cache[slo.ID] = cache
+	}
+
+	var stale []time.Duration
+	for _, window := range windows {
+		r, ok := cache.ratios[window]
+		if (!ok || now.Sub(r.at) >= cacheAge(window)) && !slices.Contains(stale, window) {
+			stale = append(stale, window)
+		}
+	}
+
+	if len(stale) > 0 {
+		switch slo.Type {
+		case es.SLOThreshold:
+			// the longest stale window contains the others, the points are read once
+			points, err := t.readPoints(ctx, scope, slices.Max(stale))
+			if err != nil {
+				return nil, err
+			}
+
+			for _, window := range stale {
+				value, ok := slo.ThresholdBadRatio(points, now.Add(-window))
+				cache.ratios[window] = ratio{value: value, ok: ok, at: now}
+			}
+
+		default:
+			detectors, err := t.reporter.ScopeDetectors(ctx, scope)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, window := range stale {
+				reports := make([]*es.UptimeReport, 0, len(detectors))
+				for _, d := range detectors {
+					r, err := t.reporter.DetectorUptime(ctx, d, now.Add(-window), now)
+					if err != nil {
+						return nil, err
+					}
+					reports = append(reports, r)
+				}
+
+				total := es.MergeUptimeReports(now.Add(-window), now, reports...)
+				monitored := total.Uptime + total.Downtime
+				if monitored <= 0 {
+					cache.ratios[window] = ratio{at: now}
+					continue
+				}
+				cache.ratios[window] = ratio{value: float64(total.Downtime) / float64(monitored), ok: true, at: now}
+			}
+		}
+	}
+
+	return func(window time.Duration) (float64, bool) {
+		r := cache.ratios[window]
+		return r.value, r.ok
+	}, nil
+}
+
+// readPoints reads the metrics of all detectors in the scope within the window, sorted by time
+func (t *Tracker) readPoints(ctx context.Context, scope report.Scope, window time.Duration) ([]es.MetricPoint, error) {
+	detectors, err := t.reporter.ScopeDetectors(ctx, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	timeRange := fmt.Sprintf("-%ds", int64(window.Seconds()))
+	var points []es.MetricPoint
+	for _, d := range detectors {
+		p, err := t.metricReader.Read(ctx, d.MetricFiler(timeRange))
+		if err != nil {
+			return nil, err
+		}
+		points = append(points, p...)
+	}
+
+	sort.Slice(points, func(i, j int) bool {
+		return points[i].Time.Before(points[j].Time)
+	})
+	return points, nil
+}
+
+// publish publishes the status on the topic of the SLO, the topic is created on the first publish
+func (t *Tracker) publish(slo *es.SLO, notification es.NotificationType) {
+	topicID := slo.ID.String()
+
+	t.mu.Lock()
+	if _, ok := t.topics[topicID]; !ok {
+		if _, err := t.eventHandler.NewTopic(topicID); err != nil {
+			t.log.Errorf("failed to create topic: %v", err)
+		}
+		t.topics[topicID] = struct{}{}
+	}
+	t.mu.Unlock()
+
+	payload, err := json.Marshal(es.SLOEvent{
+		SLOID:        topicID,
+		Name:         slo.Name,
+		Objective:    slo.Objective,
+		Status:       slo.Status,
+		Notification: notification,
+	})
+	if err != nil {
+		t.log.Errorf("failed to marshal payload: %v", err)
+		return
+	}
+
+	// INFO: publish blocks until the event is read from all subscribers
+	t.eventHandler.Publish(topicID, &eventflow.Event{
+		Type:    es.EventSLOStatus,
+		Payload: payload,
+	})
+}
+
+// send sends the result through the routing rules of the detector of the SLO.
+// The SLO of a tag is routed like a detector with the tag.
+func (t *Tracker) send(ctx context.Context, slo *es.SLO, result *es.Result) error {
+	switch {
+	case t.Routing == nil:
+		return t.notifier.Send(ctx, result)
+	case slo.DetectorID != nil:
+		return t.Routing.NotifyDetector(ctx, *slo.DetectorID, result)
+	default:
+		return t.Routing.Notify(ctx, &es.Detector{Name: slo.Name, Tags: []string{slo.Tag}}, result)
+	}
+}
+
+// newResult creates the result which is sent through the notifier
+func newResult(slo *es.SLO, notification es.NotificationType) *es.Result {
+	state := es.StateOK
+	switch notification {
+	case es.NotificationSLOFastBurn:
+		state = es.FastBurnAlert.State
+	case es.NotificationSLOSlowBurn:
+		state = es.SlowBurnAlert.State
+	}
+
+	return &es.Result{
+		Host:     "SLO",
+		Detector: slo.Name,
+		State:    state,
+		Message: fmt.Sprintf("SLI %.3f%% (objective %.3f%%), %.1f%% error budget remaining",
+			slo.Status.SLI, slo.Objective, slo.Status.BudgetRemaining),
+		Notification: notification,
+	}
+}
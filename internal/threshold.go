@@ -0,0 +1,203 @@
+package echosight
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/alexjoedt/echosight/internal/validator"
+)
+
+type ThresholdOperator string
+
+const (
+	OperatorGreater      ThresholdOperator = ">"
+	OperatorGreaterEqual ThresholdOperator = ">="
+	OperatorLess         ThresholdOperator = "<"
+	OperatorLessEqual    ThresholdOperator = "<="
+	OperatorEqual        ThresholdOperator = "=="
+	OperatorNotEqual     ThresholdOperator = "!="
+)
+
+var thresholdOperators = []ThresholdOperator{
+	OperatorGreater,
+	OperatorGreaterEqual,
+	OperatorLess,
+	OperatorLessEqual,
+	OperatorEqual,
+	OperatorNotEqual,
+}
+
+// compare reports if value op threshold is true
+func (op ThresholdOperator) compare(value float64, threshold float64) bool {
+	switch op {
+	case OperatorGreater:
+		return value > threshold
+	case OperatorGreaterEqual:
+		return value >= threshold
+	case OperatorLess:
+		return value < threshold
+	case OperatorLessEqual:
+		return value <= threshold
+	case OperatorEqual:
+		return value == threshold
+	case OperatorNotEqual:
+		return value != threshold
+	}
+	return false
+}
+
+// ThresholdValue is a number. In JSON it can also be a duration string like "800ms",
+// which is converted to milliseconds, the unit of the response_time field.
+type ThresholdValue float64
+
+func (tv *ThresholdValue) UnmarshalJSON(b []byte) error {
+	var v any
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+
+	switch value := v.(type) {
+	case float64:
+		*tv = ThresholdValue(value)
+	case string:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid threshold value: '%s'", value)
+		}
+		*tv = ThresholdValue(float64(d) / float64(time.Millisecond))
+	default:
+		return fmt.Errorf("invalid threshold value")
+	}
+
+	return nil
+}
+
+// ThresholdRule evaluates a metric field of a check result,
+// e.g. response_time > 800 -> WARN
+type ThresholdRule struct {
+	Field    string            `json:"field"`
+	Operator ThresholdOperator `json:"operator"`
+	Value    ThresholdValue    `json:"value"`
+	State    State             `json:"state"`
+
+	// Hysteresis is the amount the value must fall back behind the threshold to clear the rule
+	Hysteresis float64 `json:"hysteresis,omitempty"`
+	// For is the number of consecutive checks the rule must match before it's active
+	For int `json:"for,omitempty"`
+}
+
+func (tr *ThresholdRule) String() string {
+	return fmt.Sprintf("%s %s %v", tr.Field, tr.Operator, float64(tr.Value))
+}
+
+// clearValue returns the value which must be crossed to clear an active rule
+func (tr *ThresholdRule) clearValue() float64 {
+	switch tr.Operator {
+	case OperatorGreater, OperatorGreaterEqual:
+		return float64(tr.Value) - tr.Hysteresis
+	case OperatorLess, OperatorLessEqual:
+		return float64(tr.Value) + tr.Hysteresis
+	}
+	return float64(tr.Value)
+}
+
+func ValidateThresholdRule(v *validator.Validator, key string, rule *ThresholdRule) {
+	v.Check(rule.Field != "", key+".field", "must be provided")
+	v.Check(validator.PermittedValue(rule.Operator, thresholdOperators...), key+".operator", "invalid operator")
+	v.Check(rule.State == StateWarn || rule.State == StateCritical, key+".state", "must be WARN or CRITICAL")
+	v.Check(rule.Hysteresis >= 0, key+".hysteresis", "must not be negative")
+	v.Check(rule.For >= 0, key+".for", "must not be negative")
+}
+
+type thresholdState struct {
+	matches int
+	active  bool
+}
+
+// ThresholdEvaluator evaluates the threshold rules of a detector
+// against the metric fields of every check result.
+// It keeps track of the consecutive matches and the active rules.
+type ThresholdEvaluator struct {
+	rules  []ThresholdRule
+	states []thresholdState
+}
+
+func NewThresholdEvaluator(rules []ThresholdRule) *ThresholdEvaluator {
+	return &ThresholdEvaluator{
+		rules:  rules,
+		states: make([]thresholdState, len(rules)),
+	}
+}
+
+// Evaluate sets the state of the result to the worst state of the checker
+// and all active rules. The messages of active rules are appended to the result message.
+func (te *ThresholdEvaluator) Evaluate(result *Result) {
+	if te == nil || len(te.rules) == 0 || result == nil || result.Metric == nil {
+		return
+	}
+
+	var messages []string
+	for i := range te.rules {
+		rule := &te.rules[i]
+		state := &te.states[i]
+
+		value, ok := toFloat(result.Metric.Fields[rule.Field])
+		if !ok {
+			state.matches = 0
+			continue
+		}
+
+		if state.active {
+			// the rule stays active until the value crosses the threshold including the hysteresis
+			state.active = rule.Operator.compare(value, rule.clearValue())
+			if !state.active {
+				state.matches = 0
+			}
+		} else if rule.Operator.compare(value, float64(rule.Value)) {
+			state.matches++
+			state.active = state.matches >= max(rule.For, 1)
+		} else {
+			state.matches = 0
+		}
+
+		if !state.active {
+			continue
+		}
+
+		result.State = WorseState(result.State, rule.State)
+		messages = append(messages, fmt.Sprintf("%s (%v)", rule.String(), value))
+	}
+
+	if len(messages) > 0 {
+		msg := strings.Join(messages, ", ")
+		if result.Message != "" {
+			msg = result.Message + "; " + msg
+		}
+		result.Message = msg
+	}
+}
+
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case bool:
+		if n {
+			return 1, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
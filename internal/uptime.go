@@ -0,0 +1,189 @@
+package echosight
+
+import (
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TimeRange is a time range [From, To)
+type TimeRange struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+}
+
+// overlap returns the duration of the time range within start and end
+func (tr TimeRange) overlap(start time.Time, end time.Time) time.Duration {
+	from := tr.From
+	if start.After(from) {
+		from = start
+	}
+
+	to := tr.To
+	if end.Before(to) {
+		to = end
+	}
+
+	if !to.After(from) {
+		return 0
+	}
+	return to.Sub(from)
+}
+
+// MergeTimeRanges sorts the ranges and merges overlapping ranges
+func MergeTimeRanges(ranges []TimeRange) []TimeRange {
+	if len(ranges) == 0 {
+		return nil
+	}
+
+	sorted := make([]TimeRange, len(ranges))
+	copy(sorted, ranges)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].From.Before(sorted[j].From)
+	})
+
+	merged := []TimeRange{sorted[0]}
+	for _, tr := range sorted[1:] {
+		last := &merged[len(merged)-1]
+		if !tr.From.After(last.To) {
+			if tr.To.After(last.To) {
+				last.To = tr.To
+			}
+			continue
+		}
+		merged = append(merged, tr)
+	}
+
+	return merged
+}
+
+// UptimeReport contains the availability of a detector, a host or a tag within a time range.
+//
+// A detector is down while it's CRITICAL, WARN counts as available.
+// Time without monitoring (inactive) and excluded time (e.g. maintenance)
+// is not part of the availability.
+type UptimeReport struct {
+	DetectorID   *uuid.UUID `json:"detectorId,omitempty"`
+	DetectorName string     `json:"detectorName,omitempty"`
+	HostID       *uuid.UUID `json:"hostId,omitempty"`
+	HostName     string     `json:"hostName,omitempty"`
+	Tag          string     `json:"tag,omitempty"`
+
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+
+	// Availability in percent of the monitored time
+	Availability float64  `json:"availability"`
+	Uptime       Duration `json:"uptime"`
+	Downtime     Duration `json:"downtime"`
+	// Excluded is the time which is not monitored or excluded, e.g. maintenance
+	Excluded  Duration `json:"excluded"`
+	Incidents int      `json:"incidents"`
+	// MTTR is the mean time to recovery
+	MTTR Duration `json:"mttr"`
+	// MTBF is the mean time between failures
+	MTBF Duration `json:"mtbf"`
+}
+
+// calc calculates the availability, MTTR and MTBF from uptime, downtime and incidents
+func (ur *UptimeReport) calc() {
+	monitored := ur.Uptime + ur.Downtime
+	if monitored > 0 {
+		ur.Availability = float64(ur.Uptime) / float64(monitored) * 100
+	} else {
+		ur.Availability = 100
+	}
+
+	if ur.Incidents > 0 {
+		ur.MTTR = ur.Downtime / Duration(ur.Incidents)
+		ur.MTBF = ur.Uptime / Duration(ur.Incidents)
+	} else {
+		ur.MTTR = 0
+		ur.MTBF = ur.Uptime
+	}
+}
+
+// ComputeUptime calculates the uptime report of a detector from its state changes.
+// initial is the state at the beginning of the time range, changes must be sorted by time.
+// Excluded ranges (e.g. maintenance) are not part of the availability.
+func ComputeUptime(initial State, changes []*StateChange, from time.Time, to time.Time, excluded []TimeRange) *UptimeReport {
+	report := &UptimeReport{
+		From: from,
+		To:   to,
+	}
+
+	excluded = MergeTimeRanges(excluded)
+	inIncident := false
+
+	addSegment := func(state State, start time.Time, end time.Time) {
+		if !end.After(start) {
+			return
+		}
+
+		total := end.Sub(start)
+		var ex time.Duration
+		for _, tr := range excluded {
+			ex += tr.overlap(start, end)
+		}
+		effective := total - ex
+
+		switch state {
+		case StateCritical:
+			report.Downtime += Duration(effective)
+			report.Excluded += Duration(ex)
+			if effective > 0 && !inIncident {
+				report.Incidents++
+				inIncident = true
+			}
+		case StateOK, StateWarn:
+			report.Uptime += Duration(effective)
+			report.Excluded += Duration(ex)
+			if effective > 0 {
+				inIncident = false
+			}
+		default:
+			// not monitored
+			report.Excluded += Duration(total)
+		}
+	}
+
+	state := initial
+	cursor := from
+	for _, c := range changes {
+		if c.CreatedAt.Before(from) {
+			state = c.ToState
+			continue
+		}
+
+		if !c.CreatedAt.Before(to) {
+			break
+		}
+
+		addSegment(state, cursor, c.CreatedAt)
+		state = c.ToState
+		cursor = c.CreatedAt
+	}
+	addSegment(state, cursor, to)
+
+	report.calc()
+	return report
+}
+
+// MergeUptimeReports sums up the reports, e.g. all detectors of a host
+func MergeUptimeReports(from time.Time, to time.Time, reports ...*UptimeReport) *UptimeReport {
+	merged := &UptimeReport{
+		From: from,
+		To:   to,
+	}
+
+	for _, r := range reports {
+		merged.Uptime += r.Uptime
+		merged.Downtime += r.Downtime
+		merged.Excluded += r.Excluded
+		merged.Incidents += r.Incidents
+	}
+
+	merged.calc()
+	return merged
+}
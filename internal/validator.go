@@ -1,6 +1,9 @@
 package echosight
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/alexjoedt/echosight/internal/validator"
 	"github.com/google/uuid"
 )
@@ -23,6 +26,26 @@ func ValidateDetector(v *validator.Validator, detector *Detector) {
 	v.Check(len(detector.Name) > 3, "name", "name too short")
 	v.Check(uuid.Validate(detector.HostID.String()) == nil, "hostID", "invalid host ID")
 	v.Check(ValidateDetectorConfig(detector), "config", "invalid config for type "+detector.Type.String())
+	v.Check(detector.Timeout >= 0, "timeout", "must not be negative")
+	v.Check(detector.MaxAttempts >= 1, "maxAttempts", "must be at least 1")
+	v.Check(detector.RecoveryAttempts >= 1, "recoveryAttempts", "must be at least 1")
+	v.Check(detector.RetryInterval >= 0, "retryInterval", "must not be negative")
+	v.Check(detector.RenotifyInterval == 0 || time.Duration(detector.RenotifyInterval) >= time.Minute, "renotifyInterval", "must be at least 1m")
+	v.Check(detector.RenotifyMax >= 0, "renotifyMax", "must not be negative")
+
+	for i := range detector.Thresholds {
+		ValidateThresholdRule(v, fmt.Sprintf("thresholds[%d]", i), &detector.Thresholds[i])
+	}
+
+	for i := range detector.Anomalies {
+		ValidateAnomalyRule(v, fmt.Sprintf("anomalies[%d]", i), &detector.Anomalies[i])
+	}
+
+	if detector.FlapDetection {
+		v.Check(detector.FlapLowThreshold >= 0 && detector.FlapLowThreshold <= 100, "flapLowThreshold", "must be between 0 and 100")
+		v.Check(detector.FlapHighThreshold >= 0 && detector.FlapHighThreshold <= 100, "flapHighThreshold", "must be between 0 and 100")
+		v.Check(detector.FlapLowThreshold < detector.FlapHighThreshold, "flapLowThreshold", "must be lower than the high threshold")
+	}
 }
 
 func ValidateHost(v *validator.Validator, host *Host) {
@@ -0,0 +1,72 @@
+package echosight
+
+import (
+	"encoding/json"
+	"net/url"
+	"regexp"
+	"text/template"
+	"time"
+
+	"github.com/alexjoedt/echosight/internal/validator"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+var headerNameRX = regexp.MustCompile("^[A-Za-z0-9-]+$")
+
+// WebhookFuncs are the functions of the body templates. The values are inserted
+// without escaping, json quotes and escapes a value, e.g. {"text": {{ json .Message }}}.
+var WebhookFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+// Webhook is an outbound webhook, the notifications are posted as JSON
+// or with the templated body to the url.
+// The body is signed with HMAC-SHA256, if a secret is set.
+type Webhook struct {
+	bun.BaseModel `bun:"table:webhooks"`
+	ID            uuid.UUID         `json:"id" bun:"type:uuid,pk,default:uuid_generate_v4()"`
+	LookupVersion int               `json:"lookupVersion" bun:",default:1"`
+	Name          string            `json:"name"`
+	URL           string            `json:"url"`
+	Active        bool              `json:"active"`
+	Headers       map[string]string `json:"headers" bun:"type:jsonb"`
+	// Body is a text/template rendered with the template context, the JSON payload is sent if empty.
+	// The content type is application/json, unless it's set in the headers.
+	// The values are inserted with the json function of WebhookFuncs.
+	Body    string   `json:"body"`
+	Timeout Duration `json:"timeout"`
+
+	// SecretCrypt is the encrypted secret for the signature
+	SecretCrypt string `json:"-" bun:",nullzero"`
+	HasSecret   bool   `json:"hasSecret" bun:"-"`
+
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+func ValidateWebhook(v *validator.Validator, w *Webhook) {
+	v.Check(len(w.Name) > 3, "name", "name too short")
+	ValidateWebhookTarget(v, w)
+}
+
+// ValidateWebhookTarget validates the request of the webhook without its name,
+// e.g. for a webhook channel, whose name follows the rules of the channel names
+func ValidateWebhookTarget(v *validator.Validator, w *Webhook) {
+	u, err := url.Parse(w.URL)
+	v.Check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "", "url", "must be a valid http or https url")
+
+	v.Check(w.Timeout >= 0, "timeout", "must not be negative")
+	v.Check(time.Duration(w.Timeout) <= time.Minute, "timeout", "must not exceed 1m")
+
+	for key := range w.Headers {
+		v.Check(validator.Matches(key, headerNameRX), "headers", "invalid header name "+key)
+	}
+
+	if _, err := template.New("body").Funcs(WebhookFuncs).Parse(w.Body); err != nil {
+		v.AddError("body", err.Error())
+	}
+}